@@ -3,29 +3,74 @@ package main
 import (
 	"context"
 	"errors"
-	"example.com/yourapp/internal/app"
-	"example.com/yourapp/internal/config"
-	"example.com/yourapp/internal/server"
-	"example.com/yourapp/internal/telegram"
-	"example.com/yourapp/internal/usecase"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	_ "time/tzdata"
+
+	"example.com/yourapp/internal/app"
+	"example.com/yourapp/internal/config"
+	"example.com/yourapp/internal/server"
+	"example.com/yourapp/internal/storage/migrate"
+	sqlstore "example.com/yourapp/internal/storage/sql"
+	sqlitestore "example.com/yourapp/internal/storage/sqlite"
+	"example.com/yourapp/internal/telegram"
 )
 
 func main() {
 	cfg := config.Load()
-	a := app.New(cfg)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	a, err := app.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := a.Close(); err != nil {
+			log.Printf("store close error: %v", err)
+		}
+	}()
 	srv := server.New(cfg.HTTPAddr, a.Router)
 	botCtx, botCancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
 	if cfg.TelegramToken != "" {
-		taskService := usecase.NewTaskService(a.Store)
-		bot := telegram.NewBot(cfg.TelegramToken, taskService, a.Store, cfg.TelegramPoll)
+		bot := telegram.NewBot(cfg.TelegramToken, a.Tasks, a.Users, a.Store, a.Store, a.Auth, cfg.TelegramPoll)
+		notifier := telegram.NewNotifier(cfg.TelegramToken, a.Tasks, a.Store, cfg.ReminderInterval)
+		switch cfg.TelegramMode {
+		case "webhook":
+			if cfg.WebhookSecret == "" {
+				log.Fatal("TELEGRAM_WEBHOOK_SECRET is required in webhook mode")
+			}
+			a.Handle("POST "+cfg.WebhookPath, bot.WebhookHandler(cfg.WebhookSecret, a.Store))
+			if cfg.WebhookURL != "" {
+				if err := bot.SetWebhook(botCtx, cfg.WebhookURL, cfg.WebhookSecret); err != nil {
+					log.Printf("telegram setWebhook error: %v", err)
+				}
+			}
+		default:
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				if err := bot.Run(botCtx); err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("telegram bot error: %v", err)
+				}
+			}()
+		}
+		workers.Add(1)
 		go func() {
-			if err := bot.Run(botCtx); err != nil && !errors.Is(err, context.Canceled) {
-				log.Printf("telegram bot error: %v", err)
+			defer workers.Done()
+			if err := notifier.Run(botCtx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("reminder notifier error: %v", err)
 			}
 		}()
 	} else {
@@ -46,9 +91,11 @@ func main() {
 			log.Printf("server error: %v", err)
 		}
 		botCancel()
+		workers.Wait()
 		return
 	}
 	botCancel()
+	workers.Wait()
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 	if err := srv.Stop(ctx); err != nil {
@@ -58,3 +105,74 @@ func main() {
 		log.Printf("server error: %v", err)
 	}
 }
+
+// runMigrate implements "migrate up", "migrate down [n]" (default 1) and "migrate status"
+// for STORAGE=sqlite or, otherwise, the Postgres database at DB_DSN.
+func runMigrate(cfg config.Config, args []string) error {
+	var m *migrate.Runner
+	var err error
+	switch cfg.Storage {
+	case "sqlite":
+		store, err := sqlitestore.New(cfg.SQLitePath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		m, err = store.Migrator()
+		if err != nil {
+			return err
+		}
+	default:
+		if cfg.DBDSN == "" {
+			return errors.New("DB_DSN is required")
+		}
+		m, err = sqlstore.New(cfg.DBDriver, cfg.DBDSN).Migrator()
+		if err != nil {
+			return err
+		}
+	}
+	ctx := context.Background()
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		all, applied, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range all {
+			state := "pending"
+			if applied[mig.Version] {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", mig.Version, mig.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, want up, down [n] or status", cmd)
+	}
+}
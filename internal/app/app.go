@@ -1,39 +1,115 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
 
 	"example.com/yourapp/internal/config"
+	"example.com/yourapp/internal/domain"
 	httphandlers "example.com/yourapp/internal/handler/http"
 	"example.com/yourapp/internal/repository"
 	"example.com/yourapp/internal/storage/memory"
+	"example.com/yourapp/internal/storage/migrate"
 	sqlstore "example.com/yourapp/internal/storage/sql"
+	sqlitestore "example.com/yourapp/internal/storage/sqlite"
+	"example.com/yourapp/internal/usecase"
 )
 
 type Store interface {
 	httphandlers.Store
 	repository.TaskRepository
 	repository.UserRepository
+	repository.TokenRepository
+	repository.ProjectRepository
+	repository.AttachmentRepository
+	repository.UpdateRepository
+	// CreateTask and GetTask are what the stores call Create and GetByID.
+	CreateTask(task domain.Task) (domain.Task, error)
+	GetTask(id int64) (domain.Task, error)
 }
 
 type App struct {
 	Config config.Config
 	Router http.Handler
 	Store  Store
+	Tasks  *usecase.TaskService
+	Users  *usecase.UserService
+	Auth   *usecase.AuthService
+	mux    *http.ServeMux
 }
 
-func New(cfg config.Config) *App {
+func New(cfg config.Config) (*App, error) {
 	var store Store
 	switch cfg.Storage {
 	case "sql":
-		store = sqlstore.New(cfg.DBDriver, cfg.DBDSN)
+		sqlStore := sqlstore.New(cfg.DBDriver, cfg.DBDSN)
+		if cfg.AutoMigrate {
+			if err := migrateUp(sqlStore.Migrator()); err != nil {
+				return nil, err
+			}
+		}
+		store = sqlStore
+	case "sqlite":
+		sqliteStore, err := sqlitestore.New(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.AutoMigrate {
+			if err := migrateUp(sqliteStore.Migrator()); err != nil {
+				_ = sqliteStore.Close()
+				return nil, err
+			}
+		}
+		store = sqliteStore
 	default:
-		store = memory.New()
+		if cfg.DataDir == "" {
+			store = memory.New()
+			break
+		}
+		memStore, err := memory.Open(cfg.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		store = memStore
 	}
-	h := httphandlers.New(store)
+	tasks := usecase.NewTaskService(store)
+	users := usecase.NewUserService(store)
+	auth := usecase.NewAuthService(store, store, cfg.AdminToken, cfg.TelegramToken, cfg.SessionTTL)
+	mux := http.NewServeMux()
+	mux.Handle("/", httphandlers.New(store, tasks, users, auth))
 	return &App{
 		Config: cfg,
-		Router: h,
+		Router: mux,
 		Store:  store,
+		Tasks:  tasks,
+		Users:  users,
+		Auth:   auth,
+		mux:    mux,
+	}, nil
+}
+
+// Close releases the store, e.g. writes the final snapshot of a durable memory store.
+func (a *App) Close() error {
+	if c, ok := a.Store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Handle mounts an extra route next to the API handlers, e.g. the Telegram webhook.
+func (a *App) Handle(pattern string, h http.Handler) {
+	a.mux.Handle(pattern, h)
+}
+
+// migrateUp applies the pending migrations for AUTO_MIGRATE.
+func migrateUp(m *migrate.Runner, err error) error {
+	if err != nil {
+		return err
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		return fmt.Errorf("auto-migrate: %w", err)
 	}
+	return nil
 }
@@ -8,14 +8,24 @@ import (
 )
 
 type Config struct {
-	Env             string
-	HTTPAddr        string
-	Storage         string
-	DBDriver        string
-	DBDSN           string
-	ShutdownTimeout time.Duration
-	TelegramToken   string
-	TelegramPoll    time.Duration
+	Env              string
+	HTTPAddr         string
+	Storage          string
+	DBDriver         string
+	DBDSN            string
+	SQLitePath       string
+	DataDir          string
+	ShutdownTimeout  time.Duration
+	TelegramToken    string
+	TelegramPoll     time.Duration
+	TelegramMode     string
+	WebhookURL       string
+	WebhookPath      string
+	WebhookSecret    string
+	ReminderInterval time.Duration
+	AdminToken       string
+	SessionTTL       time.Duration
+	AutoMigrate      bool
 }
 
 func getenv(key, def string) string {
@@ -38,6 +48,18 @@ func getdur(key string, def time.Duration) time.Duration {
 	return d
 }
 
+func getbool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
 func Load() Config {
 	var addr string
 	var storage string
@@ -47,14 +69,24 @@ func Load() Config {
 	flag.StringVar(&env, "env", getenv("APP_ENV", "dev"), "env")
 	flag.Parse()
 	return Config{
-		Env:             env,
-		HTTPAddr:        addr,
-		Storage:         storage,
-		DBDriver:        getenv("DB_DRIVER", "pgx"),
-		DBDSN:           getenv("DB_DSN", ""),
-		ShutdownTimeout: getdur("SHUTDOWN_TIMEOUT", 5*time.Second),
-		TelegramToken:   getenv("TELEGRAM_TOKEN", ""),
-		TelegramPoll:    getdur("TELEGRAM_POLL_TIMEOUT", 20*time.Second),
+		Env:              env,
+		HTTPAddr:         addr,
+		Storage:          storage,
+		DBDriver:         getenv("DB_DRIVER", "pgx"),
+		DBDSN:            getenv("DB_DSN", ""),
+		SQLitePath:       getenv("SQLITE_PATH", "tasks.db"),
+		DataDir:          getenv("DATA_DIR", ""),
+		ShutdownTimeout:  getdur("SHUTDOWN_TIMEOUT", 5*time.Second),
+		TelegramToken:    getenv("TELEGRAM_TOKEN", ""),
+		TelegramPoll:     getdur("TELEGRAM_POLL_TIMEOUT", 20*time.Second),
+		TelegramMode:     getenv("TELEGRAM_MODE", "polling"),
+		WebhookURL:       getenv("TELEGRAM_WEBHOOK_URL", ""),
+		WebhookPath:      getenv("TELEGRAM_WEBHOOK_PATH", "/telegram/webhook"),
+		WebhookSecret:    getenv("TELEGRAM_WEBHOOK_SECRET", ""),
+		ReminderInterval: getdur("REMINDER_INTERVAL", 30*time.Second),
+		AdminToken:       getenv("ADMIN_TOKEN", ""),
+		SessionTTL:       getdur("SESSION_TTL", 7*24*time.Hour),
+		AutoMigrate:      getbool("AUTO_MIGRATE", false),
 	}
 }
 
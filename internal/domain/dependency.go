@@ -0,0 +1,76 @@
+package domain
+
+import "sort"
+
+// Dependency says that TaskID is blocked by BlockedByID: it can't be worked on until
+// BlockedByID is done.
+type Dependency struct {
+	TaskID      int64 `json:"task_id"`
+	BlockedByID int64 `json:"blocked_by_id"`
+}
+
+// Graph is the part of the dependency DAG around one task.
+type Graph struct {
+	Nodes []Task       `json:"nodes"`
+	Edges []Dependency `json:"edges"`
+}
+
+// CreatesCycle reports whether adding "taskID is blocked by blockedByID" to edges closes
+// a cycle, a task blocking itself included.
+func CreatesCycle(edges []Dependency, taskID, blockedByID int64) bool {
+	if taskID == blockedByID {
+		return true
+	}
+	blockers := make(map[int64][]int64)
+	for _, e := range edges {
+		blockers[e.TaskID] = append(blockers[e.TaskID], e.BlockedByID)
+	}
+	return reachable(blockers, blockedByID)[taskID]
+}
+
+// DependencyGraph picks from edges what rootID waits for and what waits for rootID,
+// transitively. It returns the sorted ids of those tasks (rootID included) and the
+// edges between them.
+func DependencyGraph(edges []Dependency, rootID int64) ([]int64, []Dependency) {
+	blockers := make(map[int64][]int64)
+	dependents := make(map[int64][]int64)
+	for _, e := range edges {
+		blockers[e.TaskID] = append(blockers[e.TaskID], e.BlockedByID)
+		dependents[e.BlockedByID] = append(dependents[e.BlockedByID], e.TaskID)
+	}
+	in := reachable(blockers, rootID)
+	for id := range reachable(dependents, rootID) {
+		in[id] = true
+	}
+	in[rootID] = true
+	ids := make([]int64, 0, len(in))
+	for id := range in {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var sub []Dependency
+	for _, e := range edges {
+		if in[e.TaskID] && in[e.BlockedByID] {
+			sub = append(sub, e)
+		}
+	}
+	return ids, sub
+}
+
+// reachable returns the ids reachable from start through next, start excluded unless it
+// is on a cycle.
+func reachable(next map[int64][]int64, start int64) map[int64]bool {
+	seen := make(map[int64]bool)
+	stack := []int64{start}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, n := range next[id] {
+			if !seen[n] {
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+	return seen
+}
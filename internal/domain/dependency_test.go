@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	// 4 waits for 3, 3 for 2 and 1; 5 waits for 1 but is unrelated to 3; 7 waits for 6.
+	edges := []Dependency{
+		{TaskID: 3, BlockedByID: 1},
+		{TaskID: 3, BlockedByID: 2},
+		{TaskID: 4, BlockedByID: 3},
+		{TaskID: 5, BlockedByID: 1},
+		{TaskID: 7, BlockedByID: 6},
+	}
+	ids, sub := DependencyGraph(edges, 3)
+	if got := fmt.Sprint(ids); got != "[1 2 3 4]" {
+		t.Fatalf("ids: got %s", got)
+	}
+	if len(sub) != 3 {
+		t.Fatalf("edges: got %+v", sub)
+	}
+	if ids, sub := DependencyGraph(edges, 8); fmt.Sprint(ids) != "[8]" || len(sub) != 0 {
+		t.Fatalf("isolated task: got %v %+v", ids, sub)
+	}
+
+	if !CreatesCycle(edges, 1, 4) || !CreatesCycle(edges, 2, 2) {
+		t.Fatalf("expected cycles to be detected")
+	}
+	if CreatesCycle(edges, 5, 4) || CreatesCycle(edges, 1, 6) {
+		t.Fatalf("expected acyclic edges to be accepted")
+	}
+}
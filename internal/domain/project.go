@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxProjectNameLength = 64
+
+var (
+	ErrInvalidProjectName = errors.New("invalid project name")
+	ErrInvalidColor       = errors.New("invalid color")
+)
+
+// Project groups a user's tasks. Tasks without a project are in the inbox.
+type Project struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NormalizeProjectName trims the name; it has to be a single line of up to
+// MaxProjectNameLength runes.
+func NormalizeProjectName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxProjectNameLength || strings.ContainsAny(name, "\r\n") {
+		return "", ErrInvalidProjectName
+	}
+	return name, nil
+}
+
+// NormalizeColor accepts "" (no color) or "#rrggbb" and lowercases it.
+func NormalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return "", nil
+	}
+	if len(color) != 7 || color[0] != '#' {
+		return "", ErrInvalidColor
+	}
+	for i := 1; i < len(color); i++ {
+		c := color[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", ErrInvalidColor
+		}
+	}
+	return color, nil
+}
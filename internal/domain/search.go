@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Highlight marks wrap the matched words in SearchResult.Highlight.
+const (
+	HighlightStart = "<b>"
+	HighlightEnd   = "</b>"
+)
+
+// SearchResult is a task matching a text query. Rank is only comparable within one
+// search; Highlight is the task text with the matched words wrapped in highlight marks.
+type SearchResult struct {
+	Task
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// SearchTerms splits text into lowercase words; "ё" is folded into "е" so either
+// spelling finds the other. Everything but letters and digits separates words.
+func SearchTerms(text string) []string {
+	var terms []string
+	for _, f := range strings.FieldsFunc(text, isNotWordRune) {
+		terms = append(terms, foldTerm(f))
+	}
+	return terms
+}
+
+// MatchesTerm reports whether a word of the text matches a query term. Terms match
+// word prefixes, so "молок" finds "молоко" and "молока".
+func MatchesTerm(word, term string) bool {
+	return strings.HasPrefix(word, term)
+}
+
+// HighlightTerms wraps every word of text that matches one of the terms in highlight
+// marks.
+func HighlightTerms(text string, terms []string) string {
+	var b strings.Builder
+	rest := text
+	for rest != "" {
+		start := strings.IndexFunc(rest, isWordRune)
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:start])
+		rest = rest[start:]
+		end := strings.IndexFunc(rest, isNotWordRune)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		if matchesAny(foldTerm(word), terms) {
+			b.WriteString(HighlightStart + word + HighlightEnd)
+			continue
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+func matchesAny(word string, terms []string) bool {
+	for _, term := range terms {
+		if MatchesTerm(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func foldTerm(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isNotWordRune(r rune) bool {
+	return !isWordRune(r)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	got := SearchTerms("Купить ЁЛКУ, e-mail; 2026!")
+	want := []string{"купить", "елку", "e", "mail", "2026"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := SearchTerms(" ?! "); len(got) != 0 {
+		t.Fatalf("expected no terms, got %q", got)
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Купить молоко и хлеб", []string{"молок"}, "Купить <b>молоко</b> и хлеб"},
+		{"Ёлка, ёлка!", []string{"елк"}, "<b>Ёлка</b>, <b>ёлка</b>!"},
+		{"call mom", []string{"dad"}, "call mom"},
+		{"", []string{"x"}, ""},
+	}
+	for _, tt := range tests {
+		if got := HighlightTerms(tt.text, tt.terms); got != tt.want {
+			t.Errorf("HighlightTerms(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
@@ -7,18 +7,88 @@ const (
 	TaskStatusDone   = "done"
 )
 
+// Priorities run from P1 (most important) to P4, the default.
+const (
+	PriorityP1      = 1
+	PriorityP2      = 2
+	PriorityP3      = 3
+	PriorityP4      = 4
+	PriorityDefault = PriorityP4
+)
+
+func ValidPriority(p int) bool {
+	return p >= PriorityP1 && p <= PriorityP4
+}
+
+const (
+	ReminderStatusPending   = "pending"
+	ReminderStatusSending   = "sending"
+	ReminderStatusDelivered = "delivered"
+	ReminderStatusFailed    = "failed"
+)
+
 type Task struct {
 	ID         int64      `json:"id"`
 	UserID     int64      `json:"user_id"`
+	ProjectID  *int64     `json:"project_id,omitempty"`
+	ParentID   *int64     `json:"parent_id,omitempty"`
 	Text       string     `json:"text"`
 	Status     string     `json:"status"`
+	Priority   int        `json:"priority"`
 	DueAt      *time.Time `json:"due_at,omitempty"`
 	RemindAt   *time.Time `json:"remind_at,omitempty"`
 	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
+	// Version starts at 1 and goes up with every write to the task.
+	Version int64 `json:"version"`
+
+	ReminderStatus   string     `json:"reminder_status"`
+	ReminderAttempts int        `json:"reminder_attempts"`
+	ReminderError    string     `json:"reminder_error,omitempty"`
+	ReminderNextAt   *time.Time `json:"reminder_next_at,omitempty"`
+
+	// Progress counts the subtasks of a parent task; it is filled in by listings.
+	Progress *Progress `json:"progress,omitempty"`
+	// Subtasks is only filled in when a single task is requested.
+	Subtasks []Task `json:"subtasks,omitempty"`
+	// BlockedBy lists the tasks this one waits for; Blocked is set while any of them is
+	// still active. Both are filled in by listings and single-task reads.
+	BlockedBy []int64 `json:"blocked_by,omitempty"`
+	Blocked   bool    `json:"blocked"`
+}
+
+// Progress is how many of a task's subtasks are done, e.g. 3 of 5.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// SubtaskProgress counts done subtasks; it is nil when there are none.
+func SubtaskProgress(subtasks []Task) *Progress {
+	if len(subtasks) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(subtasks)}
+	for _, t := range subtasks {
+		if t.Status == TaskStatusDone {
+			p.Done++
+		}
+	}
+	return p
 }
 
+// Attachment types match the Telegram message fields they come from.
+const (
+	AttachmentTypePhoto    = "photo"
+	AttachmentTypeDocument = "document"
+	AttachmentTypeVoice    = "voice"
+)
+
+// Attachment is a file sent to the bot for a task. Only Telegram's file ids are kept:
+// TelegramFileID resends the file, FileUniqueID identifies it across bots.
 type Attachment struct {
 	ID             int64  `json:"id"`
 	TaskID         int64  `json:"task_id"`
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// APIToken is a bearer token issued to a user. Only the hash of the token is stored.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Name      string     `json:"name,omitempty"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
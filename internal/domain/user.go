@@ -2,10 +2,23 @@ package domain
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID             int64     `json:"id"`
 	TelegramUserID int64     `json:"telegram_user_id"`
 	ChatID         int64     `json:"chat_id"`
 	Timezone       string    `json:"timezone"`
+	Role           string    `json:"role"`
 	CreatedAt      time.Time `json:"created_at"`
+
+	// CurrentProjectID is where the bot's /add and /list go; nil is the inbox.
+	CurrentProjectID *int64 `json:"current_project_id,omitempty"`
+}
+
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
@@ -0,0 +1,26 @@
+package httpx
+
+import (
+	"net/http"
+
+	"example.com/yourapp/pkg/response"
+)
+
+// taskAttachments lists the files sent to the bot for a task. Files stay in Telegram;
+// telegram_file_id is only usable by this bot.
+func (h *Handler) taskAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, id); !ok {
+		return
+	}
+	items, err := h.store.ListAttachments(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+}
@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/usecase"
+	"example.com/yourapp/pkg/response"
+)
+
+type principalKey struct{}
+
+// authed requires a valid bearer token and stores the authenticated user in the
+// request context.
+func (h *Handler) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := h.auth.Authenticate(bearerToken(r))
+		if err != nil {
+			if errors.Is(err, usecase.ErrUnauthorized) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "store")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+func (h *Handler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return h.authed(func(w http.ResponseWriter, r *http.Request) {
+		if !principal(r).IsAdmin() {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func principal(r *http.Request) usecase.Principal {
+	p, _ := r.Context().Value(principalKey{}).(usecase.Principal)
+	return p
+}
+
+// canAccessUser reports whether the principal may act on behalf of userID.
+func canAccessUser(p usecase.Principal, userID int64) bool {
+	return p.IsAdmin() || (!p.StaticAdmin && p.ID == userID)
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// telegramLogin accepts the Login Widget payload as a JSON object of its fields.
+func (h *Handler) telegramLogin(w http.ResponseWriter, r *http.Request) {
+	var raw map[string]json.RawMessage
+	if err := decodeJSON(r, &raw); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var str string
+		if err := json.Unmarshal(v, &str); err == nil {
+			fields[k] = str
+			continue
+		}
+		var num json.Number
+		if err := json.Unmarshal(v, &num); err != nil {
+			writeError(w, http.StatusBadRequest, k)
+			return
+		}
+		fields[k] = num.String()
+	}
+	h.writeSession(w, func() (string, domain.APIToken, domain.User, error) {
+		return h.auth.LoginWidget(fields)
+	})
+}
+
+func (h *Handler) telegramWebApp(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		InitData string `json:"init_data"`
+	}
+	if err := decodeJSON(r, &req); err != nil || req.InitData == "" {
+		writeError(w, http.StatusBadRequest, "init_data")
+		return
+	}
+	h.writeSession(w, func() (string, domain.APIToken, domain.User, error) {
+		return h.auth.LoginInitData(req.InitData)
+	})
+}
+
+func (h *Handler) writeSession(w http.ResponseWriter, login func() (string, domain.APIToken, domain.User, error)) {
+	plain, token, user, err := login()
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidTelegramAuth) {
+			writeError(w, http.StatusUnauthorized, "telegram_auth")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]any{
+		"token":      plain,
+		"expires_at": token.ExpiresAt,
+		"user":       user,
+	})
+}
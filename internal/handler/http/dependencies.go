@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/pkg/response"
+)
+
+func (h *Handler) addDependency(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	var req struct {
+		BlockedByID int64 `json:"blocked_by_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	if req.BlockedByID <= 0 {
+		writeError(w, http.StatusBadRequest, "blocked_by_id")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, id); !ok {
+		return
+	}
+	if err := h.taskSvc.Block(id, req.BlockedByID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			// The task is ours, so it is the blocker that is missing or foreign.
+			writeError(w, http.StatusBadRequest, "blocked_by_id")
+			return
+		}
+		writeTaskError(w, r, err)
+		return
+	}
+	item, ok := h.ownedTask(w, r, id)
+	if !ok {
+		return
+	}
+	response.JSON(w, http.StatusCreated, item)
+}
+
+func (h *Handler) removeDependency(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	blockedByID, err := strconv.ParseInt(r.PathValue("blocked_by_id"), 10, 64)
+	if err != nil || blockedByID <= 0 {
+		writeError(w, http.StatusBadRequest, "blocked_by_id")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, id); !ok {
+		return
+	}
+	if err := h.taskSvc.Unblock(id, blockedByID); err != nil {
+		writeTaskError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// taskGraph returns the tasks the task waits for and the tasks waiting for it, both
+// transitively, with the edges between them.
+func (h *Handler) taskGraph(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, id); !ok {
+		return
+	}
+	graph, err := h.taskSvc.Graph(id, callerTZ(r))
+	if err != nil {
+		writeTaskError(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, graph)
+}
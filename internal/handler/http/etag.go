@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+)
+
+// taskETag is the task's version as a strong entity tag.
+func taskETag(t domain.Task) string {
+	return `"` + strconv.FormatInt(t.Version, 10) + `"`
+}
+
+// checkIfMatch answers 412 unless the request's If-Match, if any, names the task's
+// current version. Weak tags never match, as RFC 9110 asks for If-Match.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, t domain.Task) bool {
+	if ifMatches(r.Header.Values("If-Match"), taskETag(t)) {
+		return true
+	}
+	w.Header().Set("ETag", taskETag(t))
+	writeError(w, http.StatusPreconditionFailed, "precondition_failed")
+	return false
+}
+
+func ifMatches(values []string, etag string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeStale answers a write that lost a race: 412 when the client sent If-Match,
+// otherwise 409, since the task changed between our own read and write.
+func writeStale(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		writeError(w, http.StatusPreconditionFailed, "precondition_failed")
+		return
+	}
+	writeError(w, http.StatusConflict, "conflict")
+}
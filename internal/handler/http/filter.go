@@ -0,0 +1,148 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/pkg/response"
+)
+
+// queryErrors collects what is wrong with each query parameter, so a client sees every
+// bad parameter at once instead of fixing them one request at a time.
+type queryErrors struct {
+	params  []string
+	reasons map[string]string
+}
+
+// add records the reason a parameter is rejected; only the first reason is kept.
+func (e *queryErrors) add(param, reason string) {
+	if _, ok := e.reasons[param]; ok {
+		return
+	}
+	if e.reasons == nil {
+		e.reasons = make(map[string]string)
+	}
+	e.params = append(e.params, param)
+	e.reasons[param] = reason
+}
+
+// write responds 400 when a parameter was rejected and reports whether it did. "error"
+// names the first bad parameter, as writeError would; "params" has them all.
+func (e *queryErrors) write(w http.ResponseWriter) bool {
+	if len(e.params) == 0 {
+		return false
+	}
+	response.JSON(w, http.StatusBadRequest, map[string]any{"error": e.params[0], "params": e.reasons})
+	return true
+}
+
+// parseTaskFilter reads the filter parameters of GET /tasks. now is what overdue=true is
+// measured against.
+func parseTaskFilter(q url.Values, now time.Time, errs *queryErrors) storage.TaskFilter {
+	var f storage.TaskFilter
+	if status := q.Get("status"); status != "" {
+		if validTaskStatus(status) {
+			f.Status = status
+		} else {
+			errs.add("status", "must be active or done")
+		}
+	}
+	if raw := q.Get("priority"); raw != "" {
+		if p, err := parsePriority(raw); err == nil {
+			f.Priority = p
+		} else {
+			errs.add("priority", "must be 1-4 or p1-p4")
+		}
+	}
+	if raw := q.Get("project_id"); raw != "" {
+		if id, err := strconv.ParseInt(raw, 10, 64); err == nil && id > 0 {
+			f.ProjectID = id
+		} else {
+			errs.add("project_id", "must be a positive integer")
+		}
+	}
+	if tags, err := domain.NormalizeTags(splitList(q["tag"])); err == nil {
+		f.Tags = tags
+	} else {
+		errs.add("tag", err.Error())
+	}
+	switch q.Get("tag_mode") {
+	case "", "any":
+	case "all":
+		f.AllTags = true
+	default:
+		errs.add("tag_mode", "must be any or all")
+	}
+
+	f.DueAfter = parseTimeParam(q, "due_after", errs)
+	f.DueBefore = parseTimeParam(q, "due_before", errs)
+	f.CreatedAfter = parseTimeParam(q, "created_after", errs)
+	f.CreatedBefore = parseTimeParam(q, "created_before", errs)
+	f.UpdatedAfter = parseTimeParam(q, "updated_after", errs)
+	f.UpdatedBefore = parseTimeParam(q, "updated_before", errs)
+	checkWindow(f.DueAfter, f.DueBefore, "due_before", errs)
+	checkWindow(f.CreatedAfter, f.CreatedBefore, "created_before", errs)
+	checkWindow(f.UpdatedAfter, f.UpdatedBefore, "updated_before", errs)
+
+	if overdue, ok := parseBoolParam(q, "overdue", errs); ok && overdue {
+		now = now.UTC()
+		f.OverdueAt = &now
+	}
+	if noDue, ok := parseBoolParam(q, "no_due", errs); ok {
+		f.NoDue = &noDue
+		if noDue && (f.DueAfter != nil || f.DueBefore != nil || f.OverdueAt != nil) {
+			errs.add("no_due", "conflicts with due_after, due_before and overdue")
+		}
+	}
+	if reminder := q.Get("reminder"); reminder != "" {
+		if reminder == storage.ReminderNone || validReminderStatus(reminder) {
+			f.Reminder = reminder
+		} else {
+			errs.add("reminder", "must be pending, sending, delivered, failed or none")
+		}
+	}
+	if text := strings.TrimSpace(q.Get("text")); text != "" {
+		f.TextContains = text
+	}
+	return f
+}
+
+func parseTimeParam(q url.Values, param string, errs *queryErrors) *time.Time {
+	raw := q.Get(param)
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		errs.add(param, "must be an RFC 3339 time")
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func parseBoolParam(q url.Values, param string, errs *queryErrors) (bool, bool) {
+	raw := q.Get(param)
+	if raw == "" {
+		return false, false
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		errs.add(param, "must be true or false")
+		return false, false
+	}
+	return v, true
+}
+
+// checkWindow rejects a window that can't hold anything; the Before parameter takes the
+// blame.
+func checkWindow(after, before *time.Time, param string, errs *queryErrors) {
+	if after != nil && before != nil && !after.Before(*before) {
+		errs.add(param, "must be later than the matching _after bound")
+	}
+}
@@ -11,28 +11,45 @@ import (
 
 	"example.com/yourapp/internal/domain"
 	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/internal/usecase"
 	"example.com/yourapp/pkg/response"
 )
 
+// Store is what the handlers read and write directly. Users and tasks go through
+// usecase.UserService and usecase.TaskService, so the API follows the same rules as the
+// bot.
 type Store interface {
-	ListUsers() ([]domain.User, error)
-	CreateUser(user domain.User) (domain.User, error)
-	ListTasks(userID int64, status string) ([]domain.Task, error)
-	GetTask(id int64) (domain.Task, error)
-	CreateTask(task domain.Task) (domain.Task, error)
-	UpdateTask(task domain.Task) (domain.Task, error)
-	DeleteTask(id int64) error
+	// ListAttachments orders by id.
+	ListAttachments(taskID int64) ([]domain.Attachment, error)
+	ListTags(userID int64) ([]domain.Tag, error)
+	GetTag(id int64) (domain.Tag, error)
+	// CreateTag returns storage.ErrConflict when the user already has a tag with that name.
+	CreateTag(tag domain.Tag) (domain.Tag, error)
+	RenameTag(id int64, name string) (domain.Tag, error)
+	DeleteTag(id int64) error
+	ListProjects(userID int64, includeArchived bool) ([]domain.Project, error)
+	GetProject(id int64) (domain.Project, error)
+	// CreateProject and UpdateProject return storage.ErrConflict for a duplicate name.
+	CreateProject(project domain.Project) (domain.Project, error)
+	UpdateProject(project domain.Project) (domain.Project, error)
+	DeleteProject(id int64, withTasks bool) error
 }
 
 type Handler struct {
-	mux   *http.ServeMux
-	store Store
+	mux     *http.ServeMux
+	store   Store
+	taskSvc *usecase.TaskService
+	userSvc *usecase.UserService
+	auth    *usecase.AuthService
 }
 
-func New(s Store) http.Handler {
+func New(s Store, tasks *usecase.TaskService, users *usecase.UserService, auth *usecase.AuthService) http.Handler {
 	h := &Handler{
-		mux:   http.NewServeMux(),
-		store: s,
+		mux:     http.NewServeMux(),
+		store:   s,
+		taskSvc: tasks,
+		userSvc: users,
+		auth:    auth,
 	}
 	h.routes()
 	return h
@@ -40,13 +57,34 @@ func New(s Store) http.Handler {
 
 func (h *Handler) routes() {
 	h.mux.HandleFunc("GET /healthz", h.health)
-	h.mux.HandleFunc("GET /users", h.users)
-	h.mux.HandleFunc("POST /users", h.createUser)
-	h.mux.HandleFunc("GET /tasks", h.tasks)
-	h.mux.HandleFunc("POST /tasks", h.createTask)
-	h.mux.HandleFunc("GET /tasks/{id}", h.task)
-	h.mux.HandleFunc("PATCH /tasks/{id}", h.updateTask)
-	h.mux.HandleFunc("DELETE /tasks/{id}", h.deleteTask)
+	h.mux.HandleFunc("POST /auth/telegram/login", h.telegramLogin)
+	h.mux.HandleFunc("POST /auth/telegram/webapp", h.telegramWebApp)
+	h.mux.HandleFunc("GET /users", h.adminOnly(h.users))
+	h.mux.HandleFunc("POST /users", h.adminOnly(h.createUser))
+	h.mux.HandleFunc("PATCH /users/{id}", h.authed(h.updateUser))
+	h.mux.HandleFunc("GET /users/{id}/tokens", h.authed(h.tokens))
+	h.mux.HandleFunc("POST /users/{id}/tokens", h.authed(h.createToken))
+	h.mux.HandleFunc("DELETE /users/{id}/tokens/{token_id}", h.authed(h.deleteToken))
+	h.mux.HandleFunc("GET /tasks", h.authed(h.tasks))
+	h.mux.HandleFunc("POST /tasks", h.authed(h.createTask))
+	h.mux.HandleFunc("GET /tasks/{id}", h.authed(h.task))
+	h.mux.HandleFunc("PATCH /tasks/{id}", h.authed(h.updateTask))
+	h.mux.HandleFunc("DELETE /tasks/{id}", h.authed(h.deleteTask))
+	h.mux.HandleFunc("GET /tasks/{id}/graph", h.authed(h.taskGraph))
+	h.mux.HandleFunc("GET /tasks/{id}/attachments", h.authed(h.taskAttachments))
+	h.mux.HandleFunc("POST /tasks/{id}/dependencies", h.authed(h.addDependency))
+	h.mux.HandleFunc("DELETE /tasks/{id}/dependencies/{blocked_by_id}", h.authed(h.removeDependency))
+	h.mux.HandleFunc("GET /projects", h.authed(h.projects))
+	h.mux.HandleFunc("POST /projects", h.authed(h.createProject))
+	h.mux.HandleFunc("GET /projects/{id}", h.authed(h.project))
+	h.mux.HandleFunc("PATCH /projects/{id}", h.authed(h.updateProject))
+	h.mux.HandleFunc("DELETE /projects/{id}", h.authed(h.deleteProject))
+	h.mux.HandleFunc("GET /projects/{id}/tasks", h.authed(h.projectTasks))
+	h.mux.HandleFunc("GET /tags", h.authed(h.tags))
+	h.mux.HandleFunc("POST /tags", h.authed(h.createTag))
+	h.mux.HandleFunc("PATCH /tags/{id}", h.authed(h.updateTag))
+	h.mux.HandleFunc("DELETE /tags/{id}", h.authed(h.deleteTag))
+	h.mux.HandleFunc("GET /reminders", h.adminOnly(h.reminders))
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -58,12 +96,17 @@ func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) users(w http.ResponseWriter, r *http.Request) {
-	items, err := h.store.ListUsers()
+	var errs queryErrors
+	page := parsePage(r.URL.Query(), storage.UserSortFields, storage.DefaultUserSort, &errs)
+	if errs.write(w) {
+		return
+	}
+	items, err := h.userSvc.List(page)
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, map[string]string{"error": "store"})
 		return
 	}
-	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+	writePage(w, items, page, storage.UserSortValues)
 }
 
 func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
@@ -71,46 +114,126 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 		TelegramUserID int64  `json:"telegram_user_id"`
 		ChatID         int64  `json:"chat_id"`
 		Timezone       string `json:"timezone"`
+		Role           string `json:"role"`
 	}
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "json")
 		return
 	}
-	if req.TelegramUserID == 0 || req.ChatID == 0 {
-		writeError(w, http.StatusBadRequest, "telegram_user_id/chat_id")
-		return
-	}
-	if req.Timezone == "" {
-		req.Timezone = "UTC"
-	}
-	user, err := h.store.CreateUser(domain.User{
+	user, err := h.userSvc.Create(usecase.NewUser{
 		TelegramUserID: req.TelegramUserID,
 		ChatID:         req.ChatID,
 		Timezone:       req.Timezone,
+		Role:           req.Role,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "store")
+		switch {
+		case errors.Is(err, usecase.ErrInvalidTelegramID):
+			writeError(w, http.StatusBadRequest, "telegram_user_id/chat_id")
+		case errors.Is(err, usecase.ErrInvalidTimezone):
+			writeError(w, http.StatusBadRequest, "timezone")
+		case errors.Is(err, usecase.ErrInvalidRole):
+			writeError(w, http.StatusBadRequest, "role")
+		case errors.Is(err, storage.ErrConflict):
+			writeError(w, http.StatusConflict, "telegram_user_id")
+		default:
+			writeError(w, http.StatusInternalServerError, "store")
+		}
 		return
 	}
 	response.JSON(w, http.StatusCreated, user)
 }
 
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	if !canAccessUser(principal(r), id) {
+		writeError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+	var req struct {
+		Timezone *string `json:"timezone"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	if req.Timezone == nil {
+		writeError(w, http.StatusBadRequest, "timezone")
+		return
+	}
+	user, err := h.userSvc.SetTimezone(id, *req.Timezone)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidTimezone):
+			writeError(w, http.StatusBadRequest, "timezone")
+		case errors.Is(err, storage.ErrNotFound):
+			writeError(w, http.StatusNotFound, "not_found")
+		default:
+			writeError(w, http.StatusInternalServerError, "store")
+		}
+		return
+	}
+	response.JSON(w, http.StatusOK, user)
+}
+
 func (h *Handler) tasks(w http.ResponseWriter, r *http.Request) {
-	userID, err := parseInt64Query(r, "user_id")
-	if err != nil || userID <= 0 {
-		writeError(w, http.StatusBadRequest, "user_id")
+	var requested int64
+	if r.URL.Query().Get("user_id") != "" {
+		id, err := parseInt64Query(r, "user_id")
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "user_id")
+			return
+		}
+		requested = id
+	}
+	userID, ok := h.scopedUserID(w, r, requested)
+	if !ok {
 		return
 	}
-	status := r.URL.Query().Get("status")
-	if status != "" && !validTaskStatus(status) {
-		writeError(w, http.StatusBadRequest, "status")
+	var errs queryErrors
+	filter := parseTaskFilter(r.URL.Query(), time.Now(), &errs)
+	page := parsePage(r.URL.Query(), storage.TaskSortFields, storage.DefaultTaskSort, &errs)
+	if errs.write(w) {
+		return
+	}
+	if r.URL.Query().Has("q") {
+		h.searchTasks(w, r, userID, filter, page.Limit-1)
 		return
 	}
-	items, err := h.store.ListTasks(userID, status)
+	items, err := h.taskSvc.List(userID, filter, page, callerTZ(r))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "store")
 		return
 	}
+	writePage(w, items, page, storage.TaskSortValues)
+}
+
+// searchTasks answers GET /tasks?q=. Results come best match first with rank and
+// highlight; they are cut at limit rather than paged, so sort and cursor don't apply.
+func (h *Handler) searchTasks(w http.ResponseWriter, r *http.Request, userID int64, filter storage.TaskFilter, limit int) {
+	q := r.URL.Query().Get("q")
+	if len(domain.SearchTerms(q)) == 0 {
+		writeError(w, http.StatusBadRequest, "q")
+		return
+	}
+	for _, param := range []string{"sort", "cursor"} {
+		if r.URL.Query().Get(param) != "" {
+			writeError(w, http.StatusBadRequest, param)
+			return
+		}
+	}
+	items, err := h.taskSvc.Search(userID, q, filter, callerTZ(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
 	response.JSON(w, http.StatusOK, map[string]any{"items": items})
 }
 
@@ -120,63 +243,67 @@ func (h *Handler) task(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "id")
 		return
 	}
-	item, err := h.store.GetTask(id)
+	if _, ok := h.ownedTask(w, r, id); !ok {
+		return
+	}
+	item, err := h.taskSvc.GetWithSubtasks(id, callerTZ(r))
 	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			writeError(w, http.StatusNotFound, "not_found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "store")
+		writeTaskError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", taskETag(item))
 	response.JSON(w, http.StatusOK, item)
 }
 
 func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID     int64      `json:"user_id"`
+		ProjectID  *int64     `json:"project_id"`
+		ParentID   *int64     `json:"parent_id"`
 		Text       string     `json:"text"`
 		Status     string     `json:"status"`
 		DueAt      *time.Time `json:"due_at"`
 		RemindAt   *time.Time `json:"remind_at"`
 		NotifiedAt *time.Time `json:"notified_at"`
+		Recurrence string     `json:"recurrence"`
+		Priority   int        `json:"priority"`
+		Tags       []string   `json:"tags"`
 	}
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "json")
 		return
 	}
-	if req.UserID <= 0 {
+	if req.UserID < 0 {
 		writeError(w, http.StatusBadRequest, "user_id")
 		return
 	}
-	req.Text = strings.TrimSpace(req.Text)
-	if req.Text == "" {
-		writeError(w, http.StatusBadRequest, "text")
-		return
-	}
-	if req.Status == "" {
-		req.Status = domain.TaskStatusActive
-	}
-	if !validTaskStatus(req.Status) {
-		writeError(w, http.StatusBadRequest, "status")
+	userID, ok := h.scopedUserID(w, r, req.UserID)
+	if !ok {
 		return
 	}
-	item, err := h.store.CreateTask(domain.Task{
-		UserID:     req.UserID,
+	item, err := h.taskSvc.Create(usecase.NewTask{
+		UserID:     userID,
+		ProjectID:  req.ProjectID,
+		ParentID:   req.ParentID,
 		Text:       req.Text,
 		Status:     req.Status,
+		Priority:   req.Priority,
+		Tags:       req.Tags,
 		DueAt:      req.DueAt,
 		RemindAt:   req.RemindAt,
 		NotifiedAt: req.NotifiedAt,
-	})
+		Recurrence: req.Recurrence,
+	}, callerTZ(r))
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
+			// The project and parent were checked, so it is the user that is missing.
 			writeError(w, http.StatusNotFound, "user")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "store")
+		writeTaskError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", taskETag(item))
 	response.JSON(w, http.StatusCreated, item)
 }
 
@@ -186,52 +313,149 @@ func (h *Handler) updateTask(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "id")
 		return
 	}
-	var req struct {
-		Text       *string    `json:"text"`
-		Status     *string    `json:"status"`
-		DueAt      *time.Time `json:"due_at"`
-		RemindAt   *time.Time `json:"remind_at"`
-		NotifiedAt *time.Time `json:"notified_at"`
+	policy, ok := subtaskPolicy(r.URL.Query().Get("subtasks"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "subtasks")
+		return
 	}
-	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, "json")
+	item, ok := h.ownedTask(w, r, id)
+	if !ok || !checkIfMatch(w, r, item) {
+		return
+	}
+	patch, ok := readTaskPatch(w, r, item)
+	if !ok {
+		return
+	}
+	if !applyTaskPatch(w, &item, patch) {
+		return
+	}
+	item, err = h.taskSvc.Update(item, policy, callerTZ(r))
+	if err != nil {
+		writeTaskError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", taskETag(item))
+	response.JSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) deleteTask(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	item, ok := h.ownedTask(w, r, id)
+	if !ok || !checkIfMatch(w, r, item) {
 		return
 	}
-	item, err := h.store.GetTask(id)
+	// Without If-Match nothing was read to act on, so there is no version to hold to.
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		version = item.Version
+	}
+	if err := h.taskSvc.Delete(id, version); err != nil {
+		writeTaskError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scopedUserID resolves the user a request acts for (requested is 0 when not given):
+// regular users default to themselves and may not name anyone else; admins must name
+// the user explicitly.
+func (h *Handler) scopedUserID(w http.ResponseWriter, r *http.Request, requested int64) (int64, bool) {
+	p := principal(r)
+	if requested == 0 {
+		if p.IsAdmin() {
+			writeError(w, http.StatusBadRequest, "user_id")
+			return 0, false
+		}
+		return p.ID, true
+	}
+	if !canAccessUser(p, requested) {
+		writeError(w, http.StatusForbidden, "forbidden")
+		return 0, false
+	}
+	return requested, true
+}
+
+// ownedTask loads a task visible to the principal, in the principal's timezone. Tasks of
+// other users are reported as not found so their ids cannot be probed.
+func (h *Handler) ownedTask(w http.ResponseWriter, r *http.Request, id int64) (domain.Task, bool) {
+	item, err := h.taskSvc.GetByID(id, callerTZ(r))
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found")
-			return
+			return domain.Task{}, false
 		}
+		writeError(w, http.StatusInternalServerError, "store")
+		return domain.Task{}, false
+	}
+	if !canAccessUser(principal(r), item.UserID) {
+		writeError(w, http.StatusNotFound, "not_found")
+		return domain.Task{}, false
+	}
+	return item, true
+}
+
+func (h *Handler) tokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.auth.ListTokens(userID)
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "store")
 		return
 	}
-	if req.Text != nil {
-		trimmed := strings.TrimSpace(*req.Text)
-		if trimmed == "" {
-			writeError(w, http.StatusBadRequest, "text")
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) createToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+		TTL  string `json:"ttl"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "ttl")
 			return
 		}
-		item.Text = trimmed
+		ttl = d
 	}
-	if req.Status != nil {
-		if !validTaskStatus(*req.Status) {
-			writeError(w, http.StatusBadRequest, "status")
+	plain, item, err := h.auth.IssueToken(userID, req.Name, ttl)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user")
 			return
 		}
-		item.Status = *req.Status
-	}
-	if req.DueAt != nil {
-		item.DueAt = req.DueAt
+		writeError(w, http.StatusInternalServerError, "store")
+		return
 	}
-	if req.RemindAt != nil {
-		item.RemindAt = req.RemindAt
+	response.JSON(w, http.StatusCreated, map[string]any{"token": plain, "item": item})
+}
+
+func (h *Handler) deleteToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.pathUser(w, r)
+	if !ok {
+		return
 	}
-	if req.NotifiedAt != nil {
-		item.NotifiedAt = req.NotifiedAt
+	tokenID, err := strconv.ParseInt(r.PathValue("token_id"), 10, 64)
+	if err != nil || tokenID <= 0 {
+		writeError(w, http.StatusBadRequest, "token_id")
+		return
 	}
-	item, err = h.store.UpdateTask(item)
-	if err != nil {
+	if err := h.auth.RevokeToken(userID, tokenID); err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found")
 			return
@@ -239,24 +463,34 @@ func (h *Handler) updateTask(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "store")
 		return
 	}
-	response.JSON(w, http.StatusOK, item)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *Handler) deleteTask(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) pathUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
 	id, err := parseID(r)
 	if err != nil || id <= 0 {
 		writeError(w, http.StatusBadRequest, "id")
+		return 0, false
+	}
+	if !canAccessUser(principal(r), id) {
+		writeError(w, http.StatusForbidden, "forbidden")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) reminders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !validReminderStatus(status) {
+		writeError(w, http.StatusBadRequest, "status")
 		return
 	}
-	if err := h.store.DeleteTask(id); err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			writeError(w, http.StatusNotFound, "not_found")
-			return
-		}
+	items, err := h.taskSvc.ListReminders(status, callerTZ(r))
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "store")
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
 }
 
 func decodeJSON(r *http.Request, dst any) error {
@@ -279,10 +513,82 @@ func parseInt64Query(r *http.Request, key string) (int64, error) {
 	return strconv.ParseInt(r.URL.Query().Get(key), 10, 64)
 }
 
+// parsePriority accepts "1".."4" as well as "p1".."P4".
+func parsePriority(raw string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(raw), "p"))
+	if err != nil || !domain.ValidPriority(p) {
+		return 0, errors.New("priority")
+	}
+	return p, nil
+}
+
+// splitList flattens repeated and comma-separated query values.
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
 func validTaskStatus(s string) bool {
 	return s == domain.TaskStatusActive || s == domain.TaskStatusDone
 }
 
+func validReminderStatus(s string) bool {
+	switch s {
+	case domain.ReminderStatusPending, domain.ReminderStatusSending, domain.ReminderStatusDelivered, domain.ReminderStatusFailed:
+		return true
+	}
+	return false
+}
+
 func writeError(w http.ResponseWriter, code int, msg string) {
 	response.JSON(w, code, map[string]string{"error": msg})
 }
+
+// callerTZ is the timezone responses are rendered in: the principal's own, or UTC for
+// the admin token.
+func callerTZ(r *http.Request) string {
+	if tz := principal(r).Timezone; tz != "" {
+		return tz
+	}
+	return "UTC"
+}
+
+// writeTaskError answers a failed TaskService call, naming the field at fault the way
+// request validation does.
+func writeTaskError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidText):
+		writeError(w, http.StatusBadRequest, "text")
+	case errors.Is(err, usecase.ErrInvalidStatus):
+		writeError(w, http.StatusBadRequest, "status")
+	case errors.Is(err, usecase.ErrInvalidPriority):
+		writeError(w, http.StatusBadRequest, "priority")
+	case errors.Is(err, usecase.ErrInvalidRecurrence):
+		writeError(w, http.StatusBadRequest, "recurrence")
+	case errors.Is(err, domain.ErrInvalidTag):
+		writeError(w, http.StatusBadRequest, "tags")
+	case errors.Is(err, usecase.ErrInvalidParent):
+		writeError(w, http.StatusBadRequest, "parent_id")
+	case errors.Is(err, usecase.ErrProjectNotFound):
+		writeError(w, http.StatusBadRequest, "project_id")
+	case errors.Is(err, usecase.ErrProjectArchived):
+		writeError(w, http.StatusConflict, "project_archived")
+	case errors.Is(err, usecase.ErrOpenSubtasks):
+		writeError(w, http.StatusConflict, "open_subtasks")
+	case errors.Is(err, usecase.ErrDependencyCycle):
+		writeError(w, http.StatusConflict, "cycle")
+	case errors.Is(err, storage.ErrStale):
+		writeStale(w, r)
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found")
+	default:
+		writeError(w, http.StatusInternalServerError, "store")
+	}
+}
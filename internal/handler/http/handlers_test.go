@@ -0,0 +1,289 @@
+package httpx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage/memory"
+	"example.com/yourapp/internal/usecase"
+)
+
+const adminToken = "static-admin"
+
+type testServer struct {
+	t     *testing.T
+	h     http.Handler
+	store *memory.Store
+	tasks *usecase.TaskService
+	auth  *usecase.AuthService
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	store := memory.New()
+	tasks := usecase.NewTaskService(store)
+	auth := usecase.NewAuthService(store, store, adminToken, "", time.Hour)
+	return &testServer{
+		t:     t,
+		h:     New(store, tasks, usecase.NewUserService(store), auth),
+		store: store,
+		tasks: tasks,
+		auth:  auth,
+	}
+}
+
+// user creates a user and returns it with a bearer token for it.
+func (s *testServer) user(telegramID int64, tz string) (domain.User, string) {
+	s.t.Helper()
+	u, err := s.store.CreateUser(domain.User{TelegramUserID: telegramID, ChatID: telegramID, Timezone: tz})
+	if err != nil {
+		s.t.Fatalf("create user: %v", err)
+	}
+	plain, _, err := s.auth.IssueToken(u.ID, "test", 0)
+	if err != nil {
+		s.t.Fatalf("issue token: %v", err)
+	}
+	return u, plain
+}
+
+func (s *testServer) task(in usecase.NewTask) domain.Task {
+	s.t.Helper()
+	created, err := s.tasks.Create(in, "UTC")
+	if err != nil {
+		s.t.Fatalf("create task: %v", err)
+	}
+	return created
+}
+
+// do sends the request; header holds extra headers as name, value pairs.
+func (s *testServer) do(method, path, token, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, code int, msg string) {
+	t.Helper()
+	if rec.Code != code {
+		t.Fatalf("expected %d, got %d: %s", code, rec.Code, rec.Body.String())
+	}
+	if got := decodeBody[map[string]string](t, rec)["error"]; got != msg {
+		t.Fatalf("expected error %q, got %q", msg, got)
+	}
+}
+
+func taskPath(id int64) string {
+	return "/tasks/" + strconv.FormatInt(id, 10)
+}
+
+func TestAuthRejectsMissingOrBadToken(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.user(1, "UTC")
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"unknown token", "Bearer tm_nope"},
+		{"wrong scheme", "Basic " + token},
+		{"empty bearer", "Bearer "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.h.ServeHTTP(rec, req)
+			expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+			if rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+	if rec := s.do(http.MethodGet, "/tasks", token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the valid token to pass, got %d", rec.Code)
+	}
+}
+
+func TestUsersRequiresAdmin(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.user(1, "UTC")
+	expectError(t, s.do(http.MethodGet, "/users", token, ""), http.StatusForbidden, "forbidden")
+	expectError(t, s.do(http.MethodPost, "/users", token, `{"telegram_user_id": 2, "chat_id": 2}`), http.StatusForbidden, "forbidden")
+	if rec := s.do(http.MethodGet, "/users", adminToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the admin to list users, got %d", rec.Code)
+	}
+}
+
+func TestOtherUsersTaskIsNotFound(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.user(1, "UTC")
+	_, bob := s.user(2, "UTC")
+	task := s.task(usecase.NewTask{UserID: alice.ID, Text: "secret"})
+	expectError(t, s.do(http.MethodGet, taskPath(task.ID), bob, ""), http.StatusNotFound, "not_found")
+	expectError(t, s.do(http.MethodPatch, taskPath(task.ID), bob, `{"text": "mine"}`), http.StatusNotFound, "not_found")
+	expectError(t, s.do(http.MethodDelete, taskPath(task.ID), bob, ""), http.StatusNotFound, "not_found")
+	if got, _ := s.store.GetTask(task.ID); got.Text != "secret" {
+		t.Fatalf("expected the task to be untouched, got %+v", got)
+	}
+	if rec := s.do(http.MethodGet, taskPath(task.ID), adminToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the admin to see any task, got %d", rec.Code)
+	}
+}
+
+func TestTasksUserIDScoping(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceToken := s.user(1, "UTC")
+	bob, bobToken := s.user(2, "UTC")
+	s.task(usecase.NewTask{UserID: alice.ID, Text: "alice"})
+	s.task(usecase.NewTask{UserID: bob.ID, Text: "bob"})
+	texts := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var out []string
+		for _, task := range decodeBody[struct{ Items []domain.Task }](t, rec).Items {
+			out = append(out, task.Text)
+		}
+		return out
+	}
+	aliceQuery := "/tasks?user_id=" + strconv.FormatInt(alice.ID, 10)
+
+	if got := texts(s.do(http.MethodGet, "/tasks", bobToken, "")); len(got) != 1 || got[0] != "bob" {
+		t.Fatalf("expected a user to default to their own tasks, got %v", got)
+	}
+	if got := texts(s.do(http.MethodGet, aliceQuery, aliceToken, "")); len(got) != 1 || got[0] != "alice" {
+		t.Fatalf("expected a user to name themselves, got %v", got)
+	}
+	expectError(t, s.do(http.MethodGet, aliceQuery, bobToken, ""), http.StatusForbidden, "forbidden")
+	expectError(t, s.do(http.MethodGet, "/tasks", adminToken, ""), http.StatusBadRequest, "user_id")
+	expectError(t, s.do(http.MethodGet, "/tasks?user_id=x", bobToken, ""), http.StatusBadRequest, "user_id")
+	if got := texts(s.do(http.MethodGet, aliceQuery, adminToken, "")); len(got) != 1 || got[0] != "alice" {
+		t.Fatalf("expected the admin to list the named user's tasks, got %v", got)
+	}
+	expectError(t, s.do(http.MethodPost, "/tasks", bobToken, `{"user_id": `+strconv.FormatInt(alice.ID, 10)+`, "text": "x"}`), http.StatusForbidden, "forbidden")
+}
+
+func TestPatchRemindAtClearsNotifiedAt(t *testing.T) {
+	s := newTestServer(t)
+	u, token := s.user(1, "UTC")
+	remind := time.Date(2030, 5, 4, 9, 0, 0, 0, time.UTC)
+	notified := remind.Add(time.Minute)
+	task := s.task(usecase.NewTask{UserID: u.ID, Text: "call", RemindAt: &remind, NotifiedAt: &notified})
+
+	rec := s.do(http.MethodPatch, taskPath(task.ID), token, `{"remind_at": "2030-05-05T09:00:00Z"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[map[string]any](t, s.do(http.MethodGet, taskPath(task.ID), token, ""))
+	if _, ok := got["notified_at"]; ok || got["reminder_status"] != domain.ReminderStatusPending {
+		t.Fatalf("expected a fresh reminder, got %v", got)
+	}
+	if got["remind_at"] != "2030-05-05T09:00:00Z" {
+		t.Fatalf("expected the new remind_at, got %v", got["remind_at"])
+	}
+}
+
+func TestTaskTimesInCallerZone(t *testing.T) {
+	s := newTestServer(t)
+	u, token := s.user(1, "Europe/Moscow")
+	const want = "2030-05-04T12:00:00+03:00"
+
+	rec := s.do(http.MethodPost, "/tasks", token, `{"text": "milk", "due_at": "2030-05-04T09:00:00Z"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[map[string]any](t, rec)
+	if created["due_at"] != want {
+		t.Fatalf("POST: expected due_at %s, got %v", want, created["due_at"])
+	}
+	id := int64(created["id"].(float64))
+	if got := decodeBody[map[string]any](t, s.do(http.MethodGet, taskPath(id), token, "")); got["due_at"] != want {
+		t.Fatalf("GET: expected due_at %s, got %v", want, got["due_at"])
+	}
+	rec = s.do(http.MethodPatch, taskPath(id), token, `{"remind_at": "2030-05-04T08:00:00+01:00"}`)
+	if got := decodeBody[map[string]any](t, rec); got["remind_at"] != "2030-05-04T10:00:00+03:00" {
+		t.Fatalf("PATCH: expected remind_at in Moscow time, got %v", got["remind_at"])
+	}
+	list := decodeBody[struct{ Items []map[string]any }](t, s.do(http.MethodGet, "/tasks", token, ""))
+	if len(list.Items) != 1 || list.Items[0]["due_at"] != want {
+		t.Fatalf("list: expected due_at %s, got %v", want, list.Items)
+	}
+	if stored, _ := s.store.GetTask(id); stored.UserID != u.ID || stored.DueAt.Location() != time.UTC {
+		t.Fatalf("expected the store to keep UTC, got %+v", stored)
+	}
+}
+
+func TestTaskValidationErrors(t *testing.T) {
+	s := newTestServer(t)
+	u, token := s.user(1, "UTC")
+	other, _ := s.user(2, "UTC")
+	foreign, err := s.store.CreateProject(domain.Project{UserID: other.ID, Name: "чужой"})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	parent := s.task(usecase.NewTask{UserID: u.ID, Text: "release"})
+	s.task(usecase.NewTask{UserID: u.ID, ParentID: &parent.ID, Text: "changelog"})
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+		err    string
+	}{
+		{"blank text on create", http.MethodPost, "/tasks", `{"text": "  "}`, http.StatusBadRequest, "text"},
+		{"blank text", http.MethodPatch, taskPath(parent.ID), `{"text": " "}`, http.StatusBadRequest, "text"},
+		{"unknown status", http.MethodPatch, taskPath(parent.ID), `{"status": "archived"}`, http.StatusBadRequest, "status"},
+		{"another user's project", http.MethodPatch, taskPath(parent.ID), `{"project_id": ` + strconv.FormatInt(foreign.ID, 10) + `}`, http.StatusBadRequest, "project_id"},
+		{"another user's project on create", http.MethodPost, "/tasks", `{"text": "x", "project_id": ` + strconv.FormatInt(foreign.ID, 10) + `}`, http.StatusBadRequest, "project_id"},
+		{"open subtasks", http.MethodPatch, taskPath(parent.ID), `{"status": "done"}`, http.StatusConflict, "open_subtasks"},
+		{"unknown subtask policy", http.MethodPatch, taskPath(parent.ID) + "?subtasks=drop", `{"status": "done"}`, http.StatusBadRequest, "subtasks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, s.do(tt.method, tt.path, token, tt.body), tt.code, tt.err)
+		})
+	}
+	if got, _ := s.store.GetTask(parent.ID); got.Version != parent.Version {
+		t.Fatalf("expected rejected requests to leave the task alone, got %+v", got)
+	}
+	rec := s.do(http.MethodPatch, taskPath(parent.ID)+"?subtasks=cascade", token, `{"status": "done"}`)
+	if rec.Code != http.StatusOK || decodeBody[domain.Task](t, rec).Status != domain.TaskStatusDone {
+		t.Fatalf("expected cascade to complete the task, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/pkg/response"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// parsePage reads the limit, sort and cursor parameters of a listing, reporting bad
+// ones into errs. The returned page asks for one row more than the client wants, so
+// writePage can tell whether there is a next page.
+func parsePage(q url.Values, fields map[string]storage.FieldKind, def []storage.SortKey, errs *queryErrors) storage.Page {
+	limit := defaultPageLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPageLimit {
+			errs.add("limit", "must be 1-"+strconv.Itoa(maxPageLimit))
+		} else {
+			limit = n
+		}
+	}
+	keys, err := storage.ParseSort(q.Get("sort"), fields, def)
+	if err != nil {
+		errs.add("sort", "unknown or repeated field")
+		return storage.Page{}
+	}
+	page := storage.Page{Sort: keys, Limit: limit + 1}
+	if raw := q.Get("cursor"); raw != "" {
+		after, err := storage.DecodeCursor(raw, keys, fields)
+		if err != nil {
+			errs.add("cursor", "garbled or made for another sort")
+		}
+		page.After = after
+	}
+	return page
+}
+
+// writePage responds with a page fetched with parsePage's page: the extra row, if it
+// came back, is dropped and the last row kept becomes next_cursor.
+func writePage[T any](w http.ResponseWriter, items []T, page storage.Page, values func(T, []storage.SortKey) []any) {
+	var next any
+	if len(items) >= page.Limit {
+		items = items[:page.Limit-1]
+		next = storage.EncodeCursor(page.Sort, values(items[len(items)-1], page.Sort))
+	}
+	if items == nil {
+		items = []T{}
+	}
+	response.JSON(w, http.StatusOK, map[string]any{"items": items, "next_cursor": next})
+}
@@ -0,0 +1,438 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+)
+
+// Media types PATCH /tasks/{id} accepts; plain application/json is read as a merge patch.
+const (
+	mediaMergePatch = "application/merge-patch+json"
+	mediaJSONPatch  = "application/json-patch+json"
+)
+
+// taskPatchFields are the members a task patch may touch, as in the task's JSON.
+var taskPatchFields = []string{
+	"text", "status", "priority", "due_at", "remind_at", "notified_at", "recurrence", "project_id", "tags",
+}
+
+// readTaskPatch reads a PATCH body as a merge patch (RFC 7396): a member that is absent
+// keeps the field, null clears it and any other value sets it. A JSON Patch (RFC 6902)
+// body is applied to the task's editable fields and turned into the equivalent merge
+// patch. It answers the request itself when the body is bad.
+func readTaskPatch(w http.ResponseWriter, r *http.Request, item domain.Task) (map[string]json.RawMessage, bool) {
+	mediaType := mediaMergePatch
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			writeError(w, http.StatusUnsupportedMediaType, "content_type")
+			return nil, false
+		}
+		mediaType = parsed
+	}
+	switch mediaType {
+	case mediaMergePatch, "application/json":
+		var patch map[string]json.RawMessage
+		if err := decodeJSON(r, &patch); err != nil || patch == nil {
+			writeError(w, http.StatusBadRequest, "json")
+			return nil, false
+		}
+		for name := range patch {
+			if !isTaskPatchField(name) {
+				writeError(w, http.StatusBadRequest, "json")
+				return nil, false
+			}
+		}
+		return patch, true
+	case mediaJSONPatch:
+		var ops []patchOp
+		if err := decodeJSON(r, &ops); err != nil {
+			writeError(w, http.StatusBadRequest, "json")
+			return nil, false
+		}
+		patch, err := jsonPatchToMerge(item, ops)
+		if err != nil {
+			if errors.Is(err, errPatchTest) {
+				writeError(w, http.StatusConflict, "patch_test")
+				return nil, false
+			}
+			writeError(w, http.StatusBadRequest, "patch")
+			return nil, false
+		}
+		return patch, true
+	}
+	writeError(w, http.StatusUnsupportedMediaType, "content_type")
+	return nil, false
+}
+
+func isTaskPatchField(name string) bool {
+	for _, f := range taskPatchFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTaskPatch writes a merge patch into item and answers the request itself when a
+// member has the wrong type. null clears a field; text and status can't be cleared, so
+// TaskService.Update rejects them, and priority goes back to the default. Values are
+// checked by TaskService.Update.
+func applyTaskPatch(w http.ResponseWriter, item *domain.Task, patch map[string]json.RawMessage) bool {
+	bad := func(field string) bool {
+		writeError(w, http.StatusBadRequest, field)
+		return false
+	}
+	for _, field := range taskPatchFields {
+		raw, ok := patch[field]
+		if !ok {
+			continue
+		}
+		null := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		switch field {
+		case "text":
+			var text string
+			if json.Unmarshal(raw, &text) != nil {
+				return bad(field)
+			}
+			item.Text = text
+		case "status":
+			var status string
+			if json.Unmarshal(raw, &status) != nil {
+				return bad(field)
+			}
+			item.Status = status
+		case "priority":
+			priority := domain.PriorityDefault
+			if !null && json.Unmarshal(raw, &priority) != nil {
+				return bad(field)
+			}
+			item.Priority = priority
+		case "due_at", "remind_at", "notified_at":
+			var at *time.Time
+			if json.Unmarshal(raw, &at) != nil {
+				return bad(field)
+			}
+			switch field {
+			case "due_at":
+				item.DueAt = at
+			case "remind_at":
+				item.RemindAt = at
+			default:
+				item.NotifiedAt = at
+			}
+		case "recurrence":
+			var rule string
+			if json.Unmarshal(raw, &rule) != nil {
+				return bad(field)
+			}
+			item.Recurrence = rule
+		case "project_id":
+			var projectID *int64
+			if json.Unmarshal(raw, &projectID) != nil {
+				return bad(field)
+			}
+			// 0 moves the task back to the inbox, like null.
+			if projectID != nil && *projectID == 0 {
+				projectID = nil
+			}
+			item.ProjectID = projectID
+		case "tags":
+			var names []string
+			if json.Unmarshal(raw, &names) != nil {
+				return bad(field)
+			}
+			item.Tags = names
+		}
+	}
+	return true
+}
+
+// patchOp is one operation of a JSON Patch.
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+var errPatchTest = errors.New("json patch test failed")
+
+// jsonPatchToMerge applies ops to a document holding item's editable fields (null for
+// unset ones) and returns the merge patch that makes the same change.
+func jsonPatchToMerge(item domain.Task, ops []patchOp) (map[string]json.RawMessage, error) {
+	// An empty list rather than null, so "/tags/-" can append to it.
+	tags := item.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	before := map[string]any{
+		"text":        item.Text,
+		"status":      item.Status,
+		"priority":    item.Priority,
+		"due_at":      item.DueAt,
+		"remind_at":   item.RemindAt,
+		"notified_at": item.NotifiedAt,
+		"recurrence":  nullIfEmpty(item.Recurrence),
+		"project_id":  item.ProjectID,
+		"tags":        tags,
+	}
+	data, err := json.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	// Compare in the patch's terms: numbers as float64, times as strings.
+	var doc any
+	var orig map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &orig); err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if doc, err = applyPatchOp(doc, op); err != nil {
+			return nil, err
+		}
+	}
+	after, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("json patch: not an object")
+	}
+	patch := make(map[string]json.RawMessage)
+	for name, v := range after {
+		if !isTaskPatchField(name) {
+			return nil, errors.New("json patch: unknown member " + name)
+		}
+		if reflect.DeepEqual(v, orig[name]) {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		patch[name] = raw
+	}
+	for _, name := range taskPatchFields {
+		if _, ok := after[name]; !ok {
+			patch[name] = json.RawMessage("null")
+		}
+	}
+	return patch, nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func applyPatchOp(doc any, op patchOp) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, errors.New("json patch: missing value")
+		}
+		var v any
+		err := json.Unmarshal(op.Value, &v)
+		return v, err
+	}
+	switch op.Op {
+	case "add", "replace":
+		v, err := value()
+		if err != nil || len(path) == 0 {
+			return v, err
+		}
+		return patchAt(doc, path, func(container any, key string) (any, error) {
+			if op.Op == "add" {
+				return addMember(container, key, v)
+			}
+			return replaceMember(container, key, v)
+		})
+	case "remove":
+		return patchAt(doc, path, removeMember)
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := lookup(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(got, v) {
+			return nil, errPatchTest
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := lookup(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, errors.New("json patch: move into itself")
+			}
+			if doc, err = patchAt(doc, from, removeMember); err != nil {
+				return nil, err
+			}
+		} else if v, err = deepCopy(v); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		return patchAt(doc, path, func(container any, key string) (any, error) {
+			return addMember(container, key, v)
+		})
+	}
+	return nil, errors.New("json patch: unknown op " + op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, errors.New("json pointer: must start with /")
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func lookup(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, errors.New("json pointer: no member " + token)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, errors.New("json pointer: not a container")
+		}
+	}
+	return doc, nil
+}
+
+// patchAt replaces the container holding the path's last token with what fn makes of it,
+// and returns the new document.
+func patchAt(doc any, path []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("json patch: can't remove the document")
+	}
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := lookup(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = patchAt(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	return replaceMember(doc, path[0], child)
+}
+
+func addMember(container any, key string, v any) (any, error) {
+	switch node := container.(type) {
+	case map[string]any:
+		node[key] = v
+		return node, nil
+	case []any:
+		if key == "-" {
+			return append(node, v), nil
+		}
+		i, err := arrayIndex(key, len(node))
+		if err != nil {
+			return nil, err
+		}
+		out := append(node[:i:i], v)
+		return append(out, node[i:]...), nil
+	}
+	return nil, errors.New("json pointer: not a container")
+}
+
+func replaceMember(container any, key string, v any) (any, error) {
+	switch node := container.(type) {
+	case map[string]any:
+		if _, ok := node[key]; !ok {
+			return nil, errors.New("json pointer: no member " + key)
+		}
+		node[key] = v
+		return node, nil
+	case []any:
+		i, err := arrayIndex(key, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = v
+		return node, nil
+	}
+	return nil, errors.New("json pointer: not a container")
+}
+
+func removeMember(container any, key string) (any, error) {
+	switch node := container.(type) {
+	case map[string]any:
+		if _, ok := node[key]; !ok {
+			return nil, errors.New("json pointer: no member " + key)
+		}
+		delete(node, key)
+		return node, nil
+	case []any:
+		i, err := arrayIndex(key, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		return append(node[:i:i], node[i+1:]...), nil
+	}
+	return nil, errors.New("json pointer: not a container")
+}
+
+// arrayIndex parses an array index token no larger than max; leading zeros aren't allowed.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.New("json pointer: bad array index " + token)
+	}
+	return i, nil
+}
+
+func deepCopy(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
@@ -0,0 +1,168 @@
+package httpx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/usecase"
+)
+
+func TestPatchTask(t *testing.T) {
+	due := time.Date(2030, 5, 4, 12, 0, 0, 0, time.UTC)
+	remind := due.Add(-time.Hour)
+	notified := remind.Add(time.Minute)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        int
+		err         string
+		check       func(t *testing.T, got domain.Task)
+	}{
+		{
+			name: "null clears due_at and remind_at",
+			body: `{"due_at": null, "remind_at": null}`,
+			code: http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if got.DueAt != nil || got.RemindAt != nil || got.NotifiedAt != nil {
+					t.Fatalf("expected no due, reminder or delivery, got %+v", got)
+				}
+			},
+		},
+		{
+			name: "a new remind_at resets notified_at",
+			body: `{"remind_at": "2030-05-05T09:00:00Z"}`,
+			code: http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if got.RemindAt == nil || !got.RemindAt.Equal(time.Date(2030, 5, 5, 9, 0, 0, 0, time.UTC)) {
+					t.Fatalf("expected the new reminder, got %v", got.RemindAt)
+				}
+				if got.NotifiedAt != nil || got.ReminderStatus != domain.ReminderStatusPending {
+					t.Fatalf("expected a fresh reminder, got %v %q", got.NotifiedAt, got.ReminderStatus)
+				}
+			},
+		},
+		{
+			name: "the same remind_at keeps notified_at",
+			body: `{"remind_at": "` + remind.Format(time.RFC3339) + `", "text": "renamed"}`,
+			code: http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if got.Text != "renamed" || got.NotifiedAt == nil || !got.NotifiedAt.Equal(notified) {
+					t.Fatalf("expected the delivery kept, got %+v", got)
+				}
+			},
+		},
+		{
+			name: "null notified_at arms the reminder again",
+			body: `{"notified_at": null}`,
+			code: http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if got.NotifiedAt != nil || got.ReminderStatus != domain.ReminderStatusPending {
+					t.Fatalf("expected a fresh reminder, got %v %q", got.NotifiedAt, got.ReminderStatus)
+				}
+			},
+		},
+		{
+			name:        "json patch",
+			contentType: mediaJSONPatch,
+			body:        `[{"op": "test", "path": "/text", "value": "milk"}, {"op": "add", "path": "/tags/-", "value": "home"}, {"op": "remove", "path": "/due_at"}]`,
+			code:        http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if len(got.Tags) != 1 || got.Tags[0] != "home" || got.DueAt != nil {
+					t.Fatalf("expected the tag added and the due date gone, got %+v", got)
+				}
+			},
+		},
+		{
+			name:        "a failed test",
+			contentType: mediaJSONPatch,
+			body:        `[{"op": "test", "path": "/text", "value": "bread"}, {"op": "replace", "path": "/text", "value": "x"}]`,
+			code:        http.StatusConflict,
+			err:         "patch_test",
+		},
+		{
+			name:        "remove of a missing member",
+			contentType: mediaJSONPatch,
+			body:        `[{"op": "remove", "path": "/colour"}]`,
+			code:        http.StatusBadRequest,
+			err:         "patch",
+		},
+		{
+			name:        "remove past the end of an array",
+			contentType: mediaJSONPatch,
+			body:        `[{"op": "remove", "path": "/tags/0"}]`,
+			code:        http.StatusBadRequest,
+			err:         "patch",
+		},
+		{
+			name:        "json patch adding an unknown member",
+			contentType: mediaJSONPatch,
+			body:        `[{"op": "add", "path": "/colour", "value": "red"}]`,
+			code:        http.StatusBadRequest,
+			err:         "patch",
+		},
+		{
+			name: "merge patch with an unknown member",
+			body: `{"text": "x", "colour": "red"}`,
+			code: http.StatusBadRequest,
+			err:  "json",
+		},
+		{
+			name:        "merge patch content type",
+			contentType: mediaMergePatch,
+			body:        `{"priority": null}`,
+			code:        http.StatusOK,
+			check: func(t *testing.T, got domain.Task) {
+				if got.Priority != domain.PriorityDefault {
+					t.Fatalf("expected the default priority, got %d", got.Priority)
+				}
+			},
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        `{"text": "x"}`,
+			code:        http.StatusUnsupportedMediaType,
+			err:         "content_type",
+		},
+		{
+			name:        "malformed content type",
+			contentType: "application/json; charset",
+			body:        `{"text": "x"}`,
+			code:        http.StatusUnsupportedMediaType,
+			err:         "content_type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			u, token := s.user(1, "UTC")
+			task := s.task(usecase.NewTask{
+				UserID:     u.ID,
+				Text:       "milk",
+				Priority:   domain.PriorityP1,
+				DueAt:      &due,
+				RemindAt:   &remind,
+				NotifiedAt: &notified,
+			})
+			var header []string
+			if tt.contentType != "" {
+				header = []string{"Content-Type", tt.contentType}
+			}
+			rec := s.do(http.MethodPatch, taskPath(task.ID), token, tt.body, header...)
+			if tt.err != "" {
+				expectError(t, rec, tt.code, tt.err)
+				if stored, _ := s.store.GetTask(task.ID); stored.Version != task.Version {
+					t.Fatalf("expected the task untouched, got %+v", stored)
+				}
+				return
+			}
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+			tt.check(t, decodeBody[domain.Task](t, rec))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/pkg/response"
+)
+
+func (h *Handler) projects(w http.ResponseWriter, r *http.Request) {
+	var requested int64
+	if r.URL.Query().Get("user_id") != "" {
+		id, err := parseInt64Query(r, "user_id")
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "user_id")
+			return
+		}
+		requested = id
+	}
+	userID, ok := h.scopedUserID(w, r, requested)
+	if !ok {
+		return
+	}
+	var includeArchived bool
+	switch r.URL.Query().Get("archived") {
+	case "", "false":
+	case "true":
+		includeArchived = true
+	default:
+		writeError(w, http.StatusBadRequest, "archived")
+		return
+	}
+	items, err := h.store.ListProjects(userID, includeArchived)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	if items == nil {
+		items = []domain.Project{}
+	}
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) project(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	item, ok := h.ownedProject(w, r, id)
+	if !ok {
+		return
+	}
+	response.JSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) createProject(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID int64  `json:"user_id"`
+		Name   string `json:"name"`
+		Color  string `json:"color"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	if req.UserID < 0 {
+		writeError(w, http.StatusBadRequest, "user_id")
+		return
+	}
+	userID, ok := h.scopedUserID(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	name, err := domain.NormalizeProjectName(req.Name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "name")
+		return
+	}
+	color, err := domain.NormalizeColor(req.Color)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "color")
+		return
+	}
+	item, err := h.store.CreateProject(domain.Project{UserID: userID, Name: name, Color: color})
+	if err != nil {
+		writeProjectError(w, err, "user")
+		return
+	}
+	response.JSON(w, http.StatusCreated, item)
+}
+
+func (h *Handler) updateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	var req struct {
+		Name     *string `json:"name"`
+		Color    *string `json:"color"`
+		Archived *bool   `json:"archived"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	item, ok := h.ownedProject(w, r, id)
+	if !ok {
+		return
+	}
+	if req.Name != nil {
+		name, err := domain.NormalizeProjectName(*req.Name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "name")
+			return
+		}
+		item.Name = name
+	}
+	if req.Color != nil {
+		color, err := domain.NormalizeColor(*req.Color)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "color")
+			return
+		}
+		item.Color = color
+	}
+	if req.Archived != nil {
+		item.Archived = *req.Archived
+	}
+	item, err = h.store.UpdateProject(item)
+	if err != nil {
+		writeProjectError(w, err, "not_found")
+		return
+	}
+	response.JSON(w, http.StatusOK, item)
+}
+
+// deleteProject moves the project's tasks to the inbox; ?tasks=delete removes them too.
+func (h *Handler) deleteProject(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	var withTasks bool
+	switch r.URL.Query().Get("tasks") {
+	case "", "move":
+	case "delete":
+		withTasks = true
+	default:
+		writeError(w, http.StatusBadRequest, "tasks")
+		return
+	}
+	if _, ok := h.ownedProject(w, r, id); !ok {
+		return
+	}
+	if err := h.store.DeleteProject(id, withTasks); err != nil {
+		writeProjectError(w, err, "not_found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) projectTasks(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !validTaskStatus(status) {
+		writeError(w, http.StatusBadRequest, "status")
+		return
+	}
+	project, ok := h.ownedProject(w, r, id)
+	if !ok {
+		return
+	}
+	items, err := h.taskSvc.List(project.UserID, storage.TaskFilter{Status: status, ProjectID: project.ID}, storage.Page{}, callerTZ(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// ownedProject loads a project visible to the principal; like ownedTask, foreign
+// projects are reported as not found.
+func (h *Handler) ownedProject(w http.ResponseWriter, r *http.Request, id int64) (domain.Project, bool) {
+	item, err := h.store.GetProject(id)
+	if err != nil {
+		writeProjectError(w, err, "not_found")
+		return domain.Project{}, false
+	}
+	if !canAccessUser(principal(r), item.UserID) {
+		writeError(w, http.StatusNotFound, "not_found")
+		return domain.Project{}, false
+	}
+	return item, true
+}
+
+func writeProjectError(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, notFound)
+	case errors.Is(err, storage.ErrConflict):
+		writeError(w, http.StatusConflict, "name")
+	default:
+		writeError(w, http.StatusInternalServerError, "store")
+	}
+}
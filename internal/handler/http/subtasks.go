@@ -0,0 +1,15 @@
+package httpx
+
+import "example.com/yourapp/internal/usecase"
+
+// subtaskPolicy reads the ?subtasks= rule for marking a task done: "require" (the
+// default) refuses while subtasks are open, "cascade" closes them too.
+func subtaskPolicy(mode string) (usecase.SubtaskPolicy, bool) {
+	switch mode {
+	case "", "require":
+		return usecase.RequireSubtasks, true
+	case "cascade":
+		return usecase.CascadeSubtasks, true
+	}
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/pkg/response"
+)
+
+func (h *Handler) tags(w http.ResponseWriter, r *http.Request) {
+	var requested int64
+	if r.URL.Query().Get("user_id") != "" {
+		id, err := parseInt64Query(r, "user_id")
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "user_id")
+			return
+		}
+		requested = id
+	}
+	userID, ok := h.scopedUserID(w, r, requested)
+	if !ok {
+		return
+	}
+	items, err := h.store.ListTags(userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "store")
+		return
+	}
+	if items == nil {
+		items = []domain.Tag{}
+	}
+	response.JSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) createTag(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID int64  `json:"user_id"`
+		Name   string `json:"name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	if req.UserID < 0 {
+		writeError(w, http.StatusBadRequest, "user_id")
+		return
+	}
+	userID, ok := h.scopedUserID(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	name, err := domain.NormalizeTag(req.Name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "name")
+		return
+	}
+	item, err := h.store.CreateTag(domain.Tag{UserID: userID, Name: name})
+	if err != nil {
+		writeTagError(w, err, "user")
+		return
+	}
+	response.JSON(w, http.StatusCreated, item)
+}
+
+func (h *Handler) updateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "json")
+		return
+	}
+	name, err := domain.NormalizeTag(req.Name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "name")
+		return
+	}
+	if _, ok := h.ownedTag(w, r, id); !ok {
+		return
+	}
+	item, err := h.store.RenameTag(id, name)
+	if err != nil {
+		writeTagError(w, err, "not_found")
+		return
+	}
+	response.JSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) deleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "id")
+		return
+	}
+	if _, ok := h.ownedTag(w, r, id); !ok {
+		return
+	}
+	if err := h.store.DeleteTag(id); err != nil {
+		writeTagError(w, err, "not_found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedTag loads a tag visible to the principal; like ownedTask, foreign tags are
+// reported as not found.
+func (h *Handler) ownedTag(w http.ResponseWriter, r *http.Request, id int64) (domain.Tag, bool) {
+	item, err := h.store.GetTag(id)
+	if err != nil {
+		writeTagError(w, err, "not_found")
+		return domain.Tag{}, false
+	}
+	if !canAccessUser(principal(r), item.UserID) {
+		writeError(w, http.StatusNotFound, "not_found")
+		return domain.Tag{}, false
+	}
+	return item, true
+}
+
+func writeTagError(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, notFound)
+	case errors.Is(err, storage.ErrConflict):
+		writeError(w, http.StatusConflict, "name")
+	case errors.Is(err, domain.ErrInvalidTag):
+		writeError(w, http.StatusBadRequest, "name")
+	default:
+		writeError(w, http.StatusInternalServerError, "store")
+	}
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "example.com/yourapp/internal/domain"
+
+// AttachmentRepository stores files attached to tasks. CreateAttachment returns
+// storage.ErrNotFound for a missing task; attachments go away with their task.
+type AttachmentRepository interface {
+	CreateAttachment(attachment domain.Attachment) (domain.Attachment, error)
+	// ListAttachments orders by id, i.e. in the order the files were attached.
+	ListAttachments(taskID int64) ([]domain.Attachment, error)
+}
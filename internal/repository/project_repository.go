@@ -0,0 +1,14 @@
+package repository
+
+import "example.com/yourapp/internal/domain"
+
+// ProjectRepository stores projects. Names are unique per user (storage.ErrConflict).
+// DeleteProject moves the project's tasks to the inbox, or deletes them when withTasks
+// is set.
+type ProjectRepository interface {
+	ListProjects(userID int64, includeArchived bool) ([]domain.Project, error)
+	GetProject(id int64) (domain.Project, error)
+	CreateProject(project domain.Project) (domain.Project, error)
+	UpdateProject(project domain.Project) (domain.Project, error)
+	DeleteProject(id int64, withTasks bool) error
+}
@@ -4,17 +4,53 @@ import (
 	"time"
 
 	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
 )
 
-// TaskRepository stores tasks in UTC and returns them in UTC.
-// ListDueForNotify should mark returned tasks as notified to keep notifications idempotent.
+// TaskRepository stores tasks in UTC and returns them in UTC. A task's project and parent
+// task have to belong to the task's user; Create returns storage.ErrNotFound otherwise.
+// Deleting a task deletes its subtasks.
+// ListActive leaves out tasks of archived projects, and ListDueForNotify does not hand
+// out their reminders, nor those of blocked tasks.
+// ListDueForNotify claims due reminders: it moves them to the sending state with a lease
+// and returns them, so the same reminder is not handed out again until the lease expires.
+// Callers must report the outcome via MarkNotified or MarkNotifyFailed; a nil retryAt
+// moves the reminder to the failed (dead-letter) state.
 type TaskRepository interface {
 	Create(task domain.Task) (domain.Task, error)
 	ListActive(userID int64) ([]domain.Task, error)
+	// ListTasks returns one page; see storage.Page.
+	ListTasks(userID int64, f storage.TaskFilter, p storage.Page) ([]domain.Task, error)
 	GetByID(id int64) (domain.Task, error)
 	MarkDone(id int64) (domain.Task, error)
+	// CompleteTask marks the task done and writes c in the same transaction, unless the
+	// task is done already; the created next occurrence is returned only in the first case.
+	CompleteTask(id int64, c storage.Completion) (done domain.Task, next *domain.Task, err error)
+	// UpdateTask writes the editable fields and returns the stored row. It returns
+	// storage.ErrStale unless task.Version is still the stored one; 0 skips the check.
+	UpdateTask(task domain.Task) (domain.Task, error)
+	// UpdateAndComplete is UpdateTask that also writes c in the same transaction when the
+	// write moves the task to done; the created next occurrence is returned only then.
+	UpdateAndComplete(task domain.Task, c storage.Completion) (updated domain.Task, next *domain.Task, err error)
 	Delete(id int64) error
+	// DeleteTask is Delete with the version check of UpdateTask.
+	DeleteTask(id, version int64) error
 	SetDue(id int64, dueAt *time.Time) (domain.Task, error)
 	SetRemind(id int64, remindAt *time.Time) (domain.Task, error)
-	ListDueForNotify(now time.Time) ([]domain.Task, error)
+	SetRecurrence(id int64, rule string) (domain.Task, error)
+	ListDueForNotify(now time.Time, lease time.Duration) ([]domain.Task, error)
+	MarkNotified(id int64, at time.Time) error
+	MarkNotifyFailed(id int64, reason string, retryAt *time.Time) error
+	ListReminders(status string) ([]domain.Task, error)
+	// ListTags returns the user's tags ordered by name.
+	ListTags(userID int64) ([]domain.Tag, error)
+	GetProject(id int64) (domain.Project, error)
+	ListSubtasks(parentID int64) ([]domain.Task, error)
+	CompleteSubtasks(parentID int64) error
+	// AddDependency returns storage.ErrCycle when the tasks would wait for each other.
+	AddDependency(taskID, blockedByID int64) error
+	RemoveDependency(taskID, blockedByID int64) error
+	ListDependencies(userID int64) ([]domain.Dependency, error)
+	// SearchTasks returns tasks whose text matches every word of query, best first.
+	SearchTasks(userID int64, query string, f storage.TaskFilter) ([]domain.SearchResult, error)
 }
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"example.com/yourapp/internal/domain"
+)
+
+// TokenRepository stores API tokens by hash; plaintext tokens are never persisted.
+// GetUserByTokenHash returns storage.ErrNotFound for unknown or expired tokens.
+type TokenRepository interface {
+	CreateToken(token domain.APIToken) (domain.APIToken, error)
+	GetUserByTokenHash(hash string, now time.Time) (domain.User, error)
+	ListTokens(userID int64) ([]domain.APIToken, error)
+	DeleteToken(userID, id int64) error
+	// DeleteExpiredTokens removes every token that has expired by now.
+	DeleteExpiredTokens(now time.Time) error
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "time"
+
+// UpdateRepository remembers the Telegram updates the bot has handled, so an update that
+// Telegram redelivers is handled once however many replicas receive webhooks. Ids older
+// than storage.UpdateRetention are forgotten.
+type UpdateRepository interface {
+	// MarkUpdateSeen records the update id and reports whether it was new.
+	MarkUpdateSeen(id int64, now time.Time) (bool, error)
+}
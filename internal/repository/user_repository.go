@@ -1,8 +1,19 @@
 package repository
 
-import "example.com/yourapp/internal/domain"
+import (
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
 
 type UserRepository interface {
+	GetUser(id int64) (domain.User, error)
+	// ListUsers returns one page; see storage.Page.
+	ListUsers(p storage.Page) ([]domain.User, error)
 	GetByTelegramID(telegramUserID int64) (domain.User, error)
+	// CreateUser returns storage.ErrConflict if the Telegram id is already taken.
 	CreateUser(user domain.User) (domain.User, error)
+	UpdateTimezone(id int64, tz string) (domain.User, error)
+	// SetCurrentProject returns storage.ErrNotFound unless projectID is nil or one of the
+	// user's projects.
+	SetCurrentProject(id int64, projectID *int64) (domain.User, error)
 }
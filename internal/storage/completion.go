@@ -0,0 +1,15 @@
+package storage
+
+import "example.com/yourapp/internal/domain"
+
+// Completion is what CompleteTask writes along with marking a task done. It happens in
+// the same transaction, and only when the call is the one that moves the task to done,
+// so two concurrent completions never both act on it.
+type Completion struct {
+	// Subtasks closes the task's open subtasks as well.
+	Subtasks bool
+	// Next is the next occurrence of a recurring task. NextSubtasks become its subtasks;
+	// their ParentID and ProjectID are taken from the created occurrence.
+	Next         *domain.Task
+	NextSubtasks []domain.Task
+}
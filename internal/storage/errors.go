@@ -1,7 +1,21 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write would break a uniqueness rule, e.g. a second
+	// user with the same Telegram id.
+	ErrConflict = errors.New("conflict")
+	// ErrCycle is returned when a task dependency would make tasks wait for each other.
+	ErrCycle = errors.New("dependency cycle")
+	// ErrStale is returned when a task was written since the version the caller read.
+	ErrStale = errors.New("stale version")
 )
+
+// UpdateRetention is how long seen Telegram update ids are kept. Telegram stops
+// redelivering an update after a day.
+const UpdateRetention = 24 * time.Hour
@@ -0,0 +1,35 @@
+package storage
+
+import "time"
+
+// ReminderNone selects tasks without a reminder in TaskFilter.Reminder.
+const ReminderNone = "none"
+
+// TaskFilter narrows a task listing; zero values match everything. Time windows include
+// their After bound and exclude their Before bound.
+type TaskFilter struct {
+	Status    string
+	Priority  int
+	ProjectID int64
+	// HideArchived drops tasks that belong to an archived project.
+	HideArchived bool
+	// Tags matches tasks carrying any of the names, or all of them when AllTags is set.
+	Tags    []string
+	AllTags bool
+	// DueAfter and DueBefore bound the due date; either drops tasks without one.
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	// OverdueAt keeps active tasks that were due before it.
+	OverdueAt *time.Time
+	// NoDue keeps tasks without a due date when true and tasks with one when false.
+	NoDue *bool
+	// Reminder is a domain.ReminderStatus* value, which also requires a reminder to be
+	// set, or ReminderNone.
+	Reminder      string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	// TextContains keeps tasks whose text contains it, ignoring case.
+	TextContains string
+}
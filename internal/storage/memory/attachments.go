@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"sort"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func (s *Store) CreateAttachment(a domain.Attachment) (domain.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[a.TaskID]; !ok {
+		return domain.Attachment{}, storage.ErrNotFound
+	}
+	a.ID = s.nextAttachmentID
+	if err := s.commit(record{Op: opPutAttachment, Attachment: &a}); err != nil {
+		return domain.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (s *Store) ListAttachments(taskID int64) ([]domain.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Attachment, 0)
+	for _, a := range s.attachments {
+		if a.TaskID == taskID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// AddDependency records that taskID is blocked by blockedByID. Both tasks have to belong
+// to the same user (storage.ErrNotFound otherwise); an edge that would close a cycle is
+// refused with storage.ErrCycle. Adding an existing edge is a no-op.
+func (s *Store) AddDependency(taskID, blockedByID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	blocker, ok := s.tasks[blockedByID]
+	if !ok || blocker.UserID != task.UserID {
+		return storage.ErrNotFound
+	}
+	if domain.CreatesCycle(s.userDependencies(task.UserID), taskID, blockedByID) {
+		return storage.ErrCycle
+	}
+	d := domain.Dependency{TaskID: taskID, BlockedByID: blockedByID}
+	if s.deps[d] {
+		return nil
+	}
+	return s.commit(record{Op: opPutDependency, Dependency: &d})
+}
+
+func (s *Store) RemoveDependency(taskID, blockedByID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := domain.Dependency{TaskID: taskID, BlockedByID: blockedByID}
+	if !s.deps[d] {
+		return storage.ErrNotFound
+	}
+	return s.commit(record{Op: opDeleteDependency, Dependency: &d})
+}
+
+// ListDependencies returns every dependency between the user's tasks.
+func (s *Store) ListDependencies(userID int64) ([]domain.Dependency, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userDependencies(userID), nil
+}
+
+// userDependencies returns the user's edges ordered by task, then blocker. Callers hold
+// s.mu.
+func (s *Store) userDependencies(userID int64) []domain.Dependency {
+	out := make([]domain.Dependency, 0)
+	for d := range s.deps {
+		if s.tasks[d.TaskID].UserID == userID {
+			out = append(out, d)
+		}
+	}
+	sortDependencies(out)
+	return out
+}
+
+// withBlockers fills in BlockedBy and Blocked. Callers hold s.mu.
+func (s *Store) withBlockers(t domain.Task) domain.Task {
+	t.BlockedBy = nil
+	t.Blocked = false
+	if ids := s.blockers[t.ID]; len(ids) > 0 {
+		t.BlockedBy = slices.Clone(ids)
+	}
+	for _, id := range t.BlockedBy {
+		if s.tasks[id].Status == domain.TaskStatusActive {
+			t.Blocked = true
+			break
+		}
+	}
+	return t
+}
+
+// indexDependency adds d to deps and blockers. Callers hold s.mu.
+func (s *Store) indexDependency(d domain.Dependency) {
+	if s.deps[d] {
+		return
+	}
+	s.deps[d] = true
+	ids := s.blockers[d.TaskID]
+	i, _ := slices.BinarySearch(ids, d.BlockedByID)
+	s.blockers[d.TaskID] = slices.Insert(ids, i, d.BlockedByID)
+}
+
+// unindexDependency removes d from deps and blockers. Callers hold s.mu.
+func (s *Store) unindexDependency(d domain.Dependency) {
+	if !s.deps[d] {
+		return
+	}
+	delete(s.deps, d)
+	ids := s.blockers[d.TaskID]
+	if i, ok := slices.BinarySearch(ids, d.BlockedByID); ok {
+		ids = slices.Delete(ids, i, i+1)
+	}
+	if len(ids) == 0 {
+		delete(s.blockers, d.TaskID)
+	} else {
+		s.blockers[d.TaskID] = ids
+	}
+}
+
+func sortDependencies(deps []domain.Dependency) {
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].TaskID != deps[j].TaskID {
+			return deps[i].TaskID < deps[j].TaskID
+		}
+		return deps[i].BlockedByID < deps[j].BlockedByID
+	})
+}
@@ -0,0 +1,505 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile      = "wal.log"
+	// compactEvery is how many log records are written before the state is folded into a
+	// new snapshot and the log starts over.
+	compactEvery = 1000
+	// recordHeaderSize is the length and CRC-32 of the payload, both uint32 little endian.
+	recordHeaderSize = 8
+	// maxRecordSize bounds a payload so a garbled length is not taken at face value.
+	maxRecordSize = 16 << 20
+)
+
+const (
+	opPutUser          = "put_user"
+	opPutTask          = "put_task"
+	opDeleteTask       = "delete_task"
+	opPutToken         = "put_token"
+	opDeleteToken      = "delete_token"
+	opPutTag           = "put_tag"
+	opDeleteTag        = "delete_tag"
+	opPutProject       = "put_project"
+	opDeleteProject    = "delete_project"
+	opPutDependency    = "put_dependency"
+	opDeleteDependency = "delete_dependency"
+	opPutAttachment    = "put_attachment"
+	opPutUpdate        = "put_update"
+	// opBatch applies Records together; they are logged as one record, so a crash keeps
+	// all or none of them.
+	opBatch = "batch"
+)
+
+// record is one mutation in the write-ahead log. Records carry the full new state of an
+// entity rather than a diff, so replaying a record twice is harmless.
+type record struct {
+	Op      string          `json:"op"`
+	User    *domain.User    `json:"user,omitempty"`
+	Task    *domain.Task    `json:"task,omitempty"`
+	Token   *storedToken    `json:"token,omitempty"`
+	Tag     *domain.Tag     `json:"tag,omitempty"`
+	Project *domain.Project `json:"project,omitempty"`
+	// Dependency is the edge for put_dependency and delete_dependency.
+	Dependency *domain.Dependency `json:"dependency,omitempty"`
+	Attachment *domain.Attachment `json:"attachment,omitempty"`
+	ID         int64              `json:"id,omitempty"`
+	// At is when the update put_update records arrived.
+	At      *time.Time `json:"at,omitempty"`
+	Records []record   `json:"records,omitempty"`
+}
+
+// storedToken keeps the hash, which domain.APIToken hides from JSON.
+type storedToken struct {
+	domain.APIToken
+	Hash string `json:"hash"`
+}
+
+type snapshot struct {
+	NextUserID       int64               `json:"next_user_id"`
+	NextTaskID       int64               `json:"next_task_id"`
+	NextTokenID      int64               `json:"next_token_id"`
+	NextTagID        int64               `json:"next_tag_id"`
+	NextProjectID    int64               `json:"next_project_id"`
+	NextAttachmentID int64               `json:"next_attachment_id"`
+	Users            []domain.User       `json:"users"`
+	Tasks            []domain.Task       `json:"tasks"`
+	Tokens           []storedToken       `json:"tokens"`
+	Tags             []domain.Tag        `json:"tags"`
+	Projects         []domain.Project    `json:"projects"`
+	Dependencies     []domain.Dependency `json:"dependencies"`
+	Attachments      []domain.Attachment `json:"attachments"`
+	Updates          []seenUpdate        `json:"updates,omitempty"`
+}
+
+type seenUpdate struct {
+	ID int64     `json:"id"`
+	At time.Time `json:"at"`
+}
+
+type journal struct {
+	dir     string
+	wal     logFile
+	records int
+	// failed is set when a failed append could not be rolled back; the log may end in a
+	// partial record then, so further appends are refused.
+	failed error
+}
+
+// logFile is the part of *os.File the journal writes through.
+type logFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// Open returns a store that survives restarts: every mutation is appended to a log in dir
+// and synced before it becomes visible, and the log is compacted into a snapshot every
+// compactEvery records. On startup the snapshot is loaded and the log replayed; a torn
+// record at the end of the log (a crash mid-write) is dropped.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := New()
+	if err := s.loadSnapshot(filepath.Join(dir, snapshotFile)); err != nil {
+		return nil, err
+	}
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := s.replay(wal)
+	if err != nil {
+		_ = wal.Close()
+		return nil, err
+	}
+	if err := wal.Truncate(valid); err != nil {
+		_ = wal.Close()
+		return nil, err
+	}
+	s.journal = &journal{dir: dir, wal: wal}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.compact(); err != nil {
+		_ = wal.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close writes a final snapshot. It is a no-op for a volatile store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return nil
+	}
+	err := s.compact()
+	if cerr := s.journal.wal.Close(); err == nil {
+		err = cerr
+	}
+	s.journal = nil
+	return err
+}
+
+// commit logs r (when durable) and applies it. Callers hold s.mu.
+func (s *Store) commit(r record) error {
+	if s.journal != nil {
+		if err := s.journal.append(r); err != nil {
+			return err
+		}
+	}
+	s.apply(r)
+	if s.journal != nil && s.journal.records >= compactEvery {
+		// The record is already durable in the log; a failed compaction is retried on
+		// the next write.
+		_ = s.compact()
+	}
+	return nil
+}
+
+func (s *Store) apply(r record) {
+	switch r.Op {
+	case opPutUser:
+		s.users[r.User.ID] = *r.User
+		s.nextUserID = max(s.nextUserID, r.User.ID+1)
+	case opPutTask:
+		if r.Task.Priority == 0 {
+			// Written before priorities existed.
+			r.Task.Priority = domain.PriorityDefault
+		}
+		if r.Task.Version == 0 {
+			// Written before versions existed.
+			r.Task.Version = 1
+		}
+		if old, ok := s.tasks[r.Task.ID]; ok {
+			s.unindexTask(old)
+		}
+		s.indexTask(*r.Task)
+		s.tasks[r.Task.ID] = *r.Task
+		s.nextTaskID = max(s.nextTaskID, r.Task.ID+1)
+	case opDeleteTask:
+		if old, ok := s.tasks[r.ID]; ok {
+			s.unindexTask(old)
+		}
+		delete(s.tasks, r.ID)
+		for d := range s.deps {
+			if d.TaskID == r.ID || d.BlockedByID == r.ID {
+				s.unindexDependency(d)
+			}
+		}
+		for id, a := range s.attachments {
+			if a.TaskID == r.ID {
+				delete(s.attachments, id)
+			}
+		}
+	case opPutToken:
+		t := r.Token.APIToken
+		t.Hash = r.Token.Hash
+		s.tokens[t.ID] = t
+		s.tokenIDs[t.Hash] = t.ID
+		s.nextTokenID = max(s.nextTokenID, t.ID+1)
+	case opDeleteToken:
+		delete(s.tokenIDs, s.tokens[r.ID].Hash)
+		delete(s.tokens, r.ID)
+	case opPutTag:
+		s.tags[r.Tag.ID] = *r.Tag
+		s.nextTagID = max(s.nextTagID, r.Tag.ID+1)
+	case opDeleteTag:
+		delete(s.tags, r.ID)
+	case opPutProject:
+		s.projects[r.Project.ID] = *r.Project
+		s.nextProjectID = max(s.nextProjectID, r.Project.ID+1)
+	case opDeleteProject:
+		delete(s.projects, r.ID)
+	case opPutDependency:
+		s.indexDependency(*r.Dependency)
+	case opDeleteDependency:
+		s.unindexDependency(*r.Dependency)
+	case opPutAttachment:
+		s.attachments[r.Attachment.ID] = *r.Attachment
+		s.nextAttachmentID = max(s.nextAttachmentID, r.Attachment.ID+1)
+	case opPutUpdate:
+		if _, ok := s.updates[r.ID]; !ok {
+			s.updateOrder = append(s.updateOrder, r.ID)
+		}
+		s.updates[r.ID] = *r.At
+	case opBatch:
+		for _, r := range r.Records {
+			s.apply(r)
+		}
+	}
+}
+
+func (j *journal) append(r record) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+	if j.failed != nil {
+		return fmt.Errorf("memory journal unusable: %w", j.failed)
+	}
+	offset, err := j.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = j.wal.Write(buf)
+	if err == nil {
+		err = j.wal.Sync()
+	}
+	if err != nil {
+		// Cut off whatever part of the record made it, so the next append doesn't land
+		// behind a torn record that replay would stop at.
+		if rerr := j.rollback(offset); rerr != nil {
+			j.failed = rerr
+		}
+		return err
+	}
+	j.records++
+	return nil
+}
+
+func (j *journal) rollback(offset int64) error {
+	if err := j.wal.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := j.wal.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return j.wal.Sync()
+}
+
+// replay applies the log and returns the offset just past the last intact record.
+func (s *Store) replay(wal *os.File) (int64, error) {
+	if _, err := wal.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(wal)
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			// EOF is a clean end; ErrUnexpectedEOF is a torn header.
+			return offset, nil
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		if size > maxRecordSize {
+			return offset, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(payload) != sum {
+			return offset, nil
+		}
+		var rec record
+		if err := json.Unmarshal(payload, &rec); err != nil || !rec.valid() {
+			return offset, nil
+		}
+		s.apply(rec)
+		offset += recordHeaderSize + int64(size)
+	}
+}
+
+func (r record) valid() bool {
+	switch r.Op {
+	case opPutUser:
+		return r.User != nil
+	case opPutTask:
+		return r.Task != nil
+	case opPutToken:
+		return r.Token != nil
+	case opPutTag:
+		return r.Tag != nil
+	case opPutProject:
+		return r.Project != nil
+	case opPutDependency, opDeleteDependency:
+		return r.Dependency != nil
+	case opPutAttachment:
+		return r.Attachment != nil
+	case opPutUpdate:
+		return r.ID != 0 && r.At != nil
+	case opDeleteTask, opDeleteToken, opDeleteTag, opDeleteProject:
+		return r.ID != 0
+	case opBatch:
+		for _, r := range r.Records {
+			if r.Op == opBatch || !r.valid() {
+				return false
+			}
+		}
+		return len(r.Records) > 0
+	}
+	return false
+}
+
+func (s *Store) loadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("memory snapshot %s: %w", path, err)
+	}
+	for i := range snap.Users {
+		s.apply(record{Op: opPutUser, User: &snap.Users[i]})
+	}
+	for i := range snap.Projects {
+		s.apply(record{Op: opPutProject, Project: &snap.Projects[i]})
+	}
+	for i := range snap.Tasks {
+		s.apply(record{Op: opPutTask, Task: &snap.Tasks[i]})
+	}
+	for i := range snap.Tokens {
+		s.apply(record{Op: opPutToken, Token: &snap.Tokens[i]})
+	}
+	for i := range snap.Tags {
+		s.apply(record{Op: opPutTag, Tag: &snap.Tags[i]})
+	}
+	for i := range snap.Dependencies {
+		s.apply(record{Op: opPutDependency, Dependency: &snap.Dependencies[i]})
+	}
+	for i := range snap.Attachments {
+		s.apply(record{Op: opPutAttachment, Attachment: &snap.Attachments[i]})
+	}
+	for i := range snap.Updates {
+		s.apply(record{Op: opPutUpdate, ID: snap.Updates[i].ID, At: &snap.Updates[i].At})
+	}
+	// Counters cover deleted rows too, so ids are never reused.
+	s.nextUserID = max(s.nextUserID, snap.NextUserID)
+	s.nextTaskID = max(s.nextTaskID, snap.NextTaskID)
+	s.nextTokenID = max(s.nextTokenID, snap.NextTokenID)
+	s.nextTagID = max(s.nextTagID, snap.NextTagID)
+	s.nextProjectID = max(s.nextProjectID, snap.NextProjectID)
+	s.nextAttachmentID = max(s.nextAttachmentID, snap.NextAttachmentID)
+	return nil
+}
+
+// compact writes the current state to a new snapshot and empties the log. The snapshot
+// is renamed into place before the log is truncated, so a crash in between only means
+// replaying records that are already in the snapshot. Callers hold s.mu.
+func (s *Store) compact() error {
+	snap := snapshot{
+		NextUserID:       s.nextUserID,
+		NextTaskID:       s.nextTaskID,
+		NextTokenID:      s.nextTokenID,
+		NextTagID:        s.nextTagID,
+		NextProjectID:    s.nextProjectID,
+		NextAttachmentID: s.nextAttachmentID,
+		Users:            make([]domain.User, 0, len(s.users)),
+		Tasks:            make([]domain.Task, 0, len(s.tasks)),
+		Tokens:           make([]storedToken, 0, len(s.tokens)),
+		Tags:             make([]domain.Tag, 0, len(s.tags)),
+		Projects:         make([]domain.Project, 0, len(s.projects)),
+		Dependencies:     make([]domain.Dependency, 0, len(s.deps)),
+		Attachments:      make([]domain.Attachment, 0, len(s.attachments)),
+	}
+	for _, u := range s.users {
+		snap.Users = append(snap.Users, u)
+	}
+	for _, t := range s.tasks {
+		snap.Tasks = append(snap.Tasks, t)
+	}
+	for _, t := range s.tokens {
+		snap.Tokens = append(snap.Tokens, storedToken{APIToken: t, Hash: t.Hash})
+	}
+	for _, t := range s.tags {
+		snap.Tags = append(snap.Tags, t)
+	}
+	for _, p := range s.projects {
+		snap.Projects = append(snap.Projects, p)
+	}
+	for d := range s.deps {
+		snap.Dependencies = append(snap.Dependencies, d)
+	}
+	for _, a := range s.attachments {
+		snap.Attachments = append(snap.Attachments, a)
+	}
+	for _, id := range s.updateOrder {
+		snap.Updates = append(snap.Updates, seenUpdate{ID: id, At: s.updates[id]})
+	}
+	sortDependencies(snap.Dependencies)
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+	sort.Slice(snap.Tasks, func(i, j int) bool { return snap.Tasks[i].ID < snap.Tasks[j].ID })
+	sort.Slice(snap.Tokens, func(i, j int) bool { return snap.Tokens[i].ID < snap.Tokens[j].ID })
+	sort.Slice(snap.Tags, func(i, j int) bool { return snap.Tags[i].ID < snap.Tags[j].ID })
+	sort.Slice(snap.Projects, func(i, j int) bool { return snap.Projects[i].ID < snap.Projects[j].ID })
+	sort.Slice(snap.Attachments, func(i, j int) bool { return snap.Attachments[i].ID < snap.Attachments[j].ID })
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	j := s.journal
+	path := filepath.Join(j.dir, snapshotFile)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+	if err := j.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.records = 0
+	return j.wal.Sync()
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
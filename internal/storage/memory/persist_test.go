@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func mustOpen(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return s
+}
+
+// crash drops the store without the final snapshot Close would write.
+func crash(s *Store) {
+	_ = s.journal.wal.Close()
+	s.journal = nil
+}
+
+func TestOpen_ReplaysLogAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, err := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	remindAt := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	first, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "first", RemindAt: &remindAt})
+	second, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "second"})
+	if _, err := s.MarkDone(first.ID); err != nil {
+		t.Fatalf("mark done: %v", err)
+	}
+	if err := s.DeleteTask(second.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.CreateToken(domain.APIToken{UserID: user.ID, Hash: "h1"}); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	defer s.Close()
+	got, err := s.GetTask(first.ID)
+	if err != nil || got.Status != domain.TaskStatusDone || got.RemindAt == nil || !got.RemindAt.Equal(remindAt) {
+		t.Fatalf("expected replayed task, got %+v %v", got, err)
+	}
+	if _, err := s.GetTask(second.ID); err == nil {
+		t.Fatalf("expected deleted task to stay deleted")
+	}
+	if u, err := s.GetUserByTokenHash("h1", remindAt); err != nil || u.ID != user.ID {
+		t.Fatalf("expected token hash to survive, got %+v %v", u, err)
+	}
+	third, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "third"})
+	if err != nil || third.ID <= second.ID {
+		t.Fatalf("expected ids not to be reused, got %d after %d (%v)", third.ID, second.ID, err)
+	}
+}
+
+func TestOpen_DropsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	kept, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "kept"})
+	walPath := filepath.Join(dir, walFile)
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	intact := info.Size()
+	if _, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "torn"}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	crash(s)
+
+	full, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	// Cut inside the header and inside the payload of the last record.
+	for _, cut := range []int64{intact + 3, intact + recordHeaderSize + 5, full.Size() - 1} {
+		copyDir := t.TempDir()
+		data, _ := os.ReadFile(walPath)
+		if err := os.WriteFile(filepath.Join(copyDir, walFile), data[:cut], 0o644); err != nil {
+			t.Fatalf("write wal: %v", err)
+		}
+		snap, _ := os.ReadFile(filepath.Join(dir, snapshotFile))
+		if err := os.WriteFile(filepath.Join(copyDir, snapshotFile), snap, 0o644); err != nil {
+			t.Fatalf("write snapshot: %v", err)
+		}
+
+		r := mustOpen(t, copyDir)
+		tasks, err := r.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{})
+		if err != nil || len(tasks) != 1 || tasks[0].ID != kept.ID {
+			t.Fatalf("cut at %d: expected only the intact task, got %+v %v", cut, tasks, err)
+		}
+		next, err := r.CreateTask(domain.Task{UserID: user.ID, Text: "after recovery"})
+		if err != nil {
+			t.Fatalf("cut at %d: create after recovery: %v", cut, err)
+		}
+		crash(r)
+
+		r = mustOpen(t, copyDir)
+		if _, err := r.GetTask(next.ID); err != nil {
+			t.Fatalf("cut at %d: expected writes after recovery to persist, got %v", cut, err)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestOpen_IgnoresCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	_, _ = s.CreateTask(domain.Task{UserID: user.ID, Text: "bad"})
+	crash(s)
+
+	walPath := filepath.Join(dir, walFile)
+	data, _ := os.ReadFile(walPath)
+	data[len(data)-2] ^= 0xff
+	if err := os.WriteFile(walPath, data, 0o644); err != nil {
+		t.Fatalf("write wal: %v", err)
+	}
+	s = mustOpen(t, dir)
+	defer s.Close()
+	if _, err := s.GetUser(user.ID); err != nil {
+		t.Fatalf("expected the user record before the corruption, got %v", err)
+	}
+	if tasks, _ := s.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{}); len(tasks) != 0 {
+		t.Fatalf("expected the corrupted record to be dropped, got %+v", tasks)
+	}
+}
+
+func TestCommit_CompactsLog(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	var last domain.Task
+	for i := 0; i < compactEvery+10; i++ {
+		task, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "bulk"})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		last = task
+	}
+	if s.journal.records >= compactEvery {
+		t.Fatalf("expected the log to be compacted, has %d records", s.journal.records)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	defer s.Close()
+	tasks, err := s.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(tasks) != compactEvery+10 || tasks[len(tasks)-1].ID != last.ID {
+		t.Fatalf("expected every task after compaction, got %d %v", len(tasks), err)
+	}
+}
+
+func TestOpen_RestoresTags(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	task, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "milk", Tags: []string{"home"}})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	tags, _ := s.ListTags(user.ID)
+	if _, err := s.RenameTag(tags[0].ID, "errands"); err != nil {
+		t.Fatalf("rename tag: %v", err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	defer s.Close()
+	got, err := s.GetTask(task.ID)
+	if err != nil || len(got.Tags) != 1 || got.Tags[0] != "errands" {
+		t.Fatalf("expected the renamed tag on the task, got %+v %v", got, err)
+	}
+	created, err := s.CreateTag(domain.Tag{UserID: user.ID, Name: "work"})
+	if err != nil || created.ID <= tags[0].ID {
+		t.Fatalf("expected a fresh tag id after reopen, got %+v %v", created, err)
+	}
+}
+
+func TestOpen_RestoresDependencies(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	blocker, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "migrate"})
+	blocked, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "deploy"})
+	gone, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "gone"})
+	if err := s.AddDependency(blocked.ID, blocker.ID); err != nil {
+		t.Fatalf("add dependency: %v", err)
+	}
+	if err := s.AddDependency(blocked.ID, gone.ID); err != nil {
+		t.Fatalf("add dependency: %v", err)
+	}
+	if err := s.DeleteTask(gone.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	got, err := s.GetTask(blocked.ID)
+	if err != nil || !got.Blocked || len(got.BlockedBy) != 1 || got.BlockedBy[0] != blocker.ID {
+		t.Fatalf("expected the replayed dependency, got %+v %v", got, err)
+	}
+	// Once more through the snapshot Close writes.
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	s = mustOpen(t, dir)
+	defer s.Close()
+	if edges, _ := s.ListDependencies(user.ID); len(edges) != 1 {
+		t.Fatalf("expected the dependency in the snapshot, got %+v", edges)
+	}
+}
+
+func TestOpen_RestoresAttachments(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	task, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "receipt"})
+	gone, _ := s.CreateTask(domain.Task{UserID: user.ID, Text: "gone"})
+	if _, err := s.CreateAttachment(domain.Attachment{TaskID: task.ID, Type: domain.AttachmentTypePhoto, TelegramFileID: "f1", FileUniqueID: "u1"}); err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	if _, err := s.CreateAttachment(domain.Attachment{TaskID: gone.ID, Type: domain.AttachmentTypeVoice, TelegramFileID: "f2", FileUniqueID: "u2"}); err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	if err := s.DeleteTask(gone.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	if items, err := s.ListAttachments(task.ID); err != nil || len(items) != 1 || items[0].TelegramFileID != "f1" {
+		t.Fatalf("expected the replayed attachment, got %+v %v", items, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	s = mustOpen(t, dir)
+	defer s.Close()
+	next, err := s.CreateAttachment(domain.Attachment{TaskID: task.ID, Type: domain.AttachmentTypeDocument, TelegramFileID: "f3", FileUniqueID: "u3"})
+	if err != nil || next.ID != 3 {
+		t.Fatalf("expected ids not to be reused after the snapshot, got %+v %v", next, err)
+	}
+	if items, _ := s.ListAttachments(gone.ID); len(items) != 0 {
+		t.Fatalf("expected attachments of a deleted task to go, got %+v", items)
+	}
+}
+
+func TestOpen_RestoresSeenUpdates(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	if fresh, err := s.MarkUpdateSeen(7, now); err != nil || !fresh {
+		t.Fatalf("mark update: %v %v", fresh, err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	if fresh, err := s.MarkUpdateSeen(7, now); err != nil || fresh {
+		t.Fatalf("expected the replayed update to be known, got %v %v", fresh, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	s = mustOpen(t, dir)
+	defer s.Close()
+	if fresh, err := s.MarkUpdateSeen(7, now); err != nil || fresh {
+		t.Fatalf("expected the update to survive the snapshot, got %v %v", fresh, err)
+	}
+}
+
+// flakyLog writes only half of the next buffer and fails, like a full disk.
+type flakyLog struct {
+	logFile
+	failNext bool
+	// failRollback makes the truncate after the failed write fail too.
+	failRollback bool
+}
+
+func (f *flakyLog) Write(p []byte) (int, error) {
+	if !f.failNext {
+		return f.logFile.Write(p)
+	}
+	f.failNext = false
+	n, _ := f.logFile.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func (f *flakyLog) Truncate(size int64) error {
+	if f.failRollback {
+		return errors.New("read-only file system")
+	}
+	return f.logFile.Truncate(size)
+}
+
+func TestCommit_RollsBackFailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	log := &flakyLog{logFile: s.journal.wal, failNext: true}
+	s.journal.wal = log
+	if _, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "lost"}); err == nil {
+		t.Fatalf("expected the failed write to be reported")
+	}
+	if tasks, _ := s.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{}); len(tasks) != 0 {
+		t.Fatalf("expected the failed write not to be applied, got %+v", tasks)
+	}
+	kept, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "kept"})
+	if err != nil {
+		t.Fatalf("create after a failed write: %v", err)
+	}
+	crash(s)
+
+	s = mustOpen(t, dir)
+	defer s.Close()
+	tasks, _ := s.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{})
+	if len(tasks) != 1 || tasks[0].ID != kept.ID {
+		t.Fatalf("expected the record after the failed one to replay, got %+v", tasks)
+	}
+}
+
+func TestCommit_RefusesWritesAfterFailedRollback(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	defer crash(s)
+	user, _ := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	s.journal.wal = &flakyLog{logFile: s.journal.wal, failNext: true, failRollback: true}
+	if _, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "lost"}); err == nil {
+		t.Fatalf("expected the failed write to be reported")
+	}
+	if _, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "next"}); err == nil {
+		t.Fatalf("expected writes to be refused once the log can't be repaired")
+	}
+}
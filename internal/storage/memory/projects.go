@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func (s *Store) ListProjects(userID int64, includeArchived bool) ([]domain.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Project, 0)
+	for _, p := range s.projects {
+		if p.UserID != userID || (p.Archived && !includeArchived) {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) GetProject(id int64) (domain.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[id]
+	if !ok {
+		return domain.Project{}, storage.ErrNotFound
+	}
+	return p, nil
+}
+
+func (s *Store) CreateProject(p domain.Project) (domain.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[p.UserID]; !ok {
+		return domain.Project{}, storage.ErrNotFound
+	}
+	if s.projectNameTaken(p.UserID, p.Name, 0) {
+		return domain.Project{}, storage.ErrConflict
+	}
+	now := time.Now().UTC()
+	p.ID = s.nextProjectID
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	if err := s.commit(record{Op: opPutProject, Project: &p}); err != nil {
+		return domain.Project{}, err
+	}
+	return p, nil
+}
+
+// UpdateProject writes the name, color and archived flag and returns the stored row.
+func (s *Store) UpdateProject(p domain.Project) (domain.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.projects[p.ID]
+	if !ok {
+		return domain.Project{}, storage.ErrNotFound
+	}
+	if s.projectNameTaken(cur.UserID, p.Name, cur.ID) {
+		return domain.Project{}, storage.ErrConflict
+	}
+	cur.Name = p.Name
+	cur.Color = p.Color
+	cur.Archived = p.Archived
+	cur.UpdatedAt = time.Now().UTC()
+	if err := s.commit(record{Op: opPutProject, Project: &cur}); err != nil {
+		return domain.Project{}, err
+	}
+	return cur, nil
+}
+
+// DeleteProject moves the project's tasks to the inbox, or deletes them when withTasks
+// is set, and clears it as anyone's current project.
+func (s *Store) DeleteProject(id int64, withTasks bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.projects[id]; !ok {
+		return storage.ErrNotFound
+	}
+	now := time.Now().UTC()
+	for _, taskID := range s.sortedTaskIDs() {
+		t, ok := s.tasks[taskID]
+		if !ok {
+			// A subtask deleted along with its parent.
+			continue
+		}
+		if t.ProjectID == nil || *t.ProjectID != id {
+			continue
+		}
+		if withTasks {
+			if err := s.deleteWithSubtasks(t.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		t.ProjectID = nil
+		t.UpdatedAt = now
+		t.Version++
+		if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+			return err
+		}
+	}
+	for _, u := range s.users {
+		if u.CurrentProjectID == nil || *u.CurrentProjectID != id {
+			continue
+		}
+		u.CurrentProjectID = nil
+		if err := s.commit(record{Op: opPutUser, User: &u}); err != nil {
+			return err
+		}
+	}
+	return s.commit(record{Op: opDeleteProject, ID: id})
+}
+
+func (s *Store) SetCurrentProject(userID int64, projectID *int64) (domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return domain.User{}, storage.ErrNotFound
+	}
+	if err := s.checkProject(userID, projectID); err != nil {
+		return domain.User{}, err
+	}
+	u.CurrentProjectID = projectID
+	if err := s.commit(record{Op: opPutUser, User: &u}); err != nil {
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+// checkProject reports storage.ErrNotFound unless projectID is nil or one of the user's
+// projects. Callers hold s.mu.
+func (s *Store) checkProject(userID int64, projectID *int64) error {
+	if projectID == nil {
+		return nil
+	}
+	p, ok := s.projects[*projectID]
+	if !ok || p.UserID != userID {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// archivedProject reports whether the task belongs to an archived project. Callers hold s.mu.
+func (s *Store) archivedProject(t domain.Task) bool {
+	if t.ProjectID == nil {
+		return false
+	}
+	return s.projects[*t.ProjectID].Archived
+}
+
+func (s *Store) projectNameTaken(userID int64, name string, exceptID int64) bool {
+	for _, p := range s.projects {
+		if p.UserID == userID && strings.ToLower(p.Name) == strings.ToLower(name) && p.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) sortedTaskIDs() []int64 {
+	ids := make([]int64, 0, len(s.tasks))
+	for id := range s.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
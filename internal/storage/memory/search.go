@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// SearchTasks returns the user's tasks matching f whose text has a word for every term
+// of query, best matches first. A task ranks higher the larger the share of its words
+// that match.
+func (s *Store) SearchTasks(userID int64, query string, f storage.TaskFilter) ([]domain.SearchResult, error) {
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	terms := domain.SearchTerms(query)
+	out := make([]domain.SearchResult, 0)
+	if len(terms) == 0 {
+		return out, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.words[userID]
+	if idx == nil {
+		return out, nil
+	}
+	var hits map[int64]int
+	for _, term := range terms {
+		termHits := make(map[int64]int)
+		// Terms match word prefixes, and the words sharing a prefix sit next to each
+		// other in vocab, starting where the term itself would go.
+		i, _ := slices.BinarySearch(idx.vocab, term)
+		for ; i < len(idx.vocab) && domain.MatchesTerm(idx.vocab[i], term); i++ {
+			for id, n := range idx.tasks[idx.vocab[i]] {
+				if hits == nil || hits[id] > 0 {
+					termHits[id] += n
+				}
+			}
+		}
+		for id, n := range termHits {
+			termHits[id] = n + hits[id]
+		}
+		hits = termHits
+	}
+	progress := s.progress()
+	for id, n := range hits {
+		t := s.tasks[id]
+		if !s.matchFilter(t, f) {
+			continue
+		}
+		t.Progress = progress[t.ID]
+		out = append(out, domain.SearchResult{
+			Task:      s.withBlockers(t),
+			Rank:      float64(n) / float64(len(domain.SearchTerms(t.Text))),
+			Highlight: domain.HighlightTerms(t.Text, terms),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rank != out[j].Rank {
+			return out[i].Rank > out[j].Rank
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// wordIndex is one user's inverted index: word, then task id, then how often the word
+// occurs in the task's text.
+type wordIndex struct {
+	tasks map[string]map[int64]int
+	// vocab lists the keys of tasks in ascending order, for prefix lookups.
+	vocab []string
+}
+
+// indexTask adds the words of t to its user's index. Callers hold s.mu.
+func (s *Store) indexTask(t domain.Task) {
+	idx := s.words[t.UserID]
+	if idx == nil {
+		idx = &wordIndex{tasks: make(map[string]map[int64]int)}
+		s.words[t.UserID] = idx
+	}
+	for _, word := range domain.SearchTerms(t.Text) {
+		tasks := idx.tasks[word]
+		if tasks == nil {
+			tasks = make(map[int64]int)
+			idx.tasks[word] = tasks
+			i, _ := slices.BinarySearch(idx.vocab, word)
+			idx.vocab = slices.Insert(idx.vocab, i, word)
+		}
+		tasks[t.ID]++
+	}
+}
+
+// unindexTask removes the words of t from its user's index. Callers hold s.mu.
+func (s *Store) unindexTask(t domain.Task) {
+	idx := s.words[t.UserID]
+	if idx == nil {
+		return
+	}
+	for _, word := range domain.SearchTerms(t.Text) {
+		tasks, ok := idx.tasks[word]
+		if !ok {
+			continue
+		}
+		delete(tasks, t.ID)
+		if len(tasks) == 0 {
+			delete(idx.tasks, word)
+			if i, ok := slices.BinarySearch(idx.vocab, word); ok {
+				idx.vocab = slices.Delete(idx.vocab, i, i+1)
+			}
+		}
+	}
+	if len(idx.tasks) == 0 {
+		delete(s.words, t.UserID)
+	}
+}
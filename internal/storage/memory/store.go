@@ -2,6 +2,7 @@ package memory
 
 import (
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,31 +11,72 @@ import (
 )
 
 type Store struct {
-	mu         sync.Mutex
-	nextUserID int64
-	nextTaskID int64
-	users      map[int64]domain.User
-	tasks      map[int64]domain.Task
+	mu               sync.Mutex
+	nextUserID       int64
+	nextTaskID       int64
+	nextTokenID      int64
+	nextTagID        int64
+	nextProjectID    int64
+	nextAttachmentID int64
+	users            map[int64]domain.User
+	tasks            map[int64]domain.Task
+	tokens           map[int64]domain.APIToken
+	// tokenIDs indexes tokens by hash.
+	tokenIDs map[string]int64
+	tags     map[int64]domain.Tag
+	projects map[int64]domain.Project
+	deps     map[domain.Dependency]bool
+	// blockers indexes deps by the blocked task: its blocker ids in ascending order.
+	blockers    map[int64][]int64
+	attachments map[int64]domain.Attachment
+	// words holds each user's inverted index for SearchTasks.
+	words map[int64]*wordIndex
+	// updates holds seen Telegram update ids and when they arrived; updateOrder lists
+	// them oldest first, for expiry.
+	updates     map[int64]time.Time
+	updateOrder []int64
+	// journal is nil for a volatile store; see Open.
+	journal *journal
 }
 
 func New() *Store {
 	return &Store{
-		nextUserID: 1,
-		nextTaskID: 1,
-		users:      make(map[int64]domain.User),
-		tasks:      make(map[int64]domain.Task),
+		nextUserID:       1,
+		nextTaskID:       1,
+		nextTokenID:      1,
+		nextTagID:        1,
+		nextProjectID:    1,
+		nextAttachmentID: 1,
+		users:            make(map[int64]domain.User),
+		tasks:            make(map[int64]domain.Task),
+		tokens:           make(map[int64]domain.APIToken),
+		tokenIDs:         make(map[string]int64),
+		tags:             make(map[int64]domain.Tag),
+		projects:         make(map[int64]domain.Project),
+		deps:             make(map[domain.Dependency]bool),
+		blockers:         make(map[int64][]int64),
+		attachments:      make(map[int64]domain.Attachment),
+		words:            make(map[int64]*wordIndex),
+		updates:          make(map[int64]time.Time),
 	}
 }
 
-func (s *Store) ListUsers() ([]domain.User, error) {
+// ListUsers returns one page of users; see storage.Page.
+func (s *Store) ListUsers(p storage.Page) ([]domain.User, error) {
+	keys := p.SortOr(storage.DefaultUserSort)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	out := make([]domain.User, 0, len(s.users))
 	for _, u := range s.users {
+		if p.After != nil && storage.CompareSortValues(keys, storage.UserSortValues(u, keys), p.After) <= 0 {
+			continue
+		}
 		out = append(out, u)
 	}
-	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
-	return out, nil
+	sort.Slice(out, func(i, j int) bool {
+		return storage.CompareSortValues(keys, storage.UserSortValues(out[i], keys), storage.UserSortValues(out[j], keys)) < 0
+	})
+	return limit(out, p.Limit), nil
 }
 
 func (s *Store) CreateUser(u domain.User) (domain.User, error) {
@@ -43,10 +85,43 @@ func (s *Store) CreateUser(u domain.User) (domain.User, error) {
 	if u.Timezone == "" {
 		u.Timezone = "UTC"
 	}
+	if u.Role == "" {
+		u.Role = domain.RoleUser
+	}
+	for _, existing := range s.users {
+		if existing.TelegramUserID == u.TelegramUserID {
+			return domain.User{}, storage.ErrConflict
+		}
+	}
 	u.ID = s.nextUserID
-	s.nextUserID++
 	u.CreatedAt = time.Now().UTC()
-	s.users[u.ID] = u
+	if err := s.commit(record{Op: opPutUser, User: &u}); err != nil {
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) GetUser(id int64) (domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return domain.User{}, storage.ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *Store) UpdateTimezone(id int64, tz string) (domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return domain.User{}, storage.ErrNotFound
+	}
+	u.Timezone = tz
+	if err := s.commit(record{Op: opPutUser, User: &u}); err != nil {
+		return domain.User{}, err
+	}
 	return u, nil
 }
 
@@ -61,25 +136,96 @@ func (s *Store) GetByTelegramID(telegramUserID int64) (domain.User, error) {
 	return domain.User{}, storage.ErrNotFound
 }
 
-func (s *Store) ListTasks(userID int64, status string) ([]domain.Task, error) {
+// ListTasks returns one page of the user's tasks matching f; see storage.Page.
+func (s *Store) ListTasks(userID int64, f storage.TaskFilter, p storage.Page) ([]domain.Task, error) {
+	keys := p.SortOr(storage.DefaultTaskSort)
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	out := make([]domain.Task, 0, len(s.tasks))
+	progress := s.progress()
 	for _, t := range s.tasks {
-		if t.UserID != userID {
+		if t.UserID != userID || !s.matchFilter(t, f) {
 			continue
 		}
-		if status != "" && t.Status != status {
+		if p.After != nil && storage.CompareSortValues(keys, storage.TaskSortValues(t, keys), p.After) <= 0 {
 			continue
 		}
-		out = append(out, t)
+		t.Progress = progress[t.ID]
+		out = append(out, s.withBlockers(t))
 	}
-	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
-	return out, nil
+	sort.Slice(out, func(i, j int) bool {
+		return storage.CompareSortValues(keys, storage.TaskSortValues(out[i], keys), storage.TaskSortValues(out[j], keys)) < 0
+	})
+	return limit(out, p.Limit), nil
+}
+
+// limit cuts items to n; zero means no limit.
+func limit[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// matchFilter reports whether t passes f; f.Tags have to be normalized. Callers hold
+// s.mu.
+func (s *Store) matchFilter(t domain.Task, f storage.TaskFilter) bool {
+	if f.Status != "" && t.Status != f.Status {
+		return false
+	}
+	if f.Priority != 0 && t.Priority != f.Priority {
+		return false
+	}
+	if f.ProjectID != 0 && (t.ProjectID == nil || *t.ProjectID != f.ProjectID) {
+		return false
+	}
+	if f.HideArchived && s.archivedProject(t) {
+		return false
+	}
+	if !matchTags(t.Tags, f) {
+		return false
+	}
+	if f.DueAfter != nil && (t.DueAt == nil || t.DueAt.Before(*f.DueAfter)) {
+		return false
+	}
+	if f.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.OverdueAt != nil && (t.Status != domain.TaskStatusActive || t.DueAt == nil || !t.DueAt.Before(*f.OverdueAt)) {
+		return false
+	}
+	if f.NoDue != nil && *f.NoDue != (t.DueAt == nil) {
+		return false
+	}
+	switch f.Reminder {
+	case "":
+	case storage.ReminderNone:
+		if t.RemindAt != nil {
+			return false
+		}
+	default:
+		if t.RemindAt == nil || t.ReminderStatus != f.Reminder {
+			return false
+		}
+	}
+	if !inWindow(t.CreatedAt, f.CreatedAfter, f.CreatedBefore) || !inWindow(t.UpdatedAt, f.UpdatedAfter, f.UpdatedBefore) {
+		return false
+	}
+	return f.TextContains == "" || strings.Contains(strings.ToLower(t.Text), strings.ToLower(f.TextContains))
+}
+
+// inWindow reports whether after <= t < before; nil bounds are open.
+func inWindow(t time.Time, after, before *time.Time) bool {
+	return (after == nil || !t.Before(*after)) && (before == nil || t.Before(*before))
 }
 
 func (s *Store) ListActive(userID int64) ([]domain.Task, error) {
-	return s.ListTasks(userID, domain.TaskStatusActive)
+	return s.ListTasks(userID, storage.TaskFilter{Status: domain.TaskStatusActive, HideArchived: true}, storage.Page{})
 }
 
 func (s *Store) GetTask(id int64) (domain.Task, error) {
@@ -89,7 +235,7 @@ func (s *Store) GetTask(id int64) (domain.Task, error) {
 	if !ok {
 		return domain.Task{}, storage.ErrNotFound
 	}
-	return t, nil
+	return s.withBlockers(t), nil
 }
 
 func (s *Store) GetByID(id int64) (domain.Task, error) {
@@ -102,15 +248,52 @@ func (s *Store) CreateTask(t domain.Task) (domain.Task, error) {
 	if _, ok := s.users[t.UserID]; !ok {
 		return domain.Task{}, storage.ErrNotFound
 	}
+	if err := s.checkProject(t.UserID, t.ProjectID); err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.checkParent(t.UserID, t.ParentID); err != nil {
+		return domain.Task{}, err
+	}
+	t, err := s.newTask(t, s.nextTaskID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+		return domain.Task{}, err
+	}
+	return t, nil
+}
+
+// newTask fills in the defaults of a task about to be stored as id and creates its tags.
+// The caller has checked the user, project and parent. Callers hold s.mu.
+func (s *Store) newTask(t domain.Task, id int64) (domain.Task, error) {
 	if t.Status == "" {
 		t.Status = domain.TaskStatusActive
 	}
+	if t.Priority == 0 {
+		t.Priority = domain.PriorityDefault
+	}
+	if t.ReminderStatus == "" {
+		t.ReminderStatus = domain.ReminderStatusPending
+		if t.NotifiedAt != nil {
+			t.ReminderStatus = domain.ReminderStatusDelivered
+		}
+	}
+	tags, err := domain.NormalizeTags(t.Tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.ensureTags(t.UserID, tags); err != nil {
+		return domain.Task{}, err
+	}
+	t.Tags = tags
+	// Computed fields are never stored.
+	t.Progress, t.Subtasks, t.BlockedBy, t.Blocked = nil, nil, nil, false
 	now := time.Now().UTC()
-	t.ID = s.nextTaskID
-	s.nextTaskID++
+	t.ID = id
 	t.CreatedAt = now
 	t.UpdatedAt = now
-	s.tasks[t.ID] = t
+	t.Version = 1
 	return t, nil
 }
 
@@ -119,16 +302,74 @@ func (s *Store) Create(task domain.Task) (domain.Task, error) {
 }
 
 func (s *Store) MarkDone(id int64) (domain.Task, error) {
+	t, _, err := s.CompleteTask(id, storage.Completion{})
+	return t, err
+}
+
+// CompleteTask marks the task done unless it already is; see storage.Completion. The
+// next occurrence comes back only when this call created it.
+func (s *Store) CompleteTask(id int64, c storage.Completion) (domain.Task, *domain.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	t, ok := s.tasks[id]
 	if !ok {
-		return domain.Task{}, storage.ErrNotFound
+		return domain.Task{}, nil, storage.ErrNotFound
+	}
+	if t.Status == domain.TaskStatusDone {
+		return s.withBlockers(t), nil, nil
 	}
 	t.Status = domain.TaskStatusDone
 	t.UpdatedAt = time.Now().UTC()
-	s.tasks[id] = t
-	return t, nil
+	t.Version++
+	records, next, err := s.completionRecords(id, c, t.UpdatedAt)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	batch := append([]record{{Op: opPutTask, Task: &t}}, records...)
+	if err := s.commit(record{Op: opBatch, Records: batch}); err != nil {
+		return domain.Task{}, nil, err
+	}
+	return s.withBlockers(t), next, nil
+}
+
+// completionRecords builds the records that write c for the task id completed at now,
+// and the next occurrence they create, if any. Callers hold s.mu.
+func (s *Store) completionRecords(id int64, c storage.Completion, now time.Time) ([]record, *domain.Task, error) {
+	var batch []record
+	if c.Subtasks {
+		batch = append(batch, s.completeSubtasks(id, now)...)
+	}
+	if c.Next == nil {
+		return batch, nil, nil
+	}
+	n := *c.Next
+	if _, ok := s.users[n.UserID]; !ok {
+		return nil, nil, storage.ErrNotFound
+	}
+	if err := s.checkProject(n.UserID, n.ProjectID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkParent(n.UserID, n.ParentID); err != nil {
+		return nil, nil, err
+	}
+	nextID := s.nextTaskID
+	n, err := s.newTask(n, nextID)
+	if err != nil {
+		return nil, nil, err
+	}
+	batch = append(batch, record{Op: opPutTask, Task: &n})
+	for _, sub := range c.NextSubtasks {
+		nextID++
+		sub.UserID = n.UserID
+		sub.ParentID = &n.ID
+		sub.ProjectID = n.ProjectID
+		sub, err := s.newTask(sub, nextID)
+		if err != nil {
+			return nil, nil, err
+		}
+		batch = append(batch, record{Op: opPutTask, Task: &sub})
+	}
+	return batch, &n, nil
 }
 
 func (s *Store) SetDue(id int64, dueAt *time.Time) (domain.Task, error) {
@@ -140,7 +381,10 @@ func (s *Store) SetDue(id int64, dueAt *time.Time) (domain.Task, error) {
 	}
 	t.DueAt = dueAt
 	t.UpdatedAt = time.Now().UTC()
-	s.tasks[id] = t
+	t.Version++
+	if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+		return domain.Task{}, err
+	}
 	return t, nil
 }
 
@@ -153,29 +397,125 @@ func (s *Store) SetRemind(id int64, remindAt *time.Time) (domain.Task, error) {
 	}
 	t.RemindAt = remindAt
 	t.NotifiedAt = nil
+	resetReminder(&t)
 	t.UpdatedAt = time.Now().UTC()
-	s.tasks[id] = t
+	t.Version++
+	if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+		return domain.Task{}, err
+	}
 	return t, nil
 }
 
-func (s *Store) UpdateTask(t domain.Task) (domain.Task, error) {
+func (s *Store) SetRecurrence(id int64, rule string) (domain.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.tasks[t.ID]; !ok {
+	t, ok := s.tasks[id]
+	if !ok {
 		return domain.Task{}, storage.ErrNotFound
 	}
+	t.Recurrence = rule
 	t.UpdatedAt = time.Now().UTC()
-	s.tasks[t.ID] = t
+	t.Version++
+	if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+		return domain.Task{}, err
+	}
 	return t, nil
 }
 
-func (s *Store) ListDueForNotify(now time.Time) ([]domain.Task, error) {
+func (s *Store) UpdateTask(t domain.Task) (domain.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, err := s.updatedTask(t)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.commit(record{Op: opPutTask, Task: &cur}); err != nil {
+		return domain.Task{}, err
+	}
+	return s.withBlockers(cur), nil
+}
+
+// UpdateAndComplete writes t as UpdateTask does and, when that takes the task to done,
+// writes c in the same batch, as CompleteTask would. The next occurrence comes back only
+// when c was written.
+func (s *Store) UpdateAndComplete(t domain.Task, c storage.Completion) (domain.Task, *domain.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, err := s.updatedTask(t)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	batch := []record{{Op: opPutTask, Task: &cur}}
+	var next *domain.Task
+	if s.tasks[t.ID].Status != domain.TaskStatusDone && cur.Status == domain.TaskStatusDone {
+		records, created, err := s.completionRecords(t.ID, c, cur.UpdatedAt)
+		if err != nil {
+			return domain.Task{}, nil, err
+		}
+		batch = append(batch, records...)
+		next = created
+	}
+	if err := s.commit(record{Op: opBatch, Records: batch}); err != nil {
+		return domain.Task{}, nil, err
+	}
+	return s.withBlockers(cur), next, nil
+}
+
+// updatedTask checks t against the stored task and returns the stored task with t's
+// edits applied, ready to commit. Callers hold s.mu.
+func (s *Store) updatedTask(t domain.Task) (domain.Task, error) {
+	cur, ok := s.tasks[t.ID]
+	if !ok {
+		return domain.Task{}, storage.ErrNotFound
+	}
+	if t.Version != 0 && t.Version != cur.Version {
+		return domain.Task{}, storage.ErrStale
+	}
+	if err := s.checkProject(cur.UserID, t.ProjectID); err != nil {
+		return domain.Task{}, err
+	}
+	tags, err := domain.NormalizeTags(t.Tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.ensureTags(cur.UserID, tags); err != nil {
+		return domain.Task{}, err
+	}
+	cur.Tags = tags
+	cur.ProjectID = t.ProjectID
+	cur.Text = t.Text
+	cur.Status = t.Status
+	cur.Priority = t.Priority
+	cur.DueAt = t.DueAt
+	switch {
+	case !sameTime(cur.RemindAt, t.RemindAt), t.NotifiedAt == nil && cur.NotifiedAt != nil:
+		// A new remind_at or a cleared notified_at starts a fresh reminder, as in
+		// SetRemind.
+		cur.NotifiedAt = nil
+		resetReminder(&cur)
+	case t.NotifiedAt != nil && !sameTime(cur.NotifiedAt, t.NotifiedAt):
+		// A new notified_at records a delivery, as in MarkNotified.
+		at := t.NotifiedAt.UTC()
+		cur.NotifiedAt = &at
+		cur.ReminderStatus = domain.ReminderStatusDelivered
+		cur.ReminderError = ""
+		cur.ReminderNextAt = nil
+	}
+	cur.RemindAt = t.RemindAt
+	cur.Recurrence = t.Recurrence
+	cur.UpdatedAt = time.Now().UTC()
+	cur.Version++
+	return cur, nil
+}
+
+func (s *Store) ListDueForNotify(now time.Time, lease time.Duration) ([]domain.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	now = now.UTC()
+	leaseUntil := now.Add(lease)
 	out := make([]domain.Task, 0)
-	for id, t := range s.tasks {
-		if t.Status != domain.TaskStatusActive {
+	for _, t := range s.tasks {
+		if t.Status != domain.TaskStatusActive || s.archivedProject(t) || s.withBlockers(t).Blocked {
 			continue
 		}
 		if t.RemindAt == nil || t.RemindAt.After(now) {
@@ -184,25 +524,181 @@ func (s *Store) ListDueForNotify(now time.Time) ([]domain.Task, error) {
 		if t.NotifiedAt != nil {
 			continue
 		}
-		t.NotifiedAt = &now
-		t.UpdatedAt = now
-		s.tasks[id] = t
+		if t.ReminderStatus != domain.ReminderStatusPending && t.ReminderStatus != domain.ReminderStatusSending {
+			continue
+		}
+		if t.ReminderNextAt != nil && t.ReminderNextAt.After(now) {
+			continue
+		}
+		t.ReminderStatus = domain.ReminderStatusSending
+		t.ReminderAttempts++
+		t.ReminderNextAt = &leaseUntil
+		if err := s.commit(record{Op: opPutTask, Task: &t}); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) MarkNotified(id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if t.ReminderStatus != domain.ReminderStatusSending {
+		return nil
+	}
+	at = at.UTC()
+	t.NotifiedAt = &at
+	t.ReminderStatus = domain.ReminderStatusDelivered
+	t.ReminderError = ""
+	t.ReminderNextAt = nil
+	return s.commit(record{Op: opPutTask, Task: &t})
+}
+
+func (s *Store) MarkNotifyFailed(id int64, reason string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if t.ReminderStatus != domain.ReminderStatusSending {
+		return nil
+	}
+	t.ReminderError = reason
+	if retryAt == nil {
+		t.ReminderStatus = domain.ReminderStatusFailed
+		t.ReminderNextAt = nil
+	} else {
+		next := retryAt.UTC()
+		t.ReminderStatus = domain.ReminderStatusPending
+		t.ReminderNextAt = &next
+	}
+	return s.commit(record{Op: opPutTask, Task: &t})
+}
+
+func (s *Store) ListReminders(status string) ([]domain.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Task, 0)
+	for _, t := range s.tasks {
+		if t.RemindAt == nil {
+			continue
+		}
+		if status != "" && t.ReminderStatus != status {
+			continue
+		}
 		out = append(out, t)
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
 	return out, nil
 }
 
-func (s *Store) DeleteTask(id int64) error {
+func (s *Store) DeleteTask(id, version int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.tasks[id]; !ok {
+	t, ok := s.tasks[id]
+	if !ok {
 		return storage.ErrNotFound
 	}
-	delete(s.tasks, id)
-	return nil
+	if version != 0 && version != t.Version {
+		return storage.ErrStale
+	}
+	return s.deleteWithSubtasks(id)
 }
 
 func (s *Store) Delete(id int64) error {
-	return s.DeleteTask(id)
+	return s.DeleteTask(id, 0)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func resetReminder(t *domain.Task) {
+	t.ReminderStatus = domain.ReminderStatusPending
+	t.ReminderAttempts = 0
+	t.ReminderError = ""
+	t.ReminderNextAt = nil
+}
+
+func (s *Store) CreateToken(t domain.APIToken) (domain.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[t.UserID]; !ok {
+		return domain.APIToken{}, storage.ErrNotFound
+	}
+	if _, ok := s.tokenIDs[t.Hash]; ok {
+		return domain.APIToken{}, storage.ErrConflict
+	}
+	t.ID = s.nextTokenID
+	t.CreatedAt = time.Now().UTC()
+	if err := s.commit(record{Op: opPutToken, Token: &storedToken{APIToken: t, Hash: t.Hash}}); err != nil {
+		return domain.APIToken{}, err
+	}
+	return t, nil
+}
+
+func (s *Store) GetUserByTokenHash(hash string, now time.Time) (domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.tokenIDs[hash]
+	if !ok {
+		return domain.User{}, storage.ErrNotFound
+	}
+	t := s.tokens[id]
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(now) {
+		return domain.User{}, storage.ErrNotFound
+	}
+	u, ok := s.users[t.UserID]
+	if !ok {
+		return domain.User{}, storage.ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *Store) ListTokens(userID int64) ([]domain.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.APIToken, 0)
+	for _, t := range s.tokens {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) DeleteToken(userID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[id]
+	if !ok || t.UserID != userID {
+		return storage.ErrNotFound
+	}
+	return s.commit(record{Op: opDeleteToken, ID: id})
+}
+
+func (s *Store) DeleteExpiredTokens(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var batch []record
+	for id, t := range s.tokens {
+		if t.ExpiresAt != nil && !t.ExpiresAt.After(now) {
+			batch = append(batch, record{Op: opDeleteToken, ID: id})
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.commit(record{Op: opBatch, Records: batch})
 }
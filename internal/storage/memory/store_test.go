@@ -0,0 +1,26 @@
+package memory_test
+
+import (
+	"testing"
+
+	"example.com/yourapp/internal/app"
+	"example.com/yourapp/internal/storage/memory"
+	"example.com/yourapp/internal/storage/storagetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Store {
+		return memory.New()
+	})
+}
+
+func TestDurableStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Store {
+		s, err := memory.Open(t.TempDir())
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	})
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// ListSubtasks returns the subtasks of a task in the order they were added.
+func (s *Store) ListSubtasks(parentID int64) ([]domain.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Task, 0)
+	for _, t := range s.tasks {
+		if t.ParentID != nil && *t.ParentID == parentID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// CompleteSubtasks marks every active subtask of the task done.
+func (s *Store) CompleteSubtasks(parentID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.completeSubtasks(parentID, time.Now().UTC())
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.commit(record{Op: opBatch, Records: batch})
+}
+
+// completeSubtasks returns the records that close the open subtasks of parentID.
+// Callers hold s.mu.
+func (s *Store) completeSubtasks(parentID int64, now time.Time) []record {
+	var batch []record
+	for _, id := range s.sortedTaskIDs() {
+		t := s.tasks[id]
+		if t.ParentID == nil || *t.ParentID != parentID || t.Status != domain.TaskStatusActive {
+			continue
+		}
+		t.Status = domain.TaskStatusDone
+		t.UpdatedAt = now
+		t.Version++
+		batch = append(batch, record{Op: opPutTask, Task: &t})
+	}
+	return batch
+}
+
+// checkParent reports storage.ErrNotFound unless parentID is nil or one of the user's
+// tasks. Callers hold s.mu.
+func (s *Store) checkParent(userID int64, parentID *int64) error {
+	if parentID == nil {
+		return nil
+	}
+	p, ok := s.tasks[*parentID]
+	if !ok || p.UserID != userID {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// deleteWithSubtasks removes a task and its subtasks. Callers hold s.mu.
+func (s *Store) deleteWithSubtasks(id int64) error {
+	for _, childID := range s.sortedTaskIDs() {
+		t := s.tasks[childID]
+		if t.ParentID == nil || *t.ParentID != id {
+			continue
+		}
+		if err := s.commit(record{Op: opDeleteTask, ID: childID}); err != nil {
+			return err
+		}
+	}
+	return s.commit(record{Op: opDeleteTask, ID: id})
+}
+
+// progress counts the subtasks of every parent task. Callers hold s.mu.
+func (s *Store) progress() map[int64]*domain.Progress {
+	out := make(map[int64]*domain.Progress)
+	for _, t := range s.tasks {
+		if t.ParentID == nil {
+			continue
+		}
+		p := out[*t.ParentID]
+		if p == nil {
+			p = &domain.Progress{}
+			out[*t.ParentID] = p
+		}
+		p.Total++
+		if t.Status == domain.TaskStatusDone {
+			p.Done++
+		}
+	}
+	return out
+}
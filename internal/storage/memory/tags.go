@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func (s *Store) ListTags(userID int64) ([]domain.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Tag, 0)
+	for _, t := range s.tags {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *Store) GetTag(id int64) (domain.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tags[id]
+	if !ok {
+		return domain.Tag{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+func (s *Store) CreateTag(t domain.Tag) (domain.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[t.UserID]; !ok {
+		return domain.Tag{}, storage.ErrNotFound
+	}
+	name, err := domain.NormalizeTag(t.Name)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	if _, ok := s.tagByName(t.UserID, name); ok {
+		return domain.Tag{}, storage.ErrConflict
+	}
+	return s.putNewTag(t.UserID, name)
+}
+
+// RenameTag renames the tag on every task that carries it.
+func (s *Store) RenameTag(id int64, name string) (domain.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tags[id]
+	if !ok {
+		return domain.Tag{}, storage.ErrNotFound
+	}
+	name, err := domain.NormalizeTag(name)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	if name == t.Name {
+		return t, nil
+	}
+	if _, ok := s.tagByName(t.UserID, name); ok {
+		return domain.Tag{}, storage.ErrConflict
+	}
+	if err := s.retagTasks(t.UserID, t.Name, name); err != nil {
+		return domain.Tag{}, err
+	}
+	t.Name = name
+	if err := s.commit(record{Op: opPutTag, Tag: &t}); err != nil {
+		return domain.Tag{}, err
+	}
+	return t, nil
+}
+
+// DeleteTag removes the tag and takes it off every task.
+func (s *Store) DeleteTag(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tags[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if err := s.retagTasks(t.UserID, t.Name, ""); err != nil {
+		return err
+	}
+	return s.commit(record{Op: opDeleteTag, ID: id})
+}
+
+// retagTasks replaces from with to ("" drops it) on the user's tasks. Callers hold s.mu.
+func (s *Store) retagTasks(userID int64, from, to string) error {
+	ids := make([]int64, 0)
+	for id, task := range s.tasks {
+		if task.UserID == userID && slices.Contains(task.Tags, from) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		task := s.tasks[id]
+		tags := make([]string, 0, len(task.Tags))
+		for _, name := range task.Tags {
+			if name != from {
+				tags = append(tags, name)
+			}
+		}
+		if to != "" {
+			tags = append(tags, to)
+			sort.Strings(tags)
+		}
+		if len(tags) == 0 {
+			tags = nil
+		}
+		task.Tags = tags
+		if err := s.commit(record{Op: opPutTask, Task: &task}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureTags creates the user's tags that do not exist yet. Callers hold s.mu.
+func (s *Store) ensureTags(userID int64, names []string) error {
+	for _, name := range names {
+		if _, ok := s.tagByName(userID, name); ok {
+			continue
+		}
+		if _, err := s.putNewTag(userID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) putNewTag(userID int64, name string) (domain.Tag, error) {
+	t := domain.Tag{
+		ID:        s.nextTagID,
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.commit(record{Op: opPutTag, Tag: &t}); err != nil {
+		return domain.Tag{}, err
+	}
+	return t, nil
+}
+
+func (s *Store) tagByName(userID int64, name string) (domain.Tag, bool) {
+	for _, t := range s.tags {
+		if t.UserID == userID && t.Name == name {
+			return t, true
+		}
+	}
+	return domain.Tag{}, false
+}
+
+func matchTags(have []string, f storage.TaskFilter) bool {
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, name := range f.Tags {
+		found := slices.Contains(have, name)
+		if found && !f.AllTags {
+			return true
+		}
+		if !found && f.AllTags {
+			return false
+		}
+	}
+	return f.AllTags
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"time"
+
+	"example.com/yourapp/internal/storage"
+)
+
+// MarkUpdateSeen records the update id and reports whether it was new. Expired ids are
+// dropped along the way.
+func (s *Store) MarkUpdateSeen(id int64, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := now.Add(-storage.UpdateRetention)
+	for len(s.updateOrder) > 0 && s.updates[s.updateOrder[0]].Before(cutoff) {
+		delete(s.updates, s.updateOrder[0])
+		s.updateOrder = s.updateOrder[1:]
+	}
+	if _, ok := s.updates[id]; ok {
+		return false, nil
+	}
+	at := now.UTC()
+	if err := s.commit(record{Op: opPutUpdate, ID: id, At: &at}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,241 @@
+// Package migrate applies numbered SQL migrations and records them in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dialect holds the statements that differ between databases.
+type Dialect struct {
+	// Lock and Unlock guard a migration run; empty for databases without session locks.
+	Lock, Unlock string
+	CreateTable  string
+}
+
+// Postgres takes an advisory lock while migrating, so replicas starting at the same time
+// apply migrations one after another instead of racing.
+var Postgres = Dialect{
+	Lock:   `select pg_advisory_lock(7316041201)`,
+	Unlock: `select pg_advisory_unlock(7316041201)`,
+	CreateTable: `
+		create table if not exists schema_migrations(
+			version bigint primary key,
+			name text not null,
+			applied_at timestamptz not null default now()
+		)`,
+}
+
+// SQLite has no session locks; a single node owns the file.
+var SQLite = Dialect{
+	CreateTable: `
+		create table if not exists schema_migrations(
+			version integer primary key,
+			name text not null,
+			applied_at timestamp not null default current_timestamp
+		)`,
+}
+
+var ErrNoDown = errors.New("migration has no down script")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Runner struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS, dialect Dialect) (*Runner, error) {
+	if db == nil {
+		return nil, errors.New("db")
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql files from the root of fsys, sorted by
+// version. Every version needs an up script; the down script is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		base, direction, ok := cutLast(base, ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql", e.Name())
+		}
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name prefix", e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d: missing up script", m.Version)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if applied[m.Version] {
+				continue
+			}
+			if err := apply(ctx, conn, m.Up, `insert into schema_migrations(version, name) values ($1, $2)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(r.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := r.migrations[i]
+			if !applied[m.Version] {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, ErrNoDown)
+			}
+			if err := apply(ctx, conn, m.Down, `delete from schema_migrations where version = $1`, m.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status reports every known migration and whether it has been applied.
+func (r *Runner) Status(ctx context.Context) ([]Migration, map[int]bool, error) {
+	var applied map[int]bool
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		applied, err = appliedVersions(ctx, conn)
+		return err
+	})
+	return r.migrations, applied, err
+}
+
+// locked runs fn on a single connection holding the dialect's lock; the lock belongs to
+// the session, so everything has to go through that connection.
+func (r *Runner) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if r.dialect.Lock != "" {
+		if _, err := conn.ExecContext(ctx, r.dialect.Lock); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), r.dialect.Unlock)
+	}
+	if _, err := conn.ExecContext(ctx, r.dialect.CreateTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, `select version from schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration script and its bookkeeping statement in one transaction.
+func apply(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"example.com/yourapp/migrations"
+)
+
+func TestLoad_SortsAndPairsScripts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_tags.up.sql":   {Data: []byte("create table tags();")},
+		"0001_init.up.sql":   {Data: []byte("create table users();")},
+		"0001_init.down.sql": {Data: []byte("drop table users;")},
+		"README.md":          {Data: []byte("ignored")},
+	}
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 2 {
+		t.Fatalf("expected versions 1, 2, got %+v", got)
+	}
+	if got[0].Name != "init" || got[0].Down == "" || got[1].Down != "" {
+		t.Fatalf("unexpected scripts %+v", got)
+	}
+}
+
+func TestLoad_RejectsBadNames(t *testing.T) {
+	for _, fsys := range []fstest.MapFS{
+		{"0001_init.sql": {Data: []byte("x")}},
+		{"init.up.sql": {Data: []byte("x")}},
+		{"0001_init.down.sql": {Data: []byte("x")}},
+		{"0001_a.up.sql": {Data: []byte("x")}, "0001_b.down.sql": {Data: []byte("x")}},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("expected error for %v", fsys)
+		}
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("load embedded: %v", err)
+	}
+	for i, m := range got {
+		if m.Version != i+1 {
+			t.Fatalf("expected contiguous versions, got %d at %d", m.Version, i)
+		}
+		if m.Down == "" {
+			t.Fatalf("migration %d has no down script", m.Version)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// SortKey is one key of a listing order.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Page selects a slice of a listing. Sort ends with "id", which makes the order total,
+// so a page can start right after a row (keyset pagination). After holds the sort key
+// values of the last row of the previous page, as returned by TaskSortValues or
+// UserSortValues; nil starts at the beginning. A zero Limit means no limit.
+type Page struct {
+	Sort  []SortKey
+	After []any
+	Limit int
+}
+
+// SortOr returns p.Sort, or def when p doesn't ask for an order.
+func (p Page) SortOr(def []SortKey) []SortKey {
+	if len(p.Sort) == 0 {
+		return def
+	}
+	return p.Sort
+}
+
+// FieldKind is the type of a sortable field's values in Page.After: int64 for
+// FieldInt, time.Time for FieldTime, and time.Time or nil for FieldNullableTime.
+// Nulls sort last in both directions.
+type FieldKind int
+
+const (
+	FieldInt FieldKind = iota
+	FieldTime
+	FieldNullableTime
+)
+
+// TaskSortFields and UserSortFields are the fields listings can be sorted by; the names
+// are the JSON names, which are also the column names.
+var (
+	TaskSortFields = map[string]FieldKind{
+		"id":         FieldInt,
+		"priority":   FieldInt,
+		"due_at":     FieldNullableTime,
+		"remind_at":  FieldNullableTime,
+		"created_at": FieldTime,
+		"updated_at": FieldTime,
+	}
+	UserSortFields = map[string]FieldKind{
+		"id":         FieldInt,
+		"created_at": FieldTime,
+	}
+)
+
+// DefaultTaskSort puts the most important tasks first.
+var DefaultTaskSort = []SortKey{{Field: "priority"}, {Field: "id"}}
+
+// DefaultUserSort is the order users were created in.
+var DefaultUserSort = []SortKey{{Field: "id"}}
+
+// ParseSort reads a spec like "due_at,-priority" ("-" sorts descending); an empty spec
+// means def. "id" is appended unless the spec already has it.
+func ParseSort(spec string, fields map[string]FieldKind, def []SortKey) ([]SortKey, error) {
+	if strings.TrimSpace(spec) == "" {
+		return def, nil
+	}
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := fields[key.Field]; !ok || seen[key.Field] {
+			return nil, ErrInvalidSort
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	if !seen["id"] {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+	return keys, nil
+}
+
+// SortSpec is the inverse of ParseSort.
+func SortSpec(keys []SortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.Desc {
+			parts = append(parts, "-"+k.Field)
+			continue
+		}
+		parts = append(parts, k.Field)
+	}
+	return strings.Join(parts, ",")
+}
+
+// cursor is what an opaque cursor string encodes. The sort spec is kept so a cursor is
+// not applied to a different order.
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// EncodeCursor makes an opaque cursor pointing after the row with the given sort key
+// values.
+func EncodeCursor(keys []SortKey, values []any) string {
+	data, _ := json.Marshal(cursor{Sort: SortSpec(keys), Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the values EncodeCursor was given, typed as Page.After expects.
+// It returns ErrInvalidCursor for a garbled cursor or one made for another order.
+func DecodeCursor(s string, keys []SortKey, fields map[string]FieldKind) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var c cursor
+	if err := dec.Decode(&c); err != nil || c.Sort != SortSpec(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		v, ok := cursorValue(c.Values[i], fields[k.Field])
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func cursorValue(raw any, kind FieldKind) (any, bool) {
+	switch kind {
+	case FieldInt:
+		n, ok := raw.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		v, err := n.Int64()
+		return v, err == nil
+	case FieldNullableTime:
+		if raw == nil {
+			return nil, true
+		}
+		fallthrough
+	case FieldTime:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, false
+		}
+		v, err := time.Parse(time.RFC3339Nano, s)
+		return v.UTC(), err == nil
+	}
+	return nil, false
+}
+
+// TaskSortValues returns the values of t's sort keys, for Page.After and EncodeCursor.
+func TaskSortValues(t domain.Task, keys []SortKey) []any {
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		switch k.Field {
+		case "id":
+			values[i] = t.ID
+		case "priority":
+			values[i] = int64(t.Priority)
+		case "due_at":
+			values[i] = nullableTime(t.DueAt)
+		case "remind_at":
+			values[i] = nullableTime(t.RemindAt)
+		case "created_at":
+			values[i] = t.CreatedAt.UTC()
+		case "updated_at":
+			values[i] = t.UpdatedAt.UTC()
+		}
+	}
+	return values
+}
+
+// UserSortValues returns the values of u's sort keys, for Page.After and EncodeCursor.
+func UserSortValues(u domain.User, keys []SortKey) []any {
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		switch k.Field {
+		case "id":
+			values[i] = u.ID
+		case "created_at":
+			values[i] = u.CreatedAt.UTC()
+		}
+	}
+	return values
+}
+
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// CompareSortValues orders two rows by their sort key values: negative when a comes
+// first. Nulls sort last in both directions.
+func CompareSortValues(keys []SortKey, a, b []any) int {
+	for i, k := range keys {
+		c := compareValue(a[i], b[i])
+		if c == 0 {
+			continue
+		}
+		if k.Desc && a[i] != nil && b[i] != nil {
+			c = -c
+		}
+		return c
+	}
+	return 0
+}
+
+func compareValue(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch a := a.(type) {
+	case int64:
+		b := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("due_at, -priority,created_at", TaskSortFields, DefaultTaskSort)
+	if err != nil || SortSpec(keys) != "due_at,-priority,created_at,id" {
+		t.Fatalf("got %+v %v", keys, err)
+	}
+	if keys, _ := ParseSort("-id", TaskSortFields, nil); SortSpec(keys) != "-id" {
+		t.Fatalf("expected id not to be appended twice, got %+v", keys)
+	}
+	if keys, _ := ParseSort("", TaskSortFields, DefaultTaskSort); SortSpec(keys) != "priority,id" {
+		t.Fatalf("expected the default, got %+v", keys)
+	}
+	for _, spec := range []string{"text", "due_at,due_at", "-", "due_at,", "priority;id"} {
+		if _, err := ParseSort(spec, TaskSortFields, nil); err != ErrInvalidSort {
+			t.Errorf("%q: expected ErrInvalidSort, got %v", spec, err)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys, _ := ParseSort("due_at,-priority,created_at", TaskSortFields, nil)
+	created := time.Date(2026, 1, 2, 10, 0, 0, 123456789, time.UTC)
+	values := []any{nil, int64(2), created, int64(42)}
+	got, err := DecodeCursor(EncodeCursor(keys, values), keys, TaskSortFields)
+	if err != nil || CompareSortValues(keys, got, values) != 0 || got[0] != nil {
+		t.Fatalf("got %+v %v", got, err)
+	}
+	other, _ := ParseSort("due_at", TaskSortFields, nil)
+	for _, c := range []string{"garbage!", "", EncodeCursor(other, []any{nil, int64(1)}), EncodeCursor(keys, []any{"x", 2, created, 42})} {
+		if _, err := DecodeCursor(c, keys, TaskSortFields); err != ErrInvalidCursor {
+			t.Errorf("%q: expected ErrInvalidCursor, got %v", c, err)
+		}
+	}
+}
+
+func TestCompareSortValues_NullsLast(t *testing.T) {
+	asc := []SortKey{{Field: "due_at"}}
+	desc := []SortKey{{Field: "due_at", Desc: true}}
+	now := time.Now()
+	for _, keys := range [][]SortKey{asc, desc} {
+		if CompareSortValues(keys, []any{now}, []any{nil}) >= 0 {
+			t.Fatalf("%+v: expected a value before null", keys)
+		}
+	}
+	if CompareSortValues(desc, []any{now}, []any{now.Add(-time.Hour)}) >= 0 {
+		t.Fatal("expected the later time first when descending")
+	}
+}
@@ -0,0 +1,62 @@
+package sqlstore
+
+import (
+	"errors"
+
+	"example.com/yourapp/internal/domain"
+)
+
+const attachmentColumns = `id, task_id, type, telegram_file_id, file_unique_id, coalesce(caption, '')`
+
+func scanAttachment(scanner taskScanner) (domain.Attachment, error) {
+	var a domain.Attachment
+	err := scanner.Scan(&a.ID, &a.TaskID, &a.Type, &a.TelegramFileID, &a.FileUniqueID, &a.Caption)
+	return a, err
+}
+
+func (s *Store) CreateAttachment(a domain.Attachment) (domain.Attachment, error) {
+	if s.db == nil {
+		return domain.Attachment{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		insert into attachments(task_id, type, telegram_file_id, file_unique_id, caption)
+		values ($1, $2, $3, $4, nullif($5, ''))
+		returning `+attachmentColumns,
+		a.TaskID,
+		a.Type,
+		a.TelegramFileID,
+		a.FileUniqueID,
+		a.Caption,
+	)
+	created, err := scanAttachment(row)
+	if err != nil {
+		return domain.Attachment{}, constraintError(err)
+	}
+	return created, nil
+}
+
+func (s *Store) ListAttachments(taskID int64) ([]domain.Attachment, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select `+attachmentColumns+`
+		from attachments
+		where task_id = $1
+		order by id`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]domain.Attachment, 0)
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
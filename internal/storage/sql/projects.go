@@ -0,0 +1,196 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"errors"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+const projectColumns = `id, user_id, name, color, archived, created_at, updated_at`
+
+// notArchived keeps tasks outside archived projects; it expects the tasks table unaliased.
+const notArchived = `(project_id is null or not exists(
+			select 1 from projects p where p.id = tasks.project_id and p.archived
+		))`
+
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func scanProject(scanner taskScanner) (domain.Project, error) {
+	var p domain.Project
+	err := scanner.Scan(&p.ID, &p.UserID, &p.Name, &p.Color, &p.Archived, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (s *Store) ListProjects(userID int64, includeArchived bool) ([]domain.Project, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select `+projectColumns+`
+		from projects
+		where user_id = $1 and ($2 or not archived)
+		order by id`,
+		userID,
+		includeArchived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []domain.Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) GetProject(id int64) (domain.Project, error) {
+	if s.db == nil {
+		return domain.Project{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select `+projectColumns+`
+		from projects
+		where id = $1`,
+		id,
+	)
+	p, err := scanProject(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Project{}, storage.ErrNotFound
+		}
+		return domain.Project{}, err
+	}
+	return p, nil
+}
+
+func (s *Store) CreateProject(p domain.Project) (domain.Project, error) {
+	if s.db == nil {
+		return domain.Project{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		insert into projects(user_id, name, color, archived)
+		values ($1, $2, $3, $4)
+		returning `+projectColumns,
+		p.UserID,
+		p.Name,
+		p.Color,
+		p.Archived,
+	)
+	created, err := scanProject(row)
+	if err != nil {
+		return domain.Project{}, constraintError(err)
+	}
+	return created, nil
+}
+
+// UpdateProject writes the name, color and archived flag and returns the stored row.
+func (s *Store) UpdateProject(p domain.Project) (domain.Project, error) {
+	if s.db == nil {
+		return domain.Project{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		update projects
+		set name = $1,
+			color = $2,
+			archived = $3,
+			updated_at = now()
+		where id = $4
+		returning `+projectColumns,
+		p.Name,
+		p.Color,
+		p.Archived,
+		p.ID,
+	)
+	updated, err := scanProject(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Project{}, storage.ErrNotFound
+		}
+		return domain.Project{}, constraintError(err)
+	}
+	return updated, nil
+}
+
+// DeleteProject moves the project's tasks to the inbox, or deletes them when withTasks
+// is set, and clears it as anyone's current project.
+func (s *Store) DeleteProject(id int64, withTasks bool) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if withTasks {
+		_, err = tx.Exec(`delete from tasks where project_id = $1`, id)
+	} else {
+		_, err = tx.Exec(`update tasks set project_id = null, version = version + 1, updated_at = now() where project_id = $1`, id)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`update users set current_project_id = null where current_project_id = $1`, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`delete from projects where id = $1`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (s *Store) SetCurrentProject(userID int64, projectID *int64) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	if err := checkProject(s.db, userID, projectID); err != nil {
+		return domain.User{}, err
+	}
+	row := s.db.QueryRow(`
+		update users
+		set current_project_id = $1
+		where id = $2
+		returning `+userColumns,
+		projectID,
+		userID,
+	)
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, storage.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+// checkProject reports storage.ErrNotFound unless projectID is nil or one of the user's
+// projects.
+func checkProject(q queryRower, userID int64, projectID *int64) error {
+	if projectID == nil {
+		return nil
+	}
+	var owner int64
+	err := q.QueryRow(`select user_id from projects where id = $1`, *projectID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != userID) {
+		return storage.ErrNotFound
+	}
+	return err
+}
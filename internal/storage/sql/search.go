@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// SearchTasks returns the user's tasks matching f whose text has a word for every term
+// of query, best matches first. Terms match word prefixes after stemming with both the
+// Russian and the English configuration; the Russian one stems Latin words as English,
+// so it alone highlights both.
+func (s *Store) SearchTasks(userID int64, query string, f storage.TaskFilter) ([]domain.SearchResult, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	terms := domain.SearchTerms(query)
+	if len(terms) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+	args := []any{userID, tsquery(terms)}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := append([]string{"user_id = $1", "search @@ q"}, filterConditions(f, arg)...)
+	rows, err := s.db.Query(`
+		select `+taskColumns+`,
+			ts_rank(search, q) as search_rank,
+			ts_headline('russian', text, q, 'StartSel=`+domain.HighlightStart+`, StopSel=`+domain.HighlightEnd+`, HighlightAll=true')
+		from tasks, (select to_tsquery('russian', $2) || to_tsquery('english', $2) as q) search_query
+		where `+strings.Join(where, "\n\t\t\tand ")+`
+		order by search_rank desc, id desc`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []domain.Task
+	var ranks []float64
+	var highlights []string
+	for rows.Next() {
+		var rank float64
+		var highlight string
+		t, err := scanTask(withExtra{rows, []any{&rank, &highlight}})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, t)
+		ranks = append(ranks, rank)
+		highlights = append(highlights, highlight)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.loadDetails(items); err != nil {
+		return nil, err
+	}
+	out := make([]domain.SearchResult, 0, len(items))
+	for i, t := range items {
+		out = append(out, domain.SearchResult{Task: t, Rank: ranks[i], Highlight: highlights[i]})
+	}
+	return out, nil
+}
+
+// tsquery ANDs the terms as prefixes. Terms are letters and digits only, so they need
+// no quoting.
+func tsquery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, t+":*")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// withExtra scans the columns after the task's into extra.
+type withExtra struct {
+	taskScanner
+	extra []any
+}
+
+func (w withExtra) Scan(dest ...any) error {
+	return w.taskScanner.Scan(append(dest, w.extra...)...)
+}
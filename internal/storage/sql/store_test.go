@@ -0,0 +1,41 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"example.com/yourapp/internal/app"
+	sqlstore "example.com/yourapp/internal/storage/sql"
+	"example.com/yourapp/internal/storage/storagetest"
+)
+
+// TestStoreConformance runs against the Postgres database in TEST_DB_DSN. The schema is
+// migrated once and the tables are truncated before every case, so point it at a
+// throwaway database.
+func TestStoreConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	store := sqlstore.New("pgx", dsn)
+	m, err := store.Migrator()
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	storagetest.Run(t, func(t *testing.T) app.Store {
+		if _, err := db.Exec(`truncate users, tasks, attachments, api_tokens restart identity cascade`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return store
+	})
+}
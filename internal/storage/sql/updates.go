@@ -0,0 +1,31 @@
+package sqlstore
+
+import (
+	"errors"
+	"time"
+
+	"example.com/yourapp/internal/storage"
+)
+
+// MarkUpdateSeen inserts the update id; the primary key makes a second insert, from this
+// or another replica, a no-op. Expired ids are deleted along the way.
+func (s *Store) MarkUpdateSeen(id int64, now time.Time) (bool, error) {
+	if s.db == nil {
+		return false, errors.New("db")
+	}
+	if _, err := s.db.Exec(`delete from telegram_updates where received_at < $1`, now.Add(-storage.UpdateRetention).UTC()); err != nil {
+		return false, err
+	}
+	res, err := s.db.Exec(`
+		insert into telegram_updates(update_id, received_at)
+		values ($1, $2)
+		on conflict do nothing`,
+		id,
+		now.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,29 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"example.com/yourapp/internal/app"
+	sqlitestore "example.com/yourapp/internal/storage/sqlite"
+	"example.com/yourapp/internal/storage/storagetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Store {
+		s, err := sqlitestore.New(filepath.Join(t.TempDir(), "tasks.db"))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		m, err := s.Migrator()
+		if err != nil {
+			t.Fatalf("migrator: %v", err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,216 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// notBlocked keeps tasks that don't wait for an active task; it expects the tasks table
+// unaliased.
+const notBlocked = `not exists(
+			select 1
+			from task_dependencies d
+			join tasks b on b.id = d.blocked_by_id
+			where d.task_id = tasks.id and b.status = '` + domain.TaskStatusActive + `'
+		)`
+
+// blockerColumns follows taskColumns in a returning clause so a written row comes back
+// with BlockedBy and Blocked; see scanBlockedTask. It expects the tasks table unaliased.
+const blockerColumns = `(
+			select group_concat(d.blocked_by_id, ',' order by d.blocked_by_id)
+			from task_dependencies d
+			where d.task_id = tasks.id
+		), not ` + notBlocked
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// AddDependency records that taskID is blocked by blockedByID. Both tasks have to belong
+// to the same user (storage.ErrNotFound otherwise); an edge that would close a cycle is
+// refused with storage.ErrCycle. Adding an existing edge is a no-op.
+func (s *Store) AddDependency(taskID, blockedByID int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	userID, err := dependencyOwner(tx, taskID, blockedByID)
+	if err != nil {
+		return err
+	}
+	if taskID == blockedByID {
+		return storage.ErrCycle
+	}
+	// Writing first takes the database write lock, so the edges read below can't change
+	// before the commit. The new edge starts at taskID and doesn't affect the check.
+	if _, err := tx.Exec(`
+		insert into task_dependencies(task_id, blocked_by_id)
+		values ($1, $2)
+		on conflict do nothing`,
+		taskID,
+		blockedByID,
+	); err != nil {
+		return constraintError(err)
+	}
+	edges, err := listDependencies(tx, userID)
+	if err != nil {
+		return err
+	}
+	if domain.CreatesCycle(edges, taskID, blockedByID) {
+		return storage.ErrCycle
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RemoveDependency(taskID, blockedByID int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	res, err := s.db.Exec(`delete from task_dependencies where task_id = $1 and blocked_by_id = $2`, taskID, blockedByID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ListDependencies returns every dependency between the user's tasks.
+func (s *Store) ListDependencies(userID int64) ([]domain.Dependency, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	return listDependencies(s.db, userID)
+}
+
+func listDependencies(q queryer, userID int64) ([]domain.Dependency, error) {
+	rows, err := q.Query(`
+		select d.task_id, d.blocked_by_id
+		from task_dependencies d
+		join tasks t on t.id = d.task_id
+		where t.user_id = $1
+		order by d.task_id, d.blocked_by_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []domain.Dependency
+	for rows.Next() {
+		var d domain.Dependency
+		if err := rows.Scan(&d.TaskID, &d.BlockedByID); err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// dependencyOwner returns the user owning both tasks, or storage.ErrNotFound when either
+// is missing or they belong to different users.
+func dependencyOwner(q queryRower, taskID, blockedByID int64) (int64, error) {
+	var owner, blockerOwner int64
+	if err := q.QueryRow(`select user_id from tasks where id = $1`, taskID).Scan(&owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrNotFound
+		}
+		return 0, err
+	}
+	if err := q.QueryRow(`select user_id from tasks where id = $1`, blockedByID).Scan(&blockerOwner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrNotFound
+		}
+		return 0, err
+	}
+	if owner != blockerOwner {
+		return 0, storage.ErrNotFound
+	}
+	return owner, nil
+}
+
+// loadBlockers fills in BlockedBy and Blocked for every task with a single query.
+func (s *Store) loadBlockers(tasks []domain.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[int64]int, len(tasks))
+	placeholders := make([]string, 0, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, t := range tasks {
+		tasks[i].BlockedBy = nil
+		tasks[i].Blocked = false
+		byID[t.ID] = i
+		args = append(args, t.ID)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+	rows, err := s.db.Query(`
+		select d.task_id, d.blocked_by_id, b.status
+		from task_dependencies d
+		join tasks b on b.id = d.blocked_by_id
+		where d.task_id in (`+strings.Join(placeholders, ", ")+`)
+		order by d.task_id, d.blocked_by_id`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskID, blockedByID int64
+		var status string
+		if err := rows.Scan(&taskID, &blockedByID, &status); err != nil {
+			return err
+		}
+		i := byID[taskID]
+		tasks[i].BlockedBy = append(tasks[i].BlockedBy, blockedByID)
+		if status == domain.TaskStatusActive {
+			tasks[i].Blocked = true
+		}
+	}
+	return rows.Err()
+}
+
+// scanBlockedTask reads taskColumns followed by blockerColumns.
+func scanBlockedTask(scanner taskScanner) (domain.Task, error) {
+	var blockedBy sql.NullString
+	var blocked bool
+	t, err := scanTask(withExtra{scanner, []any{&blockedBy, &blocked}})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	t.Blocked = blocked
+	if blockedBy.String == "" {
+		return t, nil
+	}
+	for _, field := range strings.Split(blockedBy.String, ",") {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return domain.Task{}, err
+		}
+		t.BlockedBy = append(t.BlockedBy, id)
+	}
+	return t, nil
+}
+
+func (s *Store) withBlockers(t domain.Task) (domain.Task, error) {
+	tasks := []domain.Task{t}
+	if err := s.loadBlockers(tasks); err != nil {
+		return domain.Task{}, err
+	}
+	return tasks[0], nil
+}
@@ -0,0 +1,56 @@
+package sqlitestore
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/storage"
+)
+
+// keyset turns a page into a condition selecting the rows after p.After (empty on the
+// first page) and an order by and limit clause. Field names are column names; nulls
+// sort last, as storage.CompareSortValues has it.
+func keyset(p storage.Page, keys []storage.SortKey, fields map[string]storage.FieldKind, arg func(any) string) (string, string) {
+	order := make([]string, 0, len(keys))
+	for _, k := range keys {
+		col := k.Field
+		if k.Desc {
+			col += " desc"
+		}
+		if fields[k.Field] == storage.FieldNullableTime {
+			col += " nulls last"
+		}
+		order = append(order, col)
+	}
+	tail := "order by " + strings.Join(order, ", ")
+	if p.Limit > 0 {
+		tail += "\n\t\tlimit " + strconv.Itoa(p.Limit)
+	}
+	if p.After == nil {
+		return "", tail
+	}
+	// Row-value comparison doesn't mix directions or handle nulls, so spell it out:
+	// (k1 after v1) or (k1 = v1 and k2 after v2) or ...
+	var terms, equal []string
+	for i, k := range keys {
+		v := p.After[i]
+		if v == nil {
+			// Nothing sorts after a null but other nulls.
+			equal = append(equal, k.Field+" is null")
+			continue
+		}
+		ph := arg(v)
+		op := " > "
+		if k.Desc {
+			op = " < "
+		}
+		after := k.Field + op + ph
+		if fields[k.Field] == storage.FieldNullableTime {
+			after = "(" + after + " or " + k.Field + " is null)"
+		}
+		terms = append(terms, "("+strings.Join(append(slices.Clip(equal), after), " and ")+")")
+		equal = append(equal, k.Field+" = "+ph)
+	}
+	return "(" + strings.Join(terms, "\n\t\t\t\tor ") + ")", tail
+}
@@ -0,0 +1,98 @@
+package sqlitestore
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// SearchTasks returns the user's tasks matching f whose text has a word for every term
+// of query, best matches first by bm25. Terms match word prefixes; there is no stemming.
+func (s *Store) SearchTasks(userID int64, query string, f storage.TaskFilter) ([]domain.SearchResult, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	terms := domain.SearchTerms(query)
+	if len(terms) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+	args := []any{userID, ftsQuery(terms)}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := append([]string{"user_id = $1"}, filterConditions(f, arg)...)
+	// The match runs in a subquery: bm25 and highlight only work there, and the outer
+	// query keeps tasks unaliased for the filter conditions.
+	rows, err := s.db.Query(`
+		select `+taskColumns+`, m.search_rank, m.highlight
+		from tasks
+		join (
+			select rowid as task_id,
+				-bm25(tasks_fts) as search_rank,
+				highlight(tasks_fts, 0, '`+domain.HighlightStart+`', '`+domain.HighlightEnd+`') as highlight
+			from tasks_fts
+			where tasks_fts match $2
+		) m on m.task_id = tasks.id
+		where `+strings.Join(where, "\n\t\t\tand ")+`
+		order by m.search_rank desc, id desc`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []domain.Task
+	var ranks []float64
+	var highlights []string
+	for rows.Next() {
+		var rank float64
+		var highlight string
+		t, err := scanTask(withExtra{rows, []any{&rank, &highlight}})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, t)
+		ranks = append(ranks, rank)
+		highlights = append(highlights, highlight)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.loadDetails(items); err != nil {
+		return nil, err
+	}
+	out := make([]domain.SearchResult, 0, len(items))
+	for i, t := range items {
+		out = append(out, domain.SearchResult{Task: t, Rank: ranks[i], Highlight: highlights[i]})
+	}
+	return out, nil
+}
+
+// ftsQuery ANDs the terms as prefix queries. Terms are letters and digits only, so
+// quoting them is enough to keep FTS5 operators out.
+func ftsQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, `"`+t+`"*`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// withExtra scans the columns after the task's into extra.
+type withExtra struct {
+	taskScanner
+	extra []any
+}
+
+func (w withExtra) Scan(dest ...any) error {
+	return w.taskScanner.Scan(append(dest, w.extra...)...)
+}
@@ -0,0 +1,44 @@
+package sqlitestore
+
+import (
+	"testing"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func TestSearchTasks_RanksShorterMatchesFirst(t *testing.T) {
+	s := newTestStore(t)
+	user, err := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	var ids []int64
+	for _, text := range []string{
+		"Молоко",
+		"Купить молоко, хлеб, сыр и яйца",
+		"Молоко для кота",
+		"Молоко маме",
+		"Молоко папе",
+	} {
+		task, err := s.CreateTask(domain.Task{UserID: user.ID, Text: text})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+	got, err := s.SearchTasks(user.ID, "молоко", storage.TaskFilter{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	// Equal ranks put the newer task first.
+	want := []int64{ids[0], ids[4], ids[3], ids[2], ids[1]}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), got)
+	}
+	for i, r := range got {
+		if r.ID != want[i] {
+			t.Fatalf("result %d: expected task %d, got %d (%q, rank %v)", i, want[i], r.ID, r.Text, r.Rank)
+		}
+	}
+}
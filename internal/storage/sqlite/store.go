@@ -0,0 +1,1063 @@
+// Package sqlitestore keeps everything in a single SQLite file for single-node installs.
+package sqlitestore
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/internal/storage/migrate"
+	"example.com/yourapp/migrations"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	// fold lowercases like strings.ToLower: SQLite's own lower() only knows ASCII, so it
+	// would leave Cyrillic text alone.
+	sqlite.MustRegisterDeterministicScalarFunction("fold", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+// Store mirrors the Postgres store. Timestamps are written by the store in UTC with a
+// fixed layout, so comparing them as text in SQL matches comparing the instants.
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// New opens (creating if needed) the database file at path. It leaves the schema alone;
+// apply it with Migrator.
+func New(path string) (*Store, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+		"_time_format": {"sqlite"},
+		// Transactions take the write lock up front and wait for it via busy_timeout. A
+		// deferred one that has read first (the FTS5 triggers read their index state)
+		// fails with SQLITE_BUSY instead of waiting when it tries to write.
+		"_txlock": {"immediate"},
+	}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// Migrator returns a runner for the embedded SQLite migrations.
+func (s *Store) Migrator() (*migrate.Runner, error) {
+	return migrate.New(s.db, migrations.SQLiteFS, migrate.SQLite)
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) timestamp() time.Time {
+	return s.now().UTC()
+}
+
+func utc(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+const taskColumns = `id, user_id, text, status, due_at, remind_at, notified_at, created_at, updated_at,
+	reminder_status, reminder_attempts, reminder_error, reminder_next_at, recurrence, priority, project_id, parent_id, version`
+
+const userColumns = `id, telegram_user_id, chat_id, timezone, role, created_at, current_project_id`
+
+type taskScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(scanner taskScanner) (domain.User, error) {
+	var u domain.User
+	var currentProjectID sql.NullInt64
+	if err := scanner.Scan(&u.ID, &u.TelegramUserID, &u.ChatID, &u.Timezone, &u.Role, &u.CreatedAt, &currentProjectID); err != nil {
+		return domain.User{}, err
+	}
+	u.CurrentProjectID = nullableID(currentProjectID)
+	return u, nil
+}
+
+func nullableID(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	id := v.Int64
+	return &id
+}
+
+func scanTask(scanner taskScanner) (domain.Task, error) {
+	var t domain.Task
+	var dueAt, remindAt, notifiedAt, reminderNextAt sql.NullTime
+	var reminderError sql.NullString
+	var projectID, parentID sql.NullInt64
+	if err := scanner.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Text,
+		&t.Status,
+		&dueAt,
+		&remindAt,
+		&notifiedAt,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&t.ReminderStatus,
+		&t.ReminderAttempts,
+		&reminderError,
+		&reminderNextAt,
+		&t.Recurrence,
+		&t.Priority,
+		&projectID,
+		&parentID,
+		&t.Version,
+	); err != nil {
+		return domain.Task{}, err
+	}
+	if dueAt.Valid {
+		t.DueAt = &dueAt.Time
+	}
+	if remindAt.Valid {
+		t.RemindAt = &remindAt.Time
+	}
+	if notifiedAt.Valid {
+		t.NotifiedAt = &notifiedAt.Time
+	}
+	t.ReminderError = reminderError.String
+	t.ProjectID = nullableID(projectID)
+	t.ParentID = nullableID(parentID)
+	if reminderNextAt.Valid {
+		t.ReminderNextAt = &reminderNextAt.Time
+	}
+	return t, nil
+}
+
+func collectTasks(rows *sql.Rows) ([]domain.Task, error) {
+	defer rows.Close()
+	var res []domain.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+// ListUsers returns one page of users; see storage.Page.
+func (s *Store) ListUsers(p storage.Page) ([]domain.User, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where, tail := keyset(p, p.SortOr(storage.DefaultUserSort), storage.UserSortFields, arg)
+	if where != "" {
+		where = "where " + where
+	}
+	rows, err := s.db.Query(`
+		select `+userColumns+`
+		from users
+		`+where+`
+		`+tail,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []domain.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) CreateUser(u domain.User) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	if u.Timezone == "" {
+		u.Timezone = "UTC"
+	}
+	if u.Role == "" {
+		u.Role = domain.RoleUser
+	}
+	row := s.db.QueryRow(`
+		insert into users(telegram_user_id, chat_id, timezone, role, created_at)
+		values ($1, $2, $3, $4, $5)
+		returning id, created_at`,
+		u.TelegramUserID,
+		u.ChatID,
+		u.Timezone,
+		u.Role,
+		s.timestamp(),
+	)
+	if err := row.Scan(&u.ID, &u.CreatedAt); err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return domain.User{}, storage.ErrConflict
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) GetUser(id int64) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select `+userColumns+`
+		from users
+		where id = $1`,
+		id,
+	)
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, storage.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) UpdateTimezone(id int64, tz string) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		update users
+		set timezone = $1
+		where id = $2
+		returning `+userColumns,
+		tz,
+		id,
+	)
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, storage.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) GetByTelegramID(telegramUserID int64) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select `+userColumns+`
+		from users
+		where telegram_user_id = $1`,
+		telegramUserID,
+	)
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, storage.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+// ListTasks returns one page of the user's tasks matching f; see storage.Page.
+func (s *Store) ListTasks(userID int64, f storage.TaskFilter, p storage.Page) ([]domain.Task, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := append([]string{"user_id = $1"}, filterConditions(f, arg)...)
+	after, tail := keyset(p, p.SortOr(storage.DefaultTaskSort), storage.TaskSortFields, arg)
+	if after != "" {
+		where = append(where, after)
+	}
+	rows, err := s.db.Query(`
+		select `+taskColumns+`
+		from tasks
+		where `+strings.Join(where, "\n\t\t\tand ")+`
+		`+tail,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	items, err := collectTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	return items, s.loadDetails(items)
+}
+
+// filterConditions turns f into where clauses on the unaliased tasks table; arg binds
+// a value and returns its placeholder. f.Tags have to be normalized.
+func filterConditions(f storage.TaskFilter, arg func(any) string) []string {
+	var where []string
+	if f.Status != "" {
+		where = append(where, "status = "+arg(f.Status))
+	}
+	if f.Priority != 0 {
+		where = append(where, "priority = "+arg(f.Priority))
+	}
+	if f.ProjectID != 0 {
+		where = append(where, "project_id = "+arg(f.ProjectID))
+	}
+	if f.HideArchived {
+		where = append(where, notArchived)
+	}
+	if len(f.Tags) > 0 {
+		where = append(where, tagCondition(f, arg))
+	}
+	if f.DueAfter != nil {
+		where = append(where, "due_at >= "+arg(f.DueAfter.UTC()))
+	}
+	if f.DueBefore != nil {
+		where = append(where, "due_at < "+arg(f.DueBefore.UTC()))
+	}
+	if f.OverdueAt != nil {
+		where = append(where, "status = "+arg(domain.TaskStatusActive)+" and due_at < "+arg(f.OverdueAt.UTC()))
+	}
+	if f.NoDue != nil {
+		if *f.NoDue {
+			where = append(where, "due_at is null")
+		} else {
+			where = append(where, "due_at is not null")
+		}
+	}
+	switch f.Reminder {
+	case "":
+	case storage.ReminderNone:
+		where = append(where, "remind_at is null")
+	default:
+		where = append(where, "remind_at is not null and reminder_status = "+arg(f.Reminder))
+	}
+	for _, w := range []struct {
+		column string
+		op     string
+		t      *time.Time
+	}{
+		{"created_at", ">=", f.CreatedAfter},
+		{"created_at", "<", f.CreatedBefore},
+		{"updated_at", ">=", f.UpdatedAfter},
+		{"updated_at", "<", f.UpdatedBefore},
+	} {
+		if w.t != nil {
+			where = append(where, w.column+" "+w.op+" "+arg(w.t.UTC()))
+		}
+	}
+	if f.TextContains != "" {
+		// A plain substring search: unlike like, nothing in the value is a wildcard.
+		where = append(where, "instr(fold(text), fold("+arg(f.TextContains)+")) > 0")
+	}
+	return where
+}
+
+// loadDetails fills in the tags, progress and blockers of listed tasks.
+func (s *Store) loadDetails(items []domain.Task) error {
+	if err := s.loadTags(items); err != nil {
+		return err
+	}
+	if err := s.loadProgress(items); err != nil {
+		return err
+	}
+	return s.loadBlockers(items)
+}
+
+func (s *Store) ListActive(userID int64) ([]domain.Task, error) {
+	return s.ListTasks(userID, storage.TaskFilter{Status: domain.TaskStatusActive, HideArchived: true}, storage.Page{})
+}
+
+func (s *Store) GetTask(id int64) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select `+taskColumns+`
+		from tasks
+		where id = $1`,
+		id,
+	)
+	t, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, storage.ErrNotFound
+		}
+		return domain.Task{}, err
+	}
+	t, err = s.withTags(t)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return s.withBlockers(t)
+}
+
+func (s *Store) GetByID(id int64) (domain.Task, error) {
+	return s.GetTask(id)
+}
+
+func (s *Store) CreateTask(t domain.Task) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Task{}, err
+	}
+	defer tx.Rollback()
+	t, err = s.insertTask(tx, t)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Task{}, err
+	}
+	return t, nil
+}
+
+// insertTask checks the task's project and parent and inserts it with its tags.
+func (s *Store) insertTask(tx *sql.Tx, t domain.Task) (domain.Task, error) {
+	if t.Status == "" {
+		t.Status = domain.TaskStatusActive
+	}
+	if t.Priority == 0 {
+		t.Priority = domain.PriorityDefault
+	}
+	if t.ReminderStatus == "" {
+		t.ReminderStatus = domain.ReminderStatusPending
+		if t.NotifiedAt != nil {
+			t.ReminderStatus = domain.ReminderStatusDelivered
+		}
+	}
+	tags, err := domain.NormalizeTags(t.Tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := checkProject(tx, t.UserID, t.ProjectID); err != nil {
+		return domain.Task{}, err
+	}
+	if err := checkParent(tx, t.UserID, t.ParentID); err != nil {
+		return domain.Task{}, err
+	}
+	now := s.timestamp()
+	row := tx.QueryRow(`
+		insert into tasks(user_id, text, status, due_at, remind_at, notified_at, reminder_status, recurrence,
+			priority, project_id, parent_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		returning id, created_at, updated_at, version`,
+		t.UserID,
+		t.Text,
+		t.Status,
+		utc(t.DueAt),
+		utc(t.RemindAt),
+		utc(t.NotifiedAt),
+		t.ReminderStatus,
+		t.Recurrence,
+		t.Priority,
+		t.ProjectID,
+		t.ParentID,
+		now,
+	)
+	if err := row.Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt, &t.Version); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.Task{}, storage.ErrNotFound
+		}
+		return domain.Task{}, err
+	}
+	if err := s.saveTags(tx, t.ID, t.UserID, tags); err != nil {
+		return domain.Task{}, err
+	}
+	t.Tags = tags
+	return t, nil
+}
+
+func (s *Store) Create(task domain.Task) (domain.Task, error) {
+	return s.CreateTask(task)
+}
+
+func (s *Store) MarkDone(id int64) (domain.Task, error) {
+	t, _, err := s.CompleteTask(id, storage.Completion{})
+	return t, err
+}
+
+// CompleteTask marks the task done unless it already is; see storage.Completion. The
+// next occurrence comes back only when this call created it.
+func (s *Store) CompleteTask(id int64, c storage.Completion) (domain.Task, *domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, nil, errors.New("db")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	defer tx.Rollback()
+	row := tx.QueryRow(`
+		update tasks
+		set version = version + 1,
+			status = $1,
+			updated_at = $2
+		where id = $3 and status <> $1
+		returning `+taskColumns+`, `+blockerColumns,
+		domain.TaskStatusDone,
+		s.timestamp(),
+		id,
+	)
+	t, err := scanBlockedTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Missing, or done already.
+		if err := tx.Rollback(); err != nil {
+			return domain.Task{}, nil, err
+		}
+		t, err := s.GetTask(id)
+		return t, nil, err
+	}
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	next, err := s.writeCompletion(tx, id, c)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Task{}, nil, err
+	}
+	t, err = s.withTags(t)
+	return t, next, err
+}
+
+// writeCompletion writes c for the task id that tx has just completed and returns the
+// created next occurrence, if any.
+func (s *Store) writeCompletion(tx *sql.Tx, id int64, c storage.Completion) (*domain.Task, error) {
+	if c.Subtasks {
+		if err := s.completeSubtasks(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	var next *domain.Task
+	if c.Next != nil {
+		created, err := s.insertTask(tx, *c.Next)
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range c.NextSubtasks {
+			sub.ParentID = &created.ID
+			sub.ProjectID = created.ProjectID
+			if _, err := s.insertTask(tx, sub); err != nil {
+				return nil, err
+			}
+		}
+		next = &created
+	}
+	return next, nil
+}
+
+func (s *Store) SetDue(id int64, dueAt *time.Time) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		update tasks
+		set version = version + 1,
+			due_at = $1,
+			updated_at = $2
+		where id = $3
+		returning `+taskColumns,
+		utc(dueAt),
+		s.timestamp(),
+		id,
+	)
+	t, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, storage.ErrNotFound
+		}
+		return domain.Task{}, err
+	}
+	return s.withTags(t)
+}
+
+func (s *Store) SetRemind(id int64, remindAt *time.Time) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		update tasks
+		set version = version + 1,
+			remind_at = $1,
+			notified_at = null,
+			reminder_status = $2,
+			reminder_attempts = 0,
+			reminder_error = null,
+			reminder_next_at = null,
+			updated_at = $3
+		where id = $4
+		returning `+taskColumns,
+		utc(remindAt),
+		domain.ReminderStatusPending,
+		s.timestamp(),
+		id,
+	)
+	t, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, storage.ErrNotFound
+		}
+		return domain.Task{}, err
+	}
+	return s.withTags(t)
+}
+
+func (s *Store) SetRecurrence(id int64, rule string) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		update tasks
+		set version = version + 1,
+			recurrence = $1,
+			updated_at = $2
+		where id = $3
+		returning `+taskColumns,
+		rule,
+		s.timestamp(),
+		id,
+	)
+	t, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, storage.ErrNotFound
+		}
+		return domain.Task{}, err
+	}
+	return s.withTags(t)
+}
+
+func (s *Store) UpdateTask(t domain.Task) (domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, errors.New("db")
+	}
+	tags, err := domain.NormalizeTags(t.Tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Task{}, err
+	}
+	defer tx.Rollback()
+	updated, err := s.updateTask(tx, t, tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Task{}, err
+	}
+	return updated, nil
+}
+
+// UpdateAndComplete writes t as UpdateTask does and, when that takes the task to done,
+// writes c in the same transaction, as CompleteTask would. The next occurrence comes
+// back only when c was written.
+func (s *Store) UpdateAndComplete(t domain.Task, c storage.Completion) (domain.Task, *domain.Task, error) {
+	if s.db == nil {
+		return domain.Task{}, nil, errors.New("db")
+	}
+	tags, err := domain.NormalizeTags(t.Tags)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	defer tx.Rollback()
+	var status string
+	if err := tx.QueryRow(`select status from tasks where id = $1`, t.ID).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, nil, storage.ErrNotFound
+		}
+		return domain.Task{}, nil, err
+	}
+	updated, err := s.updateTask(tx, t, tags)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	var next *domain.Task
+	if status != domain.TaskStatusDone && updated.Status == domain.TaskStatusDone {
+		if next, err = s.writeCompletion(tx, t.ID, c); err != nil {
+			return domain.Task{}, nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Task{}, nil, err
+	}
+	return updated, next, nil
+}
+
+// updateTask is UpdateTask within tx; tags are normalized already.
+func (s *Store) updateTask(tx *sql.Tx, t domain.Task, tags []string) (domain.Task, error) {
+	// A new remind_at or a cleared notified_at starts a fresh reminder, as in SetRemind; a
+	// new notified_at records a delivery, as in MarkNotified.
+	row := tx.QueryRow(`
+		update tasks
+		set version = version + 1,
+			text = $1,
+			status = $2,
+			due_at = $3,
+			remind_at = $4,
+			notified_at = case when remind_at is $4 then $5 end,
+			reminder_status = case
+				when remind_at is not $4 or $5 is null and notified_at is not null then $12
+				when notified_at is not $5 then $13
+				else reminder_status
+			end,
+			reminder_attempts = case
+				when remind_at is not $4 or $5 is null and notified_at is not null then 0
+				else reminder_attempts
+			end,
+			reminder_error = case when remind_at is $4 and notified_at is $5 then reminder_error end,
+			reminder_next_at = case when remind_at is $4 and notified_at is $5 then reminder_next_at end,
+			recurrence = $6,
+			priority = $7,
+			project_id = $8,
+			updated_at = $9
+		where id = $10 and (version = $11 or $11 = 0)
+		returning `+taskColumns+`, `+blockerColumns,
+		t.Text,
+		t.Status,
+		utc(t.DueAt),
+		utc(t.RemindAt),
+		utc(t.NotifiedAt),
+		t.Recurrence,
+		t.Priority,
+		t.ProjectID,
+		s.timestamp(),
+		t.ID,
+		t.Version,
+		domain.ReminderStatusPending,
+		domain.ReminderStatusDelivered,
+	)
+	updated, err := scanBlockedTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, staleOrMissing(tx, t.ID)
+		}
+		return domain.Task{}, err
+	}
+	if err := checkProject(tx, updated.UserID, updated.ProjectID); err != nil {
+		return domain.Task{}, err
+	}
+	if err := s.saveTags(tx, updated.ID, updated.UserID, tags); err != nil {
+		return domain.Task{}, err
+	}
+	updated.Tags = tags
+	return updated, nil
+}
+
+func (s *Store) ListDueForNotify(now time.Time, lease time.Duration) ([]domain.Task, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		update tasks
+		set reminder_status = $2,
+			reminder_attempts = reminder_attempts + 1,
+			reminder_next_at = $3
+		where status = $4
+			and remind_at is not null
+			and remind_at <= $1
+			and notified_at is null
+			and reminder_status in ($2, $5)
+			and (reminder_next_at is null or reminder_next_at <= $1)
+			and `+notArchived+`
+			and `+notBlocked+`
+		returning `+taskColumns,
+		now.UTC(),
+		domain.ReminderStatusSending,
+		now.Add(lease).UTC(),
+		domain.TaskStatusActive,
+		domain.ReminderStatusPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := collectTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, s.loadTags(claimed)
+}
+
+func (s *Store) MarkNotified(id int64, at time.Time) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	res, err := s.db.Exec(`
+		update tasks
+		set notified_at = $1,
+			reminder_status = $2,
+			reminder_error = null,
+			reminder_next_at = null
+		where id = $3 and reminder_status = $4`,
+		at.UTC(),
+		domain.ReminderStatusDelivered,
+		id,
+		domain.ReminderStatusSending,
+	)
+	return s.checkReminderUpdate(res, err, id)
+}
+
+func (s *Store) MarkNotifyFailed(id int64, reason string, retryAt *time.Time) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	status := domain.ReminderStatusPending
+	if retryAt == nil {
+		status = domain.ReminderStatusFailed
+	}
+	res, err := s.db.Exec(`
+		update tasks
+		set reminder_status = $1,
+			reminder_error = $2,
+			reminder_next_at = $3
+		where id = $4 and reminder_status = $5`,
+		status,
+		reason,
+		utc(retryAt),
+		id,
+		domain.ReminderStatusSending,
+	)
+	return s.checkReminderUpdate(res, err, id)
+}
+
+// checkReminderUpdate tells a missing task (ErrNotFound) from one that is no longer being
+// sent, which is a no-op so repeated acknowledgements stay harmless.
+func (s *Store) checkReminderUpdate(res sql.Result, err error, id int64) error {
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRow(`select exists(select 1 from tasks where id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListReminders(status string) ([]domain.Task, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select `+taskColumns+`
+		from tasks
+		where remind_at is not null
+			and ($1 = '' or reminder_status = $1)
+		order by id`,
+		status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	items, err := collectTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	return items, s.loadTags(items)
+}
+
+func (s *Store) DeleteTask(id, version int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`delete from tasks where parent_id = $1`, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`delete from tasks where id = $1 and (version = $2 or $2 = 0)`, id, version)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// The deferred rollback brings back the subtasks deleted above.
+		return staleOrMissing(tx, id)
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Delete(id int64) error {
+	return s.DeleteTask(id, 0)
+}
+
+// staleOrMissing tells why a write to task id that asked for a version matched no row.
+func staleOrMissing(q queryRower, id int64) error {
+	var exists bool
+	if err := q.QueryRow(`select exists(select 1 from tasks where id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return storage.ErrStale
+	}
+	return storage.ErrNotFound
+}
+
+func (s *Store) CreateToken(t domain.APIToken) (domain.APIToken, error) {
+	if s.db == nil {
+		return domain.APIToken{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		insert into api_tokens(user_id, name, token_hash, expires_at, created_at)
+		values ($1, $2, $3, $4, $5)
+		returning id, created_at`,
+		t.UserID,
+		t.Name,
+		t.Hash,
+		utc(t.ExpiresAt),
+		s.timestamp(),
+	)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.APIToken{}, storage.ErrNotFound
+		}
+		return domain.APIToken{}, err
+	}
+	return t, nil
+}
+
+func (s *Store) GetUserByTokenHash(hash string, now time.Time) (domain.User, error) {
+	if s.db == nil {
+		return domain.User{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select u.id, u.telegram_user_id, u.chat_id, u.timezone, u.role, u.created_at, u.current_project_id
+		from api_tokens t
+		join users u on u.id = t.user_id
+		where t.token_hash = $1
+			and (t.expires_at is null or t.expires_at > $2)`,
+		hash,
+		now.UTC(),
+	)
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, storage.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) ListTokens(userID int64) ([]domain.APIToken, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select id, user_id, name, token_hash, created_at, expires_at
+		from api_tokens
+		where user_id = $1
+		order by id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []domain.APIToken
+	for rows.Next() {
+		var t domain.APIToken
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Hash, &t.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) DeleteToken(userID, id int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	res, err := s.db.Exec(`delete from api_tokens where id = $1 and user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteExpiredTokens(now time.Time) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	_, err := s.db.Exec(`delete from api_tokens where expires_at <= $1`, now.UTC())
+	return err
+}
@@ -0,0 +1,120 @@
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := openMigrated(t, filepath.Join(t.TempDir(), "tasks.db"))
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func openMigrated(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return s
+}
+
+func TestNew_LeavesSchemaAlone(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	all, applied, err := m.Status(context.Background())
+	if err != nil || len(all) == 0 || len(applied) != 0 {
+		t.Fatalf("expected every migration pending, got %d applied of %d (%v)", len(applied), len(all), err)
+	}
+	if _, err := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1}); err == nil {
+		t.Fatalf("expected writes to fail without a schema")
+	}
+}
+
+func TestStore_TasksRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	user, err := s.CreateUser(domain.User{TelegramUserID: 1, ChatID: 1})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := s.CreateTask(domain.Task{UserID: user.ID + 100, Text: "orphan"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown user, got %v", err)
+	}
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	remindAt := time.Date(2026, 1, 2, 12, 0, 0, 0, moscow)
+	task, err := s.CreateTask(domain.Task{UserID: user.ID, Text: "call", RemindAt: &remindAt})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	got, err := s.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if got.RemindAt == nil || !got.RemindAt.Equal(remindAt) || got.ReminderStatus != domain.ReminderStatusPending {
+		t.Fatalf("unexpected task %+v", got)
+	}
+
+	// Stored as UTC text, so a time in another zone still compares correctly.
+	due, err := s.ListDueForNotify(remindAt.Add(-time.Second).In(time.UTC), time.Minute)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("expected nothing due before remind_at, got %v %v", due, err)
+	}
+	due, err = s.ListDueForNotify(remindAt, time.Minute)
+	if err != nil || len(due) != 1 || due[0].ReminderStatus != domain.ReminderStatusSending {
+		t.Fatalf("expected task claimed, got %+v %v", due, err)
+	}
+	if err := s.MarkNotified(task.ID, remindAt); err != nil {
+		t.Fatalf("mark notified: %v", err)
+	}
+
+	if err := s.DeleteTask(task.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.GetTask(task.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestNew_ReopensExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s := openMigrated(t, path)
+	if _, err := s.CreateUser(domain.User{TelegramUserID: 7, ChatID: 7}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	_ = s.Close()
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.GetByTelegramID(7); err != nil {
+		t.Fatalf("expected user to survive reopen, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// ListSubtasks returns the subtasks of a task in the order they were added.
+func (s *Store) ListSubtasks(parentID int64) ([]domain.Task, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select `+taskColumns+`
+		from tasks
+		where parent_id = $1
+		order by id`,
+		parentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	items, err := collectTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	return items, s.loadTags(items)
+}
+
+// CompleteSubtasks marks every active subtask of the task done.
+func (s *Store) CompleteSubtasks(parentID int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	return s.completeSubtasks(s.db, parentID)
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) completeSubtasks(e execer, parentID int64) error {
+	_, err := e.Exec(`
+		update tasks
+		set version = version + 1,
+			status = $1,
+			updated_at = $2
+		where parent_id = $3 and status = $4`,
+		domain.TaskStatusDone,
+		s.timestamp(),
+		parentID,
+		domain.TaskStatusActive,
+	)
+	return err
+}
+
+// checkParent reports storage.ErrNotFound unless parentID is nil or one of the user's
+// tasks.
+func checkParent(q queryRower, userID int64, parentID *int64) error {
+	if parentID == nil {
+		return nil
+	}
+	var owner int64
+	err := q.QueryRow(`select user_id from tasks where id = $1`, *parentID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != userID) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+// loadProgress fills in Progress for every task that has subtasks with a single query.
+func (s *Store) loadProgress(tasks []domain.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[int64]int, len(tasks))
+	placeholders := make([]string, 0, len(tasks))
+	args := []any{domain.TaskStatusDone}
+	for i, t := range tasks {
+		tasks[i].Progress = nil
+		byID[t.ID] = i
+		args = append(args, t.ID)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+	rows, err := s.db.Query(`
+		select parent_id, sum(case when status = $1 then 1 else 0 end), count(*)
+		from tasks
+		where parent_id in (`+strings.Join(placeholders, ", ")+`)
+		group by parent_id`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var parentID int64
+		var p domain.Progress
+		if err := rows.Scan(&parentID, &p.Done, &p.Total); err != nil {
+			return err
+		}
+		tasks[byID[parentID]].Progress = &p
+	}
+	return rows.Err()
+}
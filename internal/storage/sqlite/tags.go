@@ -0,0 +1,245 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const tagColumns = `id, user_id, name, created_at`
+
+func scanTag(scanner taskScanner) (domain.Tag, error) {
+	var t domain.Tag
+	err := scanner.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	return t, err
+}
+
+func (s *Store) ListTags(userID int64) ([]domain.Tag, error) {
+	if s.db == nil {
+		return nil, errors.New("db")
+	}
+	rows, err := s.db.Query(`
+		select `+tagColumns+`
+		from tags
+		where user_id = $1
+		order by name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []domain.Tag
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) GetTag(id int64) (domain.Tag, error) {
+	if s.db == nil {
+		return domain.Tag{}, errors.New("db")
+	}
+	row := s.db.QueryRow(`
+		select `+tagColumns+`
+		from tags
+		where id = $1`,
+		id,
+	)
+	t, err := scanTag(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Tag{}, storage.ErrNotFound
+		}
+		return domain.Tag{}, err
+	}
+	return t, nil
+}
+
+func (s *Store) CreateTag(t domain.Tag) (domain.Tag, error) {
+	if s.db == nil {
+		return domain.Tag{}, errors.New("db")
+	}
+	name, err := domain.NormalizeTag(t.Name)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	row := s.db.QueryRow(`
+		insert into tags(user_id, name, created_at)
+		values ($1, $2, $3)
+		returning `+tagColumns,
+		t.UserID,
+		name,
+		s.timestamp(),
+	)
+	created, err := scanTag(row)
+	if err != nil {
+		return domain.Tag{}, constraintError(err)
+	}
+	return created, nil
+}
+
+// RenameTag renames the tag; tasks refer to it by id, so they follow along.
+func (s *Store) RenameTag(id int64, name string) (domain.Tag, error) {
+	if s.db == nil {
+		return domain.Tag{}, errors.New("db")
+	}
+	name, err := domain.NormalizeTag(name)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	row := s.db.QueryRow(`
+		update tags
+		set name = $1
+		where id = $2
+		returning `+tagColumns,
+		name,
+		id,
+	)
+	t, err := scanTag(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Tag{}, storage.ErrNotFound
+		}
+		return domain.Tag{}, constraintError(err)
+	}
+	return t, nil
+}
+
+// DeleteTag removes the tag; task_tags rows go with it.
+func (s *Store) DeleteTag(id int64) error {
+	if s.db == nil {
+		return errors.New("db")
+	}
+	res, err := s.db.Exec(`delete from tags where id = $1`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// constraintError maps foreign key and unique violations to the storage errors.
+func constraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return storage.ErrNotFound
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return storage.ErrConflict
+		}
+	}
+	return err
+}
+
+// saveTags makes names the task's complete tag set, creating missing tags for the user.
+func (s *Store) saveTags(tx *sql.Tx, taskID, userID int64, names []string) error {
+	if _, err := tx.Exec(`delete from task_tags where task_id = $1`, taskID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(`
+			insert into tags(user_id, name, created_at)
+			values ($1, $2, $3)
+			on conflict (user_id, name) do nothing`,
+			userID,
+			name,
+			s.timestamp(),
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			insert into task_tags(task_id, tag_id)
+			select $1, id from tags where user_id = $2 and name = $3`,
+			taskID,
+			userID,
+			name,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in Tags for every task with a single query.
+func (s *Store) loadTags(tasks []domain.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[int64]int, len(tasks))
+	placeholders := make([]string, 0, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, t := range tasks {
+		tasks[i].Tags = nil
+		byID[t.ID] = i
+		args = append(args, t.ID)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+	rows, err := s.db.Query(`
+		select tt.task_id, g.name
+		from task_tags tt
+		join tags g on g.id = tt.tag_id
+		where tt.task_id in (`+strings.Join(placeholders, ", ")+`)
+		order by tt.task_id, g.name`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskID int64
+		var name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		i := byID[taskID]
+		tasks[i].Tags = append(tasks[i].Tags, name)
+	}
+	return rows.Err()
+}
+
+func (s *Store) withTags(t domain.Task) (domain.Task, error) {
+	tasks := []domain.Task{t}
+	if err := s.loadTags(tasks); err != nil {
+		return domain.Task{}, err
+	}
+	return tasks[0], nil
+}
+
+// tagCondition matches tasks carrying any (or all) of the filter's tags; arg binds a
+// value and returns its placeholder.
+func tagCondition(f storage.TaskFilter, arg func(v any) string) string {
+	names := make([]string, 0, len(f.Tags))
+	for _, name := range f.Tags {
+		names = append(names, arg(name))
+	}
+	matched := `(
+			select count(*)
+			from task_tags tt
+			join tags g on g.id = tt.tag_id
+			where tt.task_id = tasks.id and g.name in (` + strings.Join(names, ", ") + `)
+		)`
+	if f.AllTags {
+		return matched + " = " + arg(len(f.Tags))
+	}
+	return matched + " > 0"
+}
@@ -0,0 +1,1619 @@
+// Package storagetest is a behavioral suite every app.Store implementation has to pass,
+// so the memory, Postgres and SQLite stores stay interchangeable.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/app"
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// Run executes the suite. open must return an empty store for every call.
+func Run(t *testing.T, open func(t *testing.T) app.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s app.Store)
+	}{
+		{"Users", testUsers},
+		{"UserUniqueTelegramID", testUserUniqueTelegramID},
+		{"TaskCRUD", testTaskCRUD},
+		{"TaskNotFound", testTaskNotFound},
+		{"CompleteTask", testCompleteTask},
+		{"UpdateAndComplete", testUpdateAndComplete},
+		{"ListTasksScopeAndOrder", testListTasksScopeAndOrder},
+		{"ListTasksPriority", testListTasksPriority},
+		{"ListTasksPages", testListTasksPages},
+		{"ListUsersPages", testListUsersPages},
+		{"Tags", testTags},
+		{"ListTasksByTag", testListTasksByTag},
+		{"ListTasksRichFilters", testListTasksRichFilters},
+		{"Projects", testProjects},
+		{"DeleteProject", testDeleteProject},
+		{"ArchivedProjectTasks", testArchivedProjectTasks},
+		{"Subtasks", testSubtasks},
+		{"DeleteSubtasks", testDeleteSubtasks},
+		{"Dependencies", testDependencies},
+		{"BlockedReminders", testBlockedReminders},
+		{"Attachments", testAttachments},
+		{"SearchTasks", testSearchTasks},
+		{"UpdateTaskReturnsStoredRow", testUpdateTaskReturnsStoredRow},
+		{"TaskVersions", testTaskVersions},
+		{"SetRemindResetsDelivery", testSetRemindResetsDelivery},
+		{"UpdateTaskRemindResetsDelivery", testUpdateTaskRemindResetsDelivery},
+		{"UpdateTaskNotifiedAt", testUpdateTaskNotifiedAt},
+		{"NotifyClaimAndLease", testNotifyClaimAndLease},
+		{"NotifyIdempotent", testNotifyIdempotent},
+		{"NotifyFailure", testNotifyFailure},
+		{"Tokens", testTokens},
+		{"TelegramUpdates", testTelegramUpdates},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"ConcurrentClaim", testConcurrentClaim},
+		{"ConcurrentComplete", testConcurrentComplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// base is truncated to seconds so every backend stores it exactly.
+var base = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+func at(d time.Duration) *time.Time {
+	v := base.Add(d)
+	return &v
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func mustUser(t *testing.T, s app.Store, telegramID int64) domain.User {
+	t.Helper()
+	u, err := s.CreateUser(domain.User{TelegramUserID: telegramID, ChatID: telegramID})
+	if err != nil {
+		t.Fatalf("create user %d: %v", telegramID, err)
+	}
+	return u
+}
+
+func mustTask(t *testing.T, s app.Store, task domain.Task) domain.Task {
+	t.Helper()
+	created, err := s.CreateTask(task)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return created
+}
+
+func testUsers(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 100)
+	if u.ID == 0 || u.Timezone != "UTC" || u.Role != domain.RoleUser || u.CreatedAt.IsZero() {
+		t.Fatalf("expected defaults to be filled in, got %+v", u)
+	}
+	admin, err := s.CreateUser(domain.User{TelegramUserID: 200, ChatID: 201, Timezone: "Europe/Moscow", Role: domain.RoleAdmin})
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+
+	got, err := s.GetUser(admin.ID)
+	if err != nil || got.ChatID != 201 || got.Timezone != "Europe/Moscow" || !got.IsAdmin() {
+		t.Fatalf("GetUser: got %+v %v", got, err)
+	}
+	got, err = s.GetByTelegramID(100)
+	if err != nil || got.ID != u.ID {
+		t.Fatalf("GetByTelegramID: got %+v %v", got, err)
+	}
+	if _, err := s.GetByTelegramID(999); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetByTelegramID unknown: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUser(admin.ID + 100); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetUser unknown: expected ErrNotFound, got %v", err)
+	}
+
+	updated, err := s.UpdateTimezone(u.ID, "Asia/Tokyo")
+	if err != nil || updated.Timezone != "Asia/Tokyo" || updated.TelegramUserID != 100 {
+		t.Fatalf("UpdateTimezone: got %+v %v", updated, err)
+	}
+	if _, err := s.UpdateTimezone(admin.ID+100, "UTC"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("UpdateTimezone unknown: expected ErrNotFound, got %v", err)
+	}
+
+	users, err := s.ListUsers(storage.Page{})
+	if err != nil || len(users) != 2 || users[0].ID != u.ID || users[1].ID != admin.ID {
+		t.Fatalf("ListUsers: expected both users ordered by id, got %+v %v", users, err)
+	}
+	if users[0].Timezone != "Asia/Tokyo" {
+		t.Fatalf("ListUsers: expected updated timezone, got %q", users[0].Timezone)
+	}
+}
+
+func testUserUniqueTelegramID(t *testing.T, s app.Store) {
+	mustUser(t, s, 100)
+	if _, err := s.CreateUser(domain.User{TelegramUserID: 100, ChatID: 5}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for duplicate telegram_user_id, got %v", err)
+	}
+	users, err := s.ListUsers(storage.Page{})
+	if err != nil || len(users) != 1 {
+		t.Fatalf("expected the duplicate not to be stored, got %+v %v", users, err)
+	}
+}
+
+func testTaskCRUD(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "write report", DueAt: at(time.Hour), Recurrence: "FREQ=DAILY"})
+	if task.ID == 0 || task.Status != domain.TaskStatusActive || task.ReminderStatus != domain.ReminderStatusPending {
+		t.Fatalf("expected defaults on create, got %+v", task)
+	}
+	if task.CreatedAt.IsZero() || task.UpdatedAt.IsZero() {
+		t.Fatalf("expected timestamps on create, got %+v", task)
+	}
+	delivered := mustTask(t, s, domain.Task{UserID: u.ID, Text: "old", RemindAt: at(0), NotifiedAt: at(0)})
+	if delivered.ReminderStatus != domain.ReminderStatusDelivered {
+		t.Fatalf("expected a notified task to start delivered, got %q", delivered.ReminderStatus)
+	}
+
+	got, err := s.GetTask(task.ID)
+	if err != nil || got.Text != "write report" || got.UserID != u.ID || !sameTime(got.DueAt, at(time.Hour)) || got.Recurrence != "FREQ=DAILY" {
+		t.Fatalf("GetTask: got %+v %v", got, err)
+	}
+	if byID, err := s.GetByID(task.ID); err != nil || byID.ID != task.ID {
+		t.Fatalf("GetByID: got %+v %v", byID, err)
+	}
+
+	got, err = s.SetDue(task.ID, at(2*time.Hour))
+	if err != nil || !sameTime(got.DueAt, at(2*time.Hour)) {
+		t.Fatalf("SetDue: got %+v %v", got, err)
+	}
+	got, err = s.SetDue(task.ID, nil)
+	if err != nil || got.DueAt != nil {
+		t.Fatalf("SetDue(nil): got %+v %v", got, err)
+	}
+	got, err = s.SetRecurrence(task.ID, "")
+	if err != nil || got.Recurrence != "" {
+		t.Fatalf("SetRecurrence: got %+v %v", got, err)
+	}
+	got, err = s.MarkDone(task.ID)
+	if err != nil || got.Status != domain.TaskStatusDone {
+		t.Fatalf("MarkDone: got %+v %v", got, err)
+	}
+	got, err = s.MarkDone(task.ID)
+	if err != nil || got.Status != domain.TaskStatusDone {
+		t.Fatalf("MarkDone twice: got %+v %v", got, err)
+	}
+
+	if err := s.DeleteTask(task.ID, 0); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := s.GetTask(task.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetTask after delete: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(task.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("Delete twice: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetTask(delivered.ID); err != nil {
+		t.Fatalf("expected other task to survive, got %v", err)
+	}
+}
+
+func testTaskNotFound(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	if _, err := s.CreateTask(domain.Task{UserID: u.ID + 100, Text: "orphan"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("CreateTask for unknown user: expected ErrNotFound, got %v", err)
+	}
+	const missing = 4242
+	checks := map[string]error{}
+	_, checks["GetTask"] = s.GetTask(missing)
+	_, checks["MarkDone"] = s.MarkDone(missing)
+	_, _, checks["CompleteTask"] = s.CompleteTask(missing, storage.Completion{})
+	_, checks["SetDue"] = s.SetDue(missing, at(0))
+	_, checks["SetRemind"] = s.SetRemind(missing, at(0))
+	_, checks["SetRecurrence"] = s.SetRecurrence(missing, "FREQ=DAILY")
+	_, checks["UpdateTask"] = s.UpdateTask(domain.Task{ID: missing, UserID: u.ID, Text: "x", Status: domain.TaskStatusActive})
+	checks["DeleteTask"] = s.DeleteTask(missing, 0)
+	checks["MarkNotified"] = s.MarkNotified(missing, base)
+	checks["MarkNotifyFailed"] = s.MarkNotifyFailed(missing, "boom", nil)
+	for name, err := range checks {
+		if !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+}
+
+func testCompleteTask(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	p, err := s.CreateProject(domain.Project{UserID: u.ID, Name: "Дом"})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "water plants", ProjectID: &p.ID, DueAt: at(0), Recurrence: "FREQ=DAILY"})
+	c := storage.Completion{
+		Next:         &domain.Task{UserID: u.ID, Text: "water plants", ProjectID: &p.ID, DueAt: at(24 * time.Hour), Recurrence: "FREQ=DAILY"},
+		NextSubtasks: []domain.Task{{UserID: u.ID, Text: "fill the can", Tags: []string{"garden"}}},
+	}
+	done, next, err := s.CompleteTask(task.ID, c)
+	if err != nil || done.Status != domain.TaskStatusDone || done.Version != task.Version+1 {
+		t.Fatalf("CompleteTask: got %+v %v", done, err)
+	}
+	if next == nil || next.ID == task.ID || next.Status != domain.TaskStatusActive || !sameTime(next.DueAt, at(24*time.Hour)) {
+		t.Fatalf("CompleteTask: expected the next occurrence, got %+v", next)
+	}
+	subtasks, err := s.ListSubtasks(next.ID)
+	if err != nil || len(subtasks) != 1 {
+		t.Fatalf("ListSubtasks(next): got %+v %v", subtasks, err)
+	}
+	if sub := subtasks[0]; sub.ProjectID == nil || *sub.ProjectID != p.ID || !slices.Equal(sub.Tags, []string{"garden"}) || sub.Status != domain.TaskStatusActive {
+		t.Fatalf("expected the subtask in the project with its tags, got %+v", sub)
+	}
+
+	again, next, err := s.CompleteTask(task.ID, c)
+	if err != nil || next != nil || again.Status != domain.TaskStatusDone || again.Version != done.Version {
+		t.Fatalf("CompleteTask twice: expected a no-op, got %+v %+v %v", again, next, err)
+	}
+	tasks, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(tasks) != 3 {
+		t.Fatalf("expected no second occurrence, got %d tasks %v", len(tasks), err)
+	}
+}
+
+func testUpdateAndComplete(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "water plants", DueAt: at(0), Recurrence: "FREQ=DAILY"})
+	child := mustTask(t, s, domain.Task{UserID: u.ID, Text: "fill the can", ParentID: &task.ID})
+	c := storage.Completion{
+		Subtasks:     true,
+		Next:         &domain.Task{UserID: u.ID, Text: "water plants", DueAt: at(24 * time.Hour), Recurrence: "FREQ=DAILY"},
+		NextSubtasks: []domain.Task{{UserID: u.ID, Text: "fill the can"}},
+	}
+
+	stale := task
+	stale.Status = domain.TaskStatusDone
+	stale.Version = task.Version + 1
+	if _, next, err := s.UpdateAndComplete(stale, c); !errors.Is(err, storage.ErrStale) || next != nil {
+		t.Fatalf("expected ErrStale for an old version, got %+v %v", next, err)
+	}
+	missing := stale
+	missing.ID = task.ID + 1000
+	if _, _, err := s.UpdateAndComplete(missing, c); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if tasks, _ := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{}); len(tasks) != 2 {
+		t.Fatalf("expected a failed write to leave nothing behind, got %+v", tasks)
+	}
+
+	// An edit that doesn't complete the task writes only the edit.
+	edit := task
+	edit.Text = "water the plants"
+	edited, next, err := s.UpdateAndComplete(edit, c)
+	if err != nil || next != nil || edited.Text != "water the plants" || edited.Status != domain.TaskStatusActive {
+		t.Fatalf("UpdateAndComplete without completing: got %+v %+v %v", edited, next, err)
+	}
+
+	edited.Status = domain.TaskStatusDone
+	edited.Text = "watered"
+	done, next, err := s.UpdateAndComplete(edited, c)
+	if err != nil || done.Status != domain.TaskStatusDone || done.Text != "watered" || done.Version != edited.Version+1 {
+		t.Fatalf("UpdateAndComplete: got %+v %v", done, err)
+	}
+	if next == nil || next.ID == task.ID || !sameTime(next.DueAt, at(24*time.Hour)) {
+		t.Fatalf("UpdateAndComplete: expected the next occurrence, got %+v", next)
+	}
+	if got, _ := s.GetTask(child.ID); got.Status != domain.TaskStatusDone {
+		t.Fatalf("expected the subtask closed with its parent, got %+v", got)
+	}
+	if subtasks, err := s.ListSubtasks(next.ID); err != nil || len(subtasks) != 1 {
+		t.Fatalf("ListSubtasks(next): got %+v %v", subtasks, err)
+	}
+
+	// Editing a done task doesn't complete it again.
+	done.Text = "watered twice"
+	again, next, err := s.UpdateAndComplete(done, c)
+	if err != nil || next != nil || again.Text != "watered twice" {
+		t.Fatalf("UpdateAndComplete on a done task: got %+v %+v %v", again, next, err)
+	}
+	if tasks, _ := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{}); len(tasks) != 4 {
+		t.Fatalf("expected one next occurrence with its subtask, got %d tasks", len(tasks))
+	}
+}
+
+func testListTasksScopeAndOrder(t *testing.T, s app.Store) {
+	alice := mustUser(t, s, 1)
+	bob := mustUser(t, s, 2)
+	a1 := mustTask(t, s, domain.Task{UserID: alice.ID, Text: "a1"})
+	b1 := mustTask(t, s, domain.Task{UserID: bob.ID, Text: "b1"})
+	a2 := mustTask(t, s, domain.Task{UserID: alice.ID, Text: "a2"})
+	a3 := mustTask(t, s, domain.Task{UserID: alice.ID, Text: "a3"})
+	if _, err := s.MarkDone(a2.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+
+	ids := func(tasks []domain.Task) []int64 {
+		out := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			out = append(out, task.ID)
+		}
+		return out
+	}
+	equal := func(got []int64, want ...int64) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	all, err := s.ListTasks(alice.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || !equal(ids(all), a1.ID, a2.ID, a3.ID) {
+		t.Fatalf("ListTasks all: got %v %v", ids(all), err)
+	}
+	active, err := s.ListTasks(alice.ID, storage.TaskFilter{Status: domain.TaskStatusActive}, storage.Page{})
+	if err != nil || !equal(ids(active), a1.ID, a3.ID) {
+		t.Fatalf("ListTasks active: got %v %v", ids(active), err)
+	}
+	if viaRepo, err := s.ListActive(alice.ID); err != nil || !equal(ids(viaRepo), a1.ID, a3.ID) {
+		t.Fatalf("ListActive: got %v %v", ids(viaRepo), err)
+	}
+	done, err := s.ListTasks(alice.ID, storage.TaskFilter{Status: domain.TaskStatusDone}, storage.Page{})
+	if err != nil || !equal(ids(done), a2.ID) {
+		t.Fatalf("ListTasks done: got %v %v", ids(done), err)
+	}
+	bobs, err := s.ListTasks(bob.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || !equal(ids(bobs), b1.ID) {
+		t.Fatalf("ListTasks other user: got %v %v", ids(bobs), err)
+	}
+	none, err := s.ListTasks(bob.ID+100, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(none) != 0 {
+		t.Fatalf("ListTasks unknown user: got %v %v", ids(none), err)
+	}
+}
+
+func testListTasksPriority(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	low := mustTask(t, s, domain.Task{UserID: u.ID, Text: "low"})
+	if low.Priority != domain.PriorityDefault {
+		t.Fatalf("expected default priority, got %d", low.Priority)
+	}
+	urgent := mustTask(t, s, domain.Task{UserID: u.ID, Text: "urgent", Priority: domain.PriorityP1})
+	mid := mustTask(t, s, domain.Task{UserID: u.ID, Text: "mid", Priority: domain.PriorityP2})
+	other := mustTask(t, s, domain.Task{UserID: u.ID, Text: "other", Priority: domain.PriorityP2})
+
+	all, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(all) != 4 || all[0].ID != urgent.ID || all[1].ID != mid.ID || all[2].ID != other.ID || all[3].ID != low.ID {
+		t.Fatalf("expected priority then id order, got %+v %v", all, err)
+	}
+	p2, err := s.ListTasks(u.ID, storage.TaskFilter{Priority: domain.PriorityP2}, storage.Page{})
+	if err != nil || len(p2) != 2 || p2[0].ID != mid.ID || p2[1].ID != other.ID {
+		t.Fatalf("expected only P2 tasks, got %+v %v", p2, err)
+	}
+
+	low.Priority = domain.PriorityP1
+	updated, err := s.UpdateTask(low)
+	if err != nil || updated.Priority != domain.PriorityP1 {
+		t.Fatalf("UpdateTask priority: got %+v %v", updated, err)
+	}
+	p1, err := s.ListTasks(u.ID, storage.TaskFilter{Status: domain.TaskStatusActive, Priority: domain.PriorityP1}, storage.Page{})
+	if err != nil || len(p1) != 2 || p1[0].ID != low.ID || p1[1].ID != urgent.ID {
+		t.Fatalf("expected both P1 tasks, got %+v %v", p1, err)
+	}
+}
+
+func testListTasksPages(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	for i, due := range []*time.Time{at(time.Hour), nil, at(-time.Hour), at(time.Hour), nil, at(2 * time.Hour), at(-time.Hour)} {
+		mustTask(t, s, domain.Task{UserID: u.ID, Text: fmt.Sprintf("task %d", i), DueAt: due, Priority: i%3 + 1})
+	}
+	mustTask(t, s, domain.Task{UserID: other.ID, Text: "foreign", DueAt: at(time.Hour)})
+
+	for _, spec := range []string{"", "due_at,-priority,created_at", "-due_at", "-priority,-id", "remind_at"} {
+		keys, err := storage.ParseSort(spec, storage.TaskSortFields, storage.DefaultTaskSort)
+		if err != nil {
+			t.Fatalf("ParseSort(%q): %v", spec, err)
+		}
+		all, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{Sort: keys})
+		if err != nil || len(all) != 7 {
+			t.Fatalf("%q: ListTasks: got %d tasks, %v", spec, len(all), err)
+		}
+		for i := 1; i < len(all); i++ {
+			prev, cur := storage.TaskSortValues(all[i-1], keys), storage.TaskSortValues(all[i], keys)
+			if storage.CompareSortValues(keys, prev, cur) >= 0 {
+				t.Fatalf("%q: tasks %d and %d out of order", spec, all[i-1].ID, all[i].ID)
+			}
+		}
+		// Walk the listing two at a time through opaque cursors.
+		var walked []domain.Task
+		page := storage.Page{Sort: keys, Limit: 2}
+		for {
+			items, err := s.ListTasks(u.ID, storage.TaskFilter{}, page)
+			if err != nil {
+				t.Fatalf("%q: ListTasks page: %v", spec, err)
+			}
+			walked = append(walked, items...)
+			if len(items) < page.Limit {
+				break
+			}
+			cursor := storage.EncodeCursor(keys, storage.TaskSortValues(items[len(items)-1], keys))
+			if page.After, err = storage.DecodeCursor(cursor, keys, storage.TaskSortFields); err != nil {
+				t.Fatalf("%q: DecodeCursor: %v", spec, err)
+			}
+		}
+		if len(walked) != len(all) {
+			t.Fatalf("%q: walked %d tasks, want %d", spec, len(walked), len(all))
+		}
+		for i := range all {
+			if walked[i].ID != all[i].ID {
+				t.Fatalf("%q: page walk diverges at %d: got task %d, want %d", spec, i, walked[i].ID, all[i].ID)
+			}
+		}
+	}
+
+	keys, _ := storage.ParseSort("-due_at", storage.TaskSortFields, nil)
+	all, _ := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{Sort: keys})
+	if all[0].DueAt == nil || all[len(all)-1].DueAt != nil || all[len(all)-2].DueAt != nil {
+		t.Fatalf("expected tasks without a due date last, got %+v", all)
+	}
+	got, err := s.ListTasks(u.ID, storage.TaskFilter{Priority: 1}, storage.Page{Limit: 1})
+	if err != nil || len(got) != 1 || got[0].Priority != 1 {
+		t.Fatalf("expected filters to apply within a page, got %+v %v", got, err)
+	}
+}
+
+func testListUsersPages(t *testing.T, s app.Store) {
+	for i := int64(1); i <= 3; i++ {
+		mustUser(t, s, i)
+	}
+	keys, _ := storage.ParseSort("-created_at", storage.UserSortFields, storage.DefaultUserSort)
+	page := storage.Page{Sort: keys, Limit: 2}
+	first, err := s.ListUsers(page)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("ListUsers: got %+v %v", first, err)
+	}
+	page.After = storage.UserSortValues(first[1], keys)
+	rest, err := s.ListUsers(page)
+	if err != nil || len(rest) != 1 {
+		t.Fatalf("ListUsers second page: got %+v %v", rest, err)
+	}
+	seen := map[int64]bool{first[0].ID: true, first[1].ID: true, rest[0].ID: true}
+	if len(seen) != 3 {
+		t.Fatalf("expected every user once, got %+v %+v", first, rest)
+	}
+}
+
+func testTags(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "milk", Tags: []string{"Errands", "#home", "errands"}})
+	if len(task.Tags) != 2 || task.Tags[0] != "errands" || task.Tags[1] != "home" {
+		t.Fatalf("expected normalized sorted tags, got %v", task.Tags)
+	}
+	got, err := s.GetTask(task.ID)
+	if err != nil || len(got.Tags) != 2 || got.Tags[0] != "errands" || got.Tags[1] != "home" {
+		t.Fatalf("GetTask tags: got %+v %v", got, err)
+	}
+	if _, err := s.CreateTask(domain.Task{UserID: u.ID, Text: "bad", Tags: []string{"no spaces"}}); !errors.Is(err, domain.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+
+	tags, err := s.ListTags(u.ID)
+	if err != nil || len(tags) != 2 || tags[0].Name != "errands" || tags[1].Name != "home" || tags[0].UserID != u.ID {
+		t.Fatalf("ListTags: got %+v %v", tags, err)
+	}
+	if _, err := s.CreateTag(domain.Tag{UserID: u.ID, Name: "home"}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for duplicate tag, got %v", err)
+	}
+	if _, err := s.CreateTag(domain.Tag{UserID: other.ID, Name: "home"}); err != nil {
+		t.Fatalf("same name for another user: %v", err)
+	}
+	if _, err := s.CreateTag(domain.Tag{UserID: other.ID + 100, Name: "x"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown user, got %v", err)
+	}
+	work, err := s.CreateTag(domain.Tag{UserID: u.ID, Name: "work"})
+	if err != nil || work.ID == 0 || work.CreatedAt.IsZero() {
+		t.Fatalf("CreateTag: got %+v %v", work, err)
+	}
+	if fetched, err := s.GetTag(work.ID); err != nil || fetched.Name != "work" {
+		t.Fatalf("GetTag: got %+v %v", fetched, err)
+	}
+
+	home := tags[1]
+	if _, err := s.RenameTag(home.ID, "work"); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict when renaming onto an existing tag, got %v", err)
+	}
+	renamed, err := s.RenameTag(home.ID, "house")
+	if err != nil || renamed.Name != "house" || renamed.ID != home.ID {
+		t.Fatalf("RenameTag: got %+v %v", renamed, err)
+	}
+	if got, _ := s.GetTask(task.ID); len(got.Tags) != 2 || got.Tags[0] != "errands" || got.Tags[1] != "house" {
+		t.Fatalf("expected the task to follow the rename, got %v", got.Tags)
+	}
+
+	task.Tags = []string{"work"}
+	updated, err := s.UpdateTask(task)
+	if err != nil || len(updated.Tags) != 1 || updated.Tags[0] != "work" {
+		t.Fatalf("UpdateTask tags: got %+v %v", updated, err)
+	}
+	if err := s.DeleteTag(work.ID); err != nil {
+		t.Fatalf("DeleteTag: %v", err)
+	}
+	if got, _ := s.GetTask(task.ID); len(got.Tags) != 0 {
+		t.Fatalf("expected deleted tag to be dropped from the task, got %v", got.Tags)
+	}
+	missing := work.ID + 1000
+	if _, err := s.GetTag(missing); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetTag: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.RenameTag(missing, "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("RenameTag: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteTag(work.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteTag twice: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testListTasksByTag(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	both := mustTask(t, s, domain.Task{UserID: u.ID, Text: "both", Tags: []string{"home", "work"}})
+	home := mustTask(t, s, domain.Task{UserID: u.ID, Text: "home", Tags: []string{"home"}})
+	mustTask(t, s, domain.Task{UserID: u.ID, Text: "none"})
+	mustTask(t, s, domain.Task{UserID: other.ID, Text: "foreign", Tags: []string{"home", "work"}})
+
+	anyOf, err := s.ListTasks(u.ID, storage.TaskFilter{Tags: []string{"home", "work"}}, storage.Page{})
+	if err != nil || len(anyOf) != 2 || anyOf[0].ID != both.ID || anyOf[1].ID != home.ID {
+		t.Fatalf("any tags: got %+v %v", anyOf, err)
+	}
+	allOf, err := s.ListTasks(u.ID, storage.TaskFilter{Tags: []string{"work", "home", "Home"}, AllTags: true}, storage.Page{})
+	if err != nil || len(allOf) != 1 || allOf[0].ID != both.ID || len(allOf[0].Tags) != 2 {
+		t.Fatalf("all tags: got %+v %v", allOf, err)
+	}
+	unknown, err := s.ListTasks(u.ID, storage.TaskFilter{Tags: []string{"nope"}}, storage.Page{})
+	if err != nil || len(unknown) != 0 {
+		t.Fatalf("unknown tag: got %+v %v", unknown, err)
+	}
+}
+
+func testListTasksRichFilters(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	past := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Позвонить Маме", DueAt: at(-time.Hour), RemindAt: at(-2 * time.Hour)})
+	later := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Купить молоко", DueAt: at(time.Hour)})
+	open := mustTask(t, s, domain.Task{UserID: u.ID, Text: "read 100% of the book", RemindAt: at(time.Hour)})
+	done := mustTask(t, s, domain.Task{UserID: u.ID, Text: "мама звонила", DueAt: at(-time.Hour)})
+	if _, err := s.MarkDone(done.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotified(past.ID, base); err != nil {
+		t.Fatalf("MarkNotified: %v", err)
+	}
+
+	ids := func(tasks []domain.Task) []int64 {
+		out := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			out = append(out, task.ID)
+		}
+		slices.Sort(out)
+		return out
+	}
+	now := time.Now()
+	yes, no := true, false
+	tests := []struct {
+		name string
+		f    storage.TaskFilter
+		want []int64
+	}{
+		{"due after", storage.TaskFilter{DueAfter: at(0)}, []int64{later.ID}},
+		{"due after inclusive", storage.TaskFilter{DueAfter: at(time.Hour)}, []int64{later.ID}},
+		{"due before exclusive", storage.TaskFilter{DueBefore: at(-time.Hour)}, nil},
+		{"due window", storage.TaskFilter{DueAfter: at(-time.Hour), DueBefore: at(0)}, []int64{past.ID, done.ID}},
+		{"overdue", storage.TaskFilter{OverdueAt: at(0)}, []int64{past.ID}},
+		{"no due", storage.TaskFilter{NoDue: &yes}, []int64{open.ID}},
+		{"has due", storage.TaskFilter{NoDue: &no, Status: domain.TaskStatusActive}, []int64{past.ID, later.ID}},
+		{"reminder delivered", storage.TaskFilter{Reminder: domain.ReminderStatusDelivered}, []int64{past.ID}},
+		{"reminder pending", storage.TaskFilter{Reminder: domain.ReminderStatusPending}, []int64{open.ID}},
+		{"no reminder", storage.TaskFilter{Reminder: storage.ReminderNone}, []int64{later.ID, done.ID}},
+		{"created window", storage.TaskFilter{CreatedAfter: ptr(now.Add(-time.Hour)), CreatedBefore: ptr(now.Add(time.Hour))}, []int64{past.ID, later.ID, open.ID, done.ID}},
+		{"created later", storage.TaskFilter{CreatedAfter: ptr(now.Add(time.Hour))}, nil},
+		{"updated earlier", storage.TaskFilter{UpdatedBefore: ptr(now.Add(-time.Hour))}, nil},
+		{"text ignores case", storage.TaskFilter{TextContains: "МАМ"}, []int64{past.ID, done.ID}},
+		{"text is literal", storage.TaskFilter{TextContains: "100%"}, []int64{open.ID}},
+		{"text no wildcards", storage.TaskFilter{TextContains: "_"}, nil},
+		{"combined", storage.TaskFilter{TextContains: "мам", OverdueAt: at(0)}, []int64{past.ID}},
+	}
+	for _, tt := range tests {
+		got, err := s.ListTasks(u.ID, tt.f, storage.Page{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := tt.want
+		if want == nil {
+			want = []int64{}
+		}
+		slices.Sort(want)
+		if !slices.Equal(ids(got), want) {
+			t.Errorf("%s: got %v, want %v", tt.name, ids(got), want)
+		}
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func testProjects(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	work, err := s.CreateProject(domain.Project{UserID: u.ID, Name: "Work", Color: "#ff0000"})
+	if err != nil || work.ID == 0 || work.CreatedAt.IsZero() || work.Color != "#ff0000" || work.Archived {
+		t.Fatalf("CreateProject: got %+v %v", work, err)
+	}
+	if _, err := s.CreateProject(domain.Project{UserID: u.ID, Name: "Work"}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for duplicate project, got %v", err)
+	}
+	if _, err := s.CreateProject(domain.Project{UserID: u.ID, Name: "WORK"}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a name differing in case, got %v", err)
+	}
+	cyrillic := mustUser(t, s, 3)
+	if _, err := s.CreateProject(domain.Project{UserID: cyrillic.ID, Name: "Дача"}); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if _, err := s.CreateProject(domain.Project{UserID: cyrillic.ID, Name: "дача"}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a Cyrillic name differing in case, got %v", err)
+	}
+	foreign, err := s.CreateProject(domain.Project{UserID: other.ID, Name: "Work"})
+	if err != nil {
+		t.Fatalf("same name for another user: %v", err)
+	}
+	if _, err := s.CreateProject(domain.Project{UserID: other.ID + 100, Name: "x"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown user, got %v", err)
+	}
+	home, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "Home"})
+
+	home.Name = "Work"
+	if _, err := s.UpdateProject(home); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict when renaming onto an existing project, got %v", err)
+	}
+	home.Name = "House"
+	home.Archived = true
+	updated, err := s.UpdateProject(home)
+	if err != nil || updated.Name != "House" || !updated.Archived || updated.UserID != u.ID {
+		t.Fatalf("UpdateProject: got %+v %v", updated, err)
+	}
+	active, err := s.ListProjects(u.ID, false)
+	if err != nil || len(active) != 1 || active[0].ID != work.ID {
+		t.Fatalf("ListProjects(active): got %+v %v", active, err)
+	}
+	all, err := s.ListProjects(u.ID, true)
+	if err != nil || len(all) != 2 || all[0].ID != work.ID || all[1].ID != home.ID {
+		t.Fatalf("ListProjects(all): got %+v %v", all, err)
+	}
+
+	if _, err := s.CreateTask(domain.Task{UserID: u.ID, Text: "x", ProjectID: &foreign.ID}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another user's project, got %v", err)
+	}
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "report", ProjectID: &work.ID})
+	if got, err := s.GetTask(task.ID); err != nil || got.ProjectID == nil || *got.ProjectID != work.ID {
+		t.Fatalf("GetTask project: got %+v %v", got, err)
+	}
+	task.ProjectID = &foreign.ID
+	if _, err := s.UpdateTask(task); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound when moving to another user's project, got %v", err)
+	}
+	task.ProjectID = nil
+	if moved, err := s.UpdateTask(task); err != nil || moved.ProjectID != nil {
+		t.Fatalf("expected the task in the inbox, got %+v %v", moved, err)
+	}
+
+	if _, err := s.SetCurrentProject(u.ID, &foreign.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another user's project, got %v", err)
+	}
+	user, err := s.SetCurrentProject(u.ID, &work.ID)
+	if err != nil || user.CurrentProjectID == nil || *user.CurrentProjectID != work.ID {
+		t.Fatalf("SetCurrentProject: got %+v %v", user, err)
+	}
+	if got, err := s.GetUser(u.ID); err != nil || got.CurrentProjectID == nil || *got.CurrentProjectID != work.ID {
+		t.Fatalf("GetUser current project: got %+v %v", got, err)
+	}
+	if user, err := s.SetCurrentProject(u.ID, nil); err != nil || user.CurrentProjectID != nil {
+		t.Fatalf("SetCurrentProject(nil): got %+v %v", user, err)
+	}
+
+	missing := home.ID + 1000
+	if _, err := s.GetProject(missing); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetProject: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.UpdateProject(domain.Project{ID: missing, Name: "x"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("UpdateProject: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteProject(missing, false); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteProject: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteProject(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	keep, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "keep"})
+	drop, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "drop"})
+	kept := mustTask(t, s, domain.Task{UserID: u.ID, Text: "kept", ProjectID: &keep.ID})
+	dropped := mustTask(t, s, domain.Task{UserID: u.ID, Text: "dropped", ProjectID: &drop.ID})
+	if _, err := s.SetCurrentProject(u.ID, &keep.ID); err != nil {
+		t.Fatalf("SetCurrentProject: %v", err)
+	}
+
+	if err := s.DeleteProject(keep.ID, false); err != nil {
+		t.Fatalf("DeleteProject(move): %v", err)
+	}
+	if got, err := s.GetTask(kept.ID); err != nil || got.ProjectID != nil {
+		t.Fatalf("expected the task moved to the inbox, got %+v %v", got, err)
+	}
+	if got, err := s.GetUser(u.ID); err != nil || got.CurrentProjectID != nil {
+		t.Fatalf("expected the current project cleared, got %+v %v", got, err)
+	}
+	if _, err := s.GetProject(keep.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the project gone, got %v", err)
+	}
+
+	if err := s.DeleteProject(drop.ID, true); err != nil {
+		t.Fatalf("DeleteProject(delete): %v", err)
+	}
+	if _, err := s.GetTask(dropped.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the project's task deleted, got %v", err)
+	}
+	if _, err := s.GetTask(kept.ID); err != nil {
+		t.Fatalf("expected the inbox task to survive, got %v", err)
+	}
+}
+
+func testArchivedProjectTasks(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	work, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "work"})
+	old, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "old"})
+	inbox := mustTask(t, s, domain.Task{UserID: u.ID, Text: "inbox", RemindAt: at(-time.Minute)})
+	inWork := mustTask(t, s, domain.Task{UserID: u.ID, Text: "work", ProjectID: &work.ID, RemindAt: at(-time.Minute)})
+	mustTask(t, s, domain.Task{UserID: u.ID, Text: "old", ProjectID: &old.ID, RemindAt: at(-time.Minute)})
+	old.Archived = true
+	if _, err := s.UpdateProject(old); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	byProject, err := s.ListTasks(u.ID, storage.TaskFilter{ProjectID: work.ID}, storage.Page{})
+	if err != nil || len(byProject) != 1 || byProject[0].ID != inWork.ID {
+		t.Fatalf("ListTasks(project): got %+v %v", byProject, err)
+	}
+	if all, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{}); err != nil || len(all) != 3 {
+		t.Fatalf("ListTasks: expected archived tasks without HideArchived, got %d %v", len(all), err)
+	}
+	active, err := s.ListActive(u.ID)
+	if err != nil || len(active) != 2 || active[0].ID != inbox.ID || active[1].ID != inWork.ID {
+		t.Fatalf("ListActive: expected archived project hidden, got %+v %v", active, err)
+	}
+	claimed, err := s.ListDueForNotify(base, time.Minute)
+	if err != nil || len(claimed) != 2 || claimed[0].ID != inbox.ID || claimed[1].ID != inWork.ID {
+		t.Fatalf("ListDueForNotify: expected archived project skipped, got %+v %v", claimed, err)
+	}
+}
+
+func testSubtasks(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	parent := mustTask(t, s, domain.Task{UserID: u.ID, Text: "release"})
+	foreign := mustTask(t, s, domain.Task{UserID: other.ID, Text: "foreign"})
+	if _, err := s.CreateTask(domain.Task{UserID: u.ID, Text: "x", ParentID: &foreign.ID}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another user's parent, got %v", err)
+	}
+	first := mustTask(t, s, domain.Task{UserID: u.ID, Text: "changelog", ParentID: &parent.ID, Priority: domain.PriorityP1})
+	second := mustTask(t, s, domain.Task{UserID: u.ID, Text: "tag", ParentID: &parent.ID})
+	if got, err := s.GetTask(first.ID); err != nil || got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Fatalf("GetTask parent: got %+v %v", got, err)
+	}
+	if _, err := s.MarkDone(first.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+
+	subtasks, err := s.ListSubtasks(parent.ID)
+	if err != nil || len(subtasks) != 2 || subtasks[0].ID != first.ID || subtasks[1].ID != second.ID {
+		t.Fatalf("ListSubtasks: expected id order, got %+v %v", subtasks, err)
+	}
+	if none, err := s.ListSubtasks(second.ID); err != nil || len(none) != 0 {
+		t.Fatalf("ListSubtasks(leaf): got %+v %v", none, err)
+	}
+	listed, err := s.ListTasks(u.ID, storage.TaskFilter{Status: domain.TaskStatusActive}, storage.Page{})
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("ListTasks: got %+v %v", listed, err)
+	}
+	for _, task := range listed {
+		switch task.ID {
+		case parent.ID:
+			if task.Progress == nil || task.Progress.Done != 1 || task.Progress.Total != 2 {
+				t.Fatalf("expected progress 1/2, got %+v", task.Progress)
+			}
+		case second.ID:
+			if task.Progress != nil {
+				t.Fatalf("expected no progress on a subtask, got %+v", task.Progress)
+			}
+		}
+	}
+
+	if err := s.CompleteSubtasks(parent.ID); err != nil {
+		t.Fatalf("CompleteSubtasks: %v", err)
+	}
+	if got, _ := s.GetTask(second.ID); got.Status != domain.TaskStatusDone {
+		t.Fatalf("expected subtask done, got %+v", got)
+	}
+	if got, _ := s.GetTask(parent.ID); got.Status != domain.TaskStatusActive {
+		t.Fatalf("expected the parent untouched, got %+v", got)
+	}
+
+	cascade := mustTask(t, s, domain.Task{UserID: u.ID, Text: "cascade"})
+	open := mustTask(t, s, domain.Task{UserID: u.ID, Text: "open", ParentID: &cascade.ID})
+	done, _, err := s.CompleteTask(cascade.ID, storage.Completion{Subtasks: true})
+	if err != nil || done.Status != domain.TaskStatusDone {
+		t.Fatalf("CompleteTask with subtasks: got %+v %v", done, err)
+	}
+	if got, _ := s.GetTask(open.ID); got.Status != domain.TaskStatusDone {
+		t.Fatalf("expected the subtask closed with its parent, got %+v", got)
+	}
+	reopened := mustTask(t, s, domain.Task{UserID: u.ID, Text: "late", ParentID: &cascade.ID})
+	if _, _, err := s.CompleteTask(cascade.ID, storage.Completion{Subtasks: true}); err != nil {
+		t.Fatalf("CompleteTask twice: %v", err)
+	}
+	if got, _ := s.GetTask(reopened.ID); got.Status != domain.TaskStatusActive {
+		t.Fatalf("expected no cascade when the parent was already done, got %+v", got)
+	}
+}
+
+func testDeleteSubtasks(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	parent := mustTask(t, s, domain.Task{UserID: u.ID, Text: "parent"})
+	child := mustTask(t, s, domain.Task{UserID: u.ID, Text: "child", ParentID: &parent.ID})
+	kept := mustTask(t, s, domain.Task{UserID: u.ID, Text: "kept"})
+	if err := s.DeleteTask(parent.ID, 0); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := s.GetTask(child.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the subtask deleted with its parent, got %v", err)
+	}
+	if _, err := s.GetTask(kept.ID); err != nil {
+		t.Fatalf("expected other tasks to survive, got %v", err)
+	}
+
+	project, _ := s.CreateProject(domain.Project{UserID: u.ID, Name: "p"})
+	inProject := mustTask(t, s, domain.Task{UserID: u.ID, Text: "in project", ProjectID: &project.ID})
+	inbox := mustTask(t, s, domain.Task{UserID: u.ID, Text: "subtask in inbox", ParentID: &inProject.ID})
+	if err := s.DeleteProject(project.ID, true); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if _, err := s.GetTask(inbox.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected subtasks of deleted project tasks to go too, got %v", err)
+	}
+}
+
+func testDependencies(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	migrations := mustTask(t, s, domain.Task{UserID: u.ID, Text: "run migrations"})
+	build := mustTask(t, s, domain.Task{UserID: u.ID, Text: "build"})
+	deploy := mustTask(t, s, domain.Task{UserID: u.ID, Text: "deploy"})
+	foreign := mustTask(t, s, domain.Task{UserID: other.ID, Text: "foreign"})
+
+	if err := s.AddDependency(deploy.ID, migrations.ID); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if err := s.AddDependency(deploy.ID, migrations.ID); err != nil {
+		t.Fatalf("AddDependency twice: %v", err)
+	}
+	if err := s.AddDependency(migrations.ID, build.ID); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if err := s.AddDependency(build.ID, deploy.ID); !errors.Is(err, storage.ErrCycle) {
+		t.Fatalf("expected ErrCycle for a transitive cycle, got %v", err)
+	}
+	if err := s.AddDependency(build.ID, build.ID); !errors.Is(err, storage.ErrCycle) {
+		t.Fatalf("expected ErrCycle for a self-dependency, got %v", err)
+	}
+	if err := s.AddDependency(deploy.ID, foreign.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another user's task, got %v", err)
+	}
+	if err := s.AddDependency(deploy.ID, foreign.ID+1000); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing task, got %v", err)
+	}
+
+	edges, err := s.ListDependencies(u.ID)
+	if err != nil || len(edges) != 2 || edges[0] != (domain.Dependency{TaskID: migrations.ID, BlockedByID: build.ID}) ||
+		edges[1] != (domain.Dependency{TaskID: deploy.ID, BlockedByID: migrations.ID}) {
+		t.Fatalf("ListDependencies: got %+v %v", edges, err)
+	}
+	got, err := s.GetTask(deploy.ID)
+	if err != nil || !got.Blocked || len(got.BlockedBy) != 1 || got.BlockedBy[0] != migrations.ID {
+		t.Fatalf("GetTask: expected blocked by migrations, got %+v %v", got, err)
+	}
+	active, err := s.ListActive(u.ID)
+	if err != nil || len(active) != 3 {
+		t.Fatalf("ListActive: got %+v %v", active, err)
+	}
+	for _, task := range active {
+		if want := task.ID != build.ID; task.Blocked != want {
+			t.Fatalf("ListActive: task %d blocked=%v, want %v", task.ID, task.Blocked, want)
+		}
+	}
+
+	deploy.Text = "deploy to prod"
+	updated, err := s.UpdateTask(deploy)
+	if err != nil || !updated.Blocked || !slices.Equal(updated.BlockedBy, []int64{migrations.ID}) {
+		t.Fatalf("UpdateTask: expected the returned row blocked by migrations, got %+v %v", updated, err)
+	}
+	// Completing migrations doesn't lift its own blocker.
+	done, err := s.MarkDone(migrations.ID)
+	if err != nil || !done.Blocked || !slices.Equal(done.BlockedBy, []int64{build.ID}) {
+		t.Fatalf("MarkDone: expected the returned row blocked by build, got %+v %v", done, err)
+	}
+	if got, _ := s.GetTask(deploy.ID); got.Blocked || len(got.BlockedBy) != 1 {
+		t.Fatalf("expected deploy unblocked once its blocker is done, got %+v", got)
+	}
+	if err := s.RemoveDependency(deploy.ID, migrations.ID); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+	if err := s.RemoveDependency(deploy.ID, migrations.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("RemoveDependency twice: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteTask(build.ID, 0); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if edges, err := s.ListDependencies(u.ID); err != nil || len(edges) != 0 {
+		t.Fatalf("expected edges of a deleted task to go, got %+v %v", edges, err)
+	}
+}
+
+func testBlockedReminders(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	blocker := mustTask(t, s, domain.Task{UserID: u.ID, Text: "blocker"})
+	blocked := mustTask(t, s, domain.Task{UserID: u.ID, Text: "blocked", RemindAt: at(-time.Minute)})
+	if err := s.AddDependency(blocked.ID, blocker.ID); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if claimed, err := s.ListDueForNotify(base, time.Minute); err != nil || len(claimed) != 0 {
+		t.Fatalf("expected no reminder for a blocked task, got %+v %v", claimed, err)
+	}
+	if _, err := s.MarkDone(blocker.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	claimed, err := s.ListDueForNotify(base, time.Minute)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != blocked.ID {
+		t.Fatalf("expected the reminder once unblocked, got %+v %v", claimed, err)
+	}
+}
+
+func testAttachments(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "expenses"})
+	other := mustTask(t, s, domain.Task{UserID: u.ID, Text: "other"})
+
+	photo, err := s.CreateAttachment(domain.Attachment{
+		TaskID:         task.ID,
+		Type:           domain.AttachmentTypePhoto,
+		TelegramFileID: "photo-file",
+		FileUniqueID:   "photo-unique",
+		Caption:        "receipt",
+	})
+	if err != nil || photo.ID == 0 || photo.Caption != "receipt" {
+		t.Fatalf("CreateAttachment: got %+v %v", photo, err)
+	}
+	voice, err := s.CreateAttachment(domain.Attachment{
+		TaskID:         task.ID,
+		Type:           domain.AttachmentTypeVoice,
+		TelegramFileID: "voice-file",
+		FileUniqueID:   "voice-unique",
+	})
+	if err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+	if _, err := s.CreateAttachment(domain.Attachment{TaskID: other.ID + 1000, Type: domain.AttachmentTypePhoto, TelegramFileID: "x", FileUniqueID: "x"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing task, got %v", err)
+	}
+
+	items, err := s.ListAttachments(task.ID)
+	if err != nil || len(items) != 2 || items[0] != photo || items[1] != voice {
+		t.Fatalf("ListAttachments: got %+v %v", items, err)
+	}
+	if items[1].Caption != "" {
+		t.Fatalf("expected no caption, got %q", items[1].Caption)
+	}
+	if items, err := s.ListAttachments(other.ID); err != nil || items == nil || len(items) != 0 {
+		t.Fatalf("expected an empty list for a task without attachments, got %+v %v", items, err)
+	}
+
+	if err := s.DeleteTask(task.ID, 0); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if items, err := s.ListAttachments(task.ID); err != nil || len(items) != 0 {
+		t.Fatalf("expected attachments to go with their task, got %+v %v", items, err)
+	}
+}
+
+func testSearchTasks(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	groceries := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Купить молоко и хлеб"})
+	cat := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Молоко для кота"})
+	call := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Позвонить маме"})
+	shoes := mustTask(t, s, domain.Task{UserID: u.ID, Text: "Buy running shoes"})
+	done := mustTask(t, s, domain.Task{UserID: u.ID, Text: "молоко"})
+	mustTask(t, s, domain.Task{UserID: other.ID, Text: "молоко"})
+	if _, err := s.MarkDone(done.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+
+	ids := func(items []domain.SearchResult) map[int64]bool {
+		out := make(map[int64]bool)
+		for _, r := range items {
+			if r.Rank <= 0 {
+				t.Fatalf("expected a positive rank, got %+v", r)
+			}
+			out[r.ID] = true
+		}
+		return out
+	}
+	got, err := s.SearchTasks(u.ID, "молоко", storage.TaskFilter{})
+	if found := ids(got); err != nil || len(got) != 3 || !found[groceries.ID] || !found[cat.ID] || !found[done.ID] {
+		t.Fatalf("SearchTasks: got %+v %v", got, err)
+	}
+	got, err = s.SearchTasks(u.ID, "МОЛОК", storage.TaskFilter{Status: domain.TaskStatusActive})
+	if found := ids(got); err != nil || len(got) != 2 || !found[groceries.ID] || !found[cat.ID] {
+		t.Fatalf("SearchTasks: expected prefix matches among active tasks, got %+v %v", got, err)
+	}
+	got, err = s.SearchTasks(u.ID, "хлеб, молоко", storage.TaskFilter{})
+	if err != nil || len(got) != 1 || got[0].ID != groceries.ID {
+		t.Fatalf("SearchTasks: expected every word to match, got %+v %v", got, err)
+	}
+	if want := "Купить <b>молоко</b> и <b>хлеб</b>"; got[0].Highlight != want {
+		t.Fatalf("Highlight: got %q, want %q", got[0].Highlight, want)
+	}
+	got, err = s.SearchTasks(u.ID, "shoes", storage.TaskFilter{})
+	if err != nil || len(got) != 1 || got[0].Highlight != "Buy running <b>shoes</b>" {
+		t.Fatalf("SearchTasks: got %+v %v", got, err)
+	}
+	if got, err := s.SearchTasks(u.ID, "?!", storage.TaskFilter{}); err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty result for a query without words, got %+v %v", got, err)
+	}
+
+	call.Text = "Позвонить папе"
+	if _, err := s.UpdateTask(call); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got, _ := s.SearchTasks(u.ID, "маме", storage.TaskFilter{}); len(got) != 0 {
+		t.Fatalf("expected the old text to be gone from the index, got %+v", got)
+	}
+	if got, _ := s.SearchTasks(u.ID, "папе", storage.TaskFilter{}); len(got) != 1 || got[0].ID != call.ID {
+		t.Fatalf("expected the new text in the index, got %+v", got)
+	}
+	if err := s.DeleteTask(shoes.ID, 0); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if got, _ := s.SearchTasks(u.ID, "shoes", storage.TaskFilter{}); len(got) != 0 {
+		t.Fatalf("expected a deleted task to be gone from the index, got %+v", got)
+	}
+}
+
+func testUpdateTaskReturnsStoredRow(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "draft", RemindAt: at(-time.Minute)})
+	claimed, err := s.ListDueForNotify(base, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claim: got %+v %v", claimed, err)
+	}
+
+	// The input is stale and carries fields UpdateTask must not touch.
+	input := task
+	input.Text = "final"
+	input.DueAt = at(time.Hour)
+	input.Recurrence = "FREQ=WEEKLY"
+	input.UserID = other.ID
+	input.CreatedAt = time.Time{}
+	input.ReminderStatus = domain.ReminderStatusFailed
+	input.ReminderAttempts = 99
+	// Skip the version check, which the claim above would fail; see testTaskVersions.
+	input.Version = 0
+
+	updated, err := s.UpdateTask(input)
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if updated.Text != "final" || !sameTime(updated.DueAt, at(time.Hour)) || updated.Recurrence != "FREQ=WEEKLY" {
+		t.Fatalf("expected editable fields to be written, got %+v", updated)
+	}
+	if updated.UserID != u.ID || !updated.CreatedAt.Equal(task.CreatedAt) {
+		t.Fatalf("expected owner and created_at to be kept, got %+v", updated)
+	}
+	if updated.ReminderStatus != domain.ReminderStatusSending || updated.ReminderAttempts != 1 {
+		t.Fatalf("expected reminder delivery state to be kept, got %+v", updated)
+	}
+	if updated.UpdatedAt.Before(task.UpdatedAt) {
+		t.Fatalf("expected updated_at to move forward, got %v after %v", updated.UpdatedAt, task.UpdatedAt)
+	}
+	stored, err := s.GetTask(task.ID)
+	if err != nil || stored.Text != updated.Text || stored.UserID != updated.UserID || stored.ReminderAttempts != updated.ReminderAttempts {
+		t.Fatalf("expected UpdateTask to return the stored row, got %+v vs %+v (%v)", updated, stored, err)
+	}
+}
+
+func testTaskVersions(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "draft", RemindAt: at(-time.Minute)})
+	if task.Version != 1 {
+		t.Fatalf("expected a new task at version 1, got %d", task.Version)
+	}
+
+	// Two writers read the same version; only the first write may land.
+	first, second := task, task
+	first.Text = "first"
+	second.Text = "second"
+	updated, err := s.UpdateTask(first)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("UpdateTask: got %+v %v", updated, err)
+	}
+	if _, err := s.UpdateTask(second); !errors.Is(err, storage.ErrStale) {
+		t.Fatalf("expected ErrStale for the second writer, got %v", err)
+	}
+	if stored, _ := s.GetTask(task.ID); stored.Text != "first" || stored.Version != 2 {
+		t.Fatalf("expected the first write to stay, got %+v", stored)
+	}
+
+	// Every other edit moves the version too; reminder bookkeeping doesn't.
+	due, err := s.SetDue(task.ID, at(time.Hour))
+	if err != nil || due.Version != 3 {
+		t.Fatalf("SetDue: got %+v %v", due, err)
+	}
+	if claimed, err := s.ListDueForNotify(base, time.Minute); err != nil || len(claimed) != 1 || claimed[0].Version != 3 {
+		t.Fatalf("claim: got %+v %v", claimed, err)
+	}
+	if err := s.MarkNotifyFailed(task.ID, "timeout", at(-time.Second)); err != nil {
+		t.Fatalf("MarkNotifyFailed: %v", err)
+	}
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim again: %v", err)
+	}
+	if err := s.MarkNotified(task.ID, base); err != nil {
+		t.Fatalf("MarkNotified: %v", err)
+	}
+	stored, err := s.GetTask(task.ID)
+	if err != nil || stored.Version != 3 || stored.NotifiedAt == nil {
+		t.Fatalf("expected version 3 after delivery, got %+v %v", stored, err)
+	}
+
+	if err := s.DeleteTask(task.ID, 2); !errors.Is(err, storage.ErrStale) {
+		t.Fatalf("expected ErrStale deleting an old version, got %v", err)
+	}
+	if _, err := s.GetTask(task.ID); err != nil {
+		t.Fatalf("expected the task to survive a stale delete: %v", err)
+	}
+	if err := s.DeleteTask(task.ID, stored.Version); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := s.UpdateTask(stored); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a deleted task, got %v", err)
+	}
+	if err := s.DeleteTask(task.ID, stored.Version); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func testSetRemindResetsDelivery(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "call", RemindAt: at(0)})
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotifyFailed(task.ID, "blocked", nil); err != nil {
+		t.Fatalf("MarkNotifyFailed: %v", err)
+	}
+
+	got, err := s.SetRemind(task.ID, at(time.Hour))
+	if err != nil {
+		t.Fatalf("SetRemind: %v", err)
+	}
+	if !sameTime(got.RemindAt, at(time.Hour)) || got.NotifiedAt != nil || got.ReminderStatus != domain.ReminderStatusPending ||
+		got.ReminderAttempts != 0 || got.ReminderError != "" || got.ReminderNextAt != nil {
+		t.Fatalf("expected a fresh reminder, got %+v", got)
+	}
+	got, err = s.SetRemind(task.ID, nil)
+	if err != nil || got.RemindAt != nil {
+		t.Fatalf("SetRemind(nil): got %+v %v", got, err)
+	}
+}
+
+func testUpdateTaskRemindResetsDelivery(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "call", DueAt: at(time.Hour), RemindAt: at(0)})
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotified(task.ID, base); err != nil {
+		t.Fatalf("MarkNotified: %v", err)
+	}
+	delivered, err := s.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+
+	// Same remind_at: the delivery is kept.
+	delivered.Text = "call mom"
+	kept, err := s.UpdateTask(delivered)
+	if err != nil || kept.NotifiedAt == nil || kept.ReminderStatus != domain.ReminderStatusDelivered {
+		t.Fatalf("expected the delivery to be kept, got %+v %v", kept, err)
+	}
+
+	kept.RemindAt = at(time.Hour)
+	moved, err := s.UpdateTask(kept)
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if !sameTime(moved.RemindAt, at(time.Hour)) || moved.NotifiedAt != nil || moved.ReminderStatus != domain.ReminderStatusPending ||
+		moved.ReminderAttempts != 0 || moved.ReminderError != "" || moved.ReminderNextAt != nil {
+		t.Fatalf("expected a fresh reminder, got %+v", moved)
+	}
+	claimed, err := s.ListDueForNotify(base.Add(time.Hour), time.Minute)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != task.ID {
+		t.Fatalf("expected the moved reminder to fire again, got %+v %v", claimed, err)
+	}
+
+	claimed[0].RemindAt = nil
+	claimed[0].DueAt = nil
+	cleared, err := s.UpdateTask(claimed[0])
+	if err != nil || cleared.RemindAt != nil || cleared.DueAt != nil || cleared.ReminderStatus != domain.ReminderStatusPending {
+		t.Fatalf("expected due date and reminder to be cleared, got %+v %v", cleared, err)
+	}
+}
+
+func testUpdateTaskNotifiedAt(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "call", RemindAt: at(0)})
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotifyFailed(task.ID, "timeout", at(time.Minute)); err != nil {
+		t.Fatalf("MarkNotifyFailed: %v", err)
+	}
+	pending, err := s.GetTask(task.ID)
+	if err != nil || pending.ReminderStatus != domain.ReminderStatusPending {
+		t.Fatalf("expected a pending retry, got %+v %v", pending, err)
+	}
+
+	// Setting notified_at records the delivery.
+	pending.NotifiedAt = at(time.Second)
+	delivered, err := s.UpdateTask(pending)
+	if err != nil || !sameTime(delivered.NotifiedAt, at(time.Second)) || delivered.ReminderStatus != domain.ReminderStatusDelivered ||
+		delivered.ReminderError != "" || delivered.ReminderNextAt != nil {
+		t.Fatalf("expected the reminder delivered, got %+v %v", delivered, err)
+	}
+	if listed, err := s.ListReminders(domain.ReminderStatusPending); err != nil || len(listed) != 0 {
+		t.Fatalf("expected no pending reminders, got %+v %v", listed, err)
+	}
+	if claimed, err := s.ListDueForNotify(base.Add(time.Hour), time.Minute); err != nil || len(claimed) != 0 {
+		t.Fatalf("expected a delivered reminder not to fire, got %+v %v", claimed, err)
+	}
+
+	// Clearing it arms the reminder again.
+	delivered.NotifiedAt = nil
+	rearmed, err := s.UpdateTask(delivered)
+	if err != nil || rearmed.NotifiedAt != nil || rearmed.ReminderStatus != domain.ReminderStatusPending ||
+		rearmed.ReminderAttempts != 0 || rearmed.ReminderError != "" || rearmed.ReminderNextAt != nil {
+		t.Fatalf("expected a fresh reminder, got %+v %v", rearmed, err)
+	}
+	claimed, err := s.ListDueForNotify(base.Add(time.Hour), time.Minute)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != task.ID {
+		t.Fatalf("expected the cleared reminder to fire again, got %+v %v", claimed, err)
+	}
+}
+
+func testNotifyClaimAndLease(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	due := mustTask(t, s, domain.Task{UserID: u.ID, Text: "due", RemindAt: at(-time.Minute)})
+	exact := mustTask(t, s, domain.Task{UserID: u.ID, Text: "exact", RemindAt: at(0)})
+	mustTask(t, s, domain.Task{UserID: u.ID, Text: "future", RemindAt: at(time.Hour)})
+	mustTask(t, s, domain.Task{UserID: u.ID, Text: "no reminder"})
+	mustTask(t, s, domain.Task{UserID: u.ID, Text: "notified", RemindAt: at(-time.Hour), NotifiedAt: at(-time.Hour)})
+	done := mustTask(t, s, domain.Task{UserID: u.ID, Text: "done", RemindAt: at(-time.Hour)})
+	if _, err := s.MarkDone(done.ID); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+
+	claimed, err := s.ListDueForNotify(base, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != due.ID || claimed[1].ID != exact.ID {
+		t.Fatalf("expected due and exact tasks ordered by id, got %+v", claimed)
+	}
+	for _, task := range claimed {
+		if task.ReminderStatus != domain.ReminderStatusSending || task.ReminderAttempts != 1 || !sameTime(task.ReminderNextAt, at(2*time.Minute)) {
+			t.Fatalf("expected claimed task to hold a lease, got %+v", task)
+		}
+	}
+
+	again, err := s.ListDueForNotify(base.Add(time.Minute), 2*time.Minute)
+	if err != nil || len(again) != 0 {
+		t.Fatalf("expected leased tasks not to be claimed again, got %+v %v", again, err)
+	}
+	expired, err := s.ListDueForNotify(base.Add(2*time.Minute), 2*time.Minute)
+	if err != nil || len(expired) != 2 || expired[0].ReminderAttempts != 2 {
+		t.Fatalf("expected expired leases to be reclaimed, got %+v %v", expired, err)
+	}
+
+	sending, err := s.ListReminders(domain.ReminderStatusSending)
+	if err != nil || len(sending) != 2 {
+		t.Fatalf("ListReminders(sending): got %+v %v", sending, err)
+	}
+	all, err := s.ListReminders("")
+	if err != nil || len(all) != 5 {
+		t.Fatalf("ListReminders(all): expected every task with a reminder, got %d %v", len(all), err)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].ID >= all[i].ID {
+			t.Fatalf("ListReminders: expected id order, got %+v", all)
+		}
+	}
+}
+
+func testNotifyIdempotent(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "call", RemindAt: at(0)})
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotified(task.ID, base.Add(time.Second)); err != nil {
+		t.Fatalf("MarkNotified: %v", err)
+	}
+	if err := s.MarkNotified(task.ID, base.Add(time.Hour)); err != nil {
+		t.Fatalf("MarkNotified twice: %v", err)
+	}
+	if err := s.MarkNotifyFailed(task.ID, "late failure", nil); err != nil {
+		t.Fatalf("MarkNotifyFailed after delivery: %v", err)
+	}
+
+	got, err := s.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if got.ReminderStatus != domain.ReminderStatusDelivered || !sameTime(got.NotifiedAt, at(time.Second)) ||
+		got.ReminderError != "" || got.ReminderNextAt != nil {
+		t.Fatalf("expected the first acknowledgement to win, got %+v", got)
+	}
+	claimed, err := s.ListDueForNotify(base.Add(24*time.Hour), time.Minute)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("expected a delivered reminder never to be claimed again, got %+v %v", claimed, err)
+	}
+}
+
+func testNotifyFailure(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "call", RemindAt: at(0)})
+	if _, err := s.ListDueForNotify(base, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := s.MarkNotifyFailed(task.ID, "timeout", at(10*time.Minute)); err != nil {
+		t.Fatalf("MarkNotifyFailed(retry): %v", err)
+	}
+	got, err := s.GetTask(task.ID)
+	if err != nil || got.ReminderStatus != domain.ReminderStatusPending || got.ReminderError != "timeout" || !sameTime(got.ReminderNextAt, at(10*time.Minute)) {
+		t.Fatalf("expected retry to be scheduled, got %+v %v", got, err)
+	}
+	if early, err := s.ListDueForNotify(base.Add(5*time.Minute), time.Minute); err != nil || len(early) != 0 {
+		t.Fatalf("expected no claim before retry time, got %+v %v", early, err)
+	}
+	retried, err := s.ListDueForNotify(base.Add(10*time.Minute), time.Minute)
+	if err != nil || len(retried) != 1 || retried[0].ReminderAttempts != 2 {
+		t.Fatalf("expected retry claim, got %+v %v", retried, err)
+	}
+
+	if err := s.MarkNotifyFailed(task.ID, "chat not found", nil); err != nil {
+		t.Fatalf("MarkNotifyFailed(dead): %v", err)
+	}
+	got, err = s.GetTask(task.ID)
+	if err != nil || got.ReminderStatus != domain.ReminderStatusFailed || got.ReminderError != "chat not found" || got.ReminderNextAt != nil {
+		t.Fatalf("expected dead letter, got %+v %v", got, err)
+	}
+	if late, err := s.ListDueForNotify(base.Add(24*time.Hour), time.Minute); err != nil || len(late) != 0 {
+		t.Fatalf("expected failed reminder to stay parked, got %+v %v", late, err)
+	}
+	failed, err := s.ListReminders(domain.ReminderStatusFailed)
+	if err != nil || len(failed) != 1 || failed[0].ID != task.ID {
+		t.Fatalf("ListReminders(failed): got %+v %v", failed, err)
+	}
+}
+
+func testTokens(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	other := mustUser(t, s, 2)
+	if _, err := s.CreateToken(domain.APIToken{UserID: other.ID + 100, Hash: "orphan"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("CreateToken for unknown user: expected ErrNotFound, got %v", err)
+	}
+	forever, err := s.CreateToken(domain.APIToken{UserID: u.ID, Name: "cli", Hash: "h1"})
+	if err != nil || forever.ID == 0 || forever.CreatedAt.IsZero() {
+		t.Fatalf("CreateToken: got %+v %v", forever, err)
+	}
+	short, err := s.CreateToken(domain.APIToken{UserID: u.ID, Name: "session", Hash: "h2", ExpiresAt: at(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateToken(expiring): %v", err)
+	}
+
+	if got, err := s.GetUserByTokenHash("h1", base); err != nil || got.ID != u.ID {
+		t.Fatalf("GetUserByTokenHash: got %+v %v", got, err)
+	}
+	if got, err := s.GetUserByTokenHash("h2", base); err != nil || got.ID != u.ID {
+		t.Fatalf("GetUserByTokenHash before expiry: got %+v %v", got, err)
+	}
+	if _, err := s.GetUserByTokenHash("h2", base.Add(time.Hour)); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetUserByTokenHash at expiry: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUserByTokenHash("nope", base); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetUserByTokenHash unknown: expected ErrNotFound, got %v", err)
+	}
+
+	tokens, err := s.ListTokens(u.ID)
+	if err != nil || len(tokens) != 2 || tokens[0].ID != forever.ID || tokens[1].ID != short.ID || tokens[1].ExpiresAt == nil {
+		t.Fatalf("ListTokens: got %+v %v", tokens, err)
+	}
+	if err := s.DeleteToken(other.ID, forever.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteToken by other user: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteToken(u.ID, forever.ID); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	if _, err := s.GetUserByTokenHash("h1", base); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected revoked token to stop working, got %v", err)
+	}
+	if err := s.DeleteToken(u.ID, forever.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteToken twice: expected ErrNotFound, got %v", err)
+	}
+
+	kept, err := s.CreateToken(domain.APIToken{UserID: u.ID, Name: "cli", Hash: "h3"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if err := s.DeleteExpiredTokens(base); err != nil {
+		t.Fatalf("DeleteExpiredTokens before expiry: %v", err)
+	}
+	if tokens, _ := s.ListTokens(u.ID); len(tokens) != 2 {
+		t.Fatalf("expected live tokens to stay, got %+v", tokens)
+	}
+	if err := s.DeleteExpiredTokens(base.Add(time.Hour)); err != nil {
+		t.Fatalf("DeleteExpiredTokens: %v", err)
+	}
+	if tokens, err := s.ListTokens(u.ID); err != nil || len(tokens) != 1 || tokens[0].ID != kept.ID {
+		t.Fatalf("expected only the token without expiry to stay, got %+v %v", tokens, err)
+	}
+}
+
+func testTelegramUpdates(t *testing.T, s app.Store) {
+	seen := func(id int64, now time.Time, want bool) {
+		t.Helper()
+		if fresh, err := s.MarkUpdateSeen(id, now); err != nil || fresh != want {
+			t.Fatalf("MarkUpdateSeen(%d): expected %v, got %v %v", id, want, fresh, err)
+		}
+	}
+	seen(1, base, true)
+	seen(1, base.Add(time.Minute), false)
+	seen(2, base.Add(time.Minute), true)
+	// Forgotten once the retention is over.
+	seen(1, base.Add(storage.UpdateRetention+time.Hour), true)
+}
+
+func testConcurrentCreate(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	const workers, perWorker = 8, 10
+	ids := make(chan int64, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				task, err := s.CreateTask(domain.Task{UserID: u.ID, Text: "parallel"})
+				if err != nil {
+					t.Errorf("create task: %v", err)
+					return
+				}
+				ids <- task.ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := map[int64]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate task id %d", id)
+		}
+		seen[id] = true
+	}
+	tasks, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(tasks) != workers*perWorker {
+		t.Fatalf("expected %d tasks, got %d %v", workers*perWorker, len(tasks), err)
+	}
+}
+
+func testConcurrentClaim(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	const total = 20
+	for i := 0; i < total; i++ {
+		mustTask(t, s, domain.Task{UserID: u.ID, Text: "due", RemindAt: at(-time.Minute)})
+	}
+	const workers = 8
+	var mu sync.Mutex
+	claims := map[int64]int{}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := s.ListDueForNotify(base, time.Minute)
+			if err != nil {
+				t.Errorf("claim: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, task := range claimed {
+				claims[task.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+	if len(claims) != total {
+		t.Fatalf("expected all %d tasks to be claimed, got %d", total, len(claims))
+	}
+	for id, n := range claims {
+		if n != 1 {
+			t.Fatalf("task %d claimed %d times", id, n)
+		}
+	}
+}
+
+func testConcurrentComplete(t *testing.T, s app.Store) {
+	u := mustUser(t, s, 1)
+	task := mustTask(t, s, domain.Task{UserID: u.ID, Text: "daily", DueAt: at(0), Recurrence: "FREQ=DAILY"})
+	const workers = 8
+	spawned := make(chan int64, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := domain.Task{UserID: u.ID, Text: "daily", DueAt: at(24 * time.Hour), Recurrence: "FREQ=DAILY"}
+			_, created, err := s.CompleteTask(task.ID, storage.Completion{Next: &next})
+			if err != nil {
+				t.Errorf("CompleteTask: %v", err)
+				return
+			}
+			if created != nil {
+				spawned <- created.ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(spawned)
+	if len(spawned) != 1 {
+		t.Fatalf("expected exactly one next occurrence, got %d", len(spawned))
+	}
+	tasks, err := s.ListTasks(u.ID, storage.TaskFilter{}, storage.Page{})
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d %v", len(tasks), err)
+	}
+}
@@ -0,0 +1,167 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"example.com/yourapp/internal/domain"
+)
+
+// handleAttachment saves a photo, document or voice message for a task. The task is
+// either named in an "/attach <id>" caption or is the one the replied-to message is
+// about.
+func (b *Bot) handleAttachment(ctx context.Context, msg *Message) error {
+	if msg.From == nil {
+		return nil
+	}
+	attachment, ok := messageAttachment(msg)
+	if !ok {
+		return nil
+	}
+	taskID, caption, err := attachmentTarget(msg)
+	if err != nil {
+		if msg.ReplyToMessage == nil && !strings.HasPrefix(strings.TrimSpace(msg.Caption), "/") {
+			// An ordinary file in the chat, not meant for a task.
+			return nil
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Чтобы прикрепить файл, ответь им на сообщение о задаче или подпиши /attach <id>.")
+	}
+	user, err := b.ensureUser(msg.From, msg.Chat.ID)
+	if err != nil {
+		_ = b.client.SendMessage(ctx, msg.Chat.ID, "Что-то пошло не так, попробуй ещё раз.")
+		return err
+	}
+	tz := user.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	if err := b.ensureTaskOwner(taskID, user.ID, tz); err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
+	}
+	attachment.TaskID = taskID
+	attachment.Caption = caption
+	if _, err := b.attachments.CreateAttachment(attachment); err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог прикрепить файл.")
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Прикрепил к #%d. Посмотреть: /show %d", taskID, taskID))
+}
+
+// handleShowCommand sends the task and then every file attached to it.
+func (b *Bot) handleShowCommand(ctx context.Context, msg *Message, user domain.User, args, tz string) error {
+	id, err := parseIDArg(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /show <id>")
+	}
+	task, err := b.taskService.GetByID(id, tz)
+	if err != nil || task.UserID != user.ID {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
+	}
+	attachments, err := b.attachments.ListAttachments(id)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог получить вложения.")
+	}
+	if err := b.client.SendMessage(ctx, msg.Chat.ID, formatTask(task, len(attachments))); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := b.sendAttachment(ctx, msg.Chat.ID, a); err != nil {
+			// The file id may have gone stale on Telegram's side; keep sending the rest.
+			log.Printf("telegram send attachment %d error: %v", a.ID, err)
+		}
+	}
+	return nil
+}
+
+func (b *Bot) sendAttachment(ctx context.Context, chatID int64, a domain.Attachment) error {
+	switch a.Type {
+	case domain.AttachmentTypePhoto:
+		return b.client.SendPhoto(ctx, chatID, a.TelegramFileID, a.Caption)
+	case domain.AttachmentTypeVoice:
+		return b.client.SendVoice(ctx, chatID, a.TelegramFileID, a.Caption)
+	default:
+		return b.client.SendDocument(ctx, chatID, a.TelegramFileID, a.Caption)
+	}
+}
+
+// messageAttachment picks the file out of a message; for photos that is the largest
+// size.
+func messageAttachment(msg *Message) (domain.Attachment, bool) {
+	switch {
+	case len(msg.Photo) > 0:
+		p := msg.Photo[len(msg.Photo)-1]
+		return domain.Attachment{Type: domain.AttachmentTypePhoto, TelegramFileID: p.FileID, FileUniqueID: p.FileUniqueID}, true
+	case msg.Document != nil:
+		return domain.Attachment{Type: domain.AttachmentTypeDocument, TelegramFileID: msg.Document.FileID, FileUniqueID: msg.Document.FileUniqueID}, true
+	case msg.Voice != nil:
+		return domain.Attachment{Type: domain.AttachmentTypeVoice, TelegramFileID: msg.Voice.FileID, FileUniqueID: msg.Voice.FileUniqueID}, true
+	}
+	return domain.Attachment{}, false
+}
+
+// attachmentTarget finds the task a file is meant for and the caption to keep. An
+// "/attach <id> [caption]" caption wins over the replied-to message.
+func attachmentTarget(msg *Message) (int64, string, error) {
+	command, args := parseCommand(msg.Caption)
+	if command == "attach" {
+		idPart, caption, _ := strings.Cut(args, " ")
+		id, err := parseIDArg(strings.TrimPrefix(idPart, "#"))
+		if err != nil {
+			return 0, "", err
+		}
+		return id, strings.TrimSpace(caption), nil
+	}
+	if command != "" || msg.ReplyToMessage == nil {
+		return 0, "", errors.New("no task")
+	}
+	id, ok := taskIDFromText(msg.ReplyToMessage.Text)
+	if !ok {
+		return 0, "", errors.New("no task")
+	}
+	return id, strings.TrimSpace(msg.Caption), nil
+}
+
+// taskIDFromText returns the first "#<id>" in a bot message, e.g. "Ок, добавил задачу #5."
+// or "Напоминание: #5 ...".
+func taskIDFromText(text string) (int64, bool) {
+	for rest := text; ; {
+		i := strings.IndexByte(rest, '#')
+		if i < 0 {
+			return 0, false
+		}
+		rest = rest[i+1:]
+		end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+		if end < 0 {
+			end = len(rest)
+		}
+		if id, err := strconv.ParseInt(rest[:end], 10, 64); err == nil && id > 0 {
+			return id, true
+		}
+	}
+}
+
+func formatTask(t domain.Task, attachments int) string {
+	lines := []string{fmt.Sprintf("#%d %s", t.ID, t.Text)}
+	if t.Status == domain.TaskStatusDone {
+		lines = append(lines, "Статус: готово")
+	}
+	if t.DueAt != nil {
+		lines = append(lines, "Срок: "+formatTime(t.DueAt))
+	}
+	if t.Recurrence != "" {
+		lines = append(lines, "Повтор: "+t.Recurrence)
+	}
+	if len(t.Tags) > 0 {
+		lines = append(lines, "Теги: #"+strings.Join(t.Tags, " #"))
+	}
+	if attachments == 0 {
+		lines = append(lines, "Вложений нет. Ответь файлом на это сообщение, чтобы прикрепить.")
+	} else {
+		lines = append(lines, fmt.Sprintf("Вложений: %d", attachments))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,28 @@
+package telegram
+
+import "testing"
+
+func TestAttachmentTarget(t *testing.T) {
+	reply := &Message{Text: "Ок, добавил подзадачу #13 к #12."}
+	tests := []struct {
+		msg     Message
+		id      int64
+		caption string
+		ok      bool
+	}{
+		{Message{Caption: "/attach 7 чек"}, 7, "чек", true},
+		{Message{Caption: "/attach #7", ReplyToMessage: reply}, 7, "", true},
+		{Message{Caption: "скан", ReplyToMessage: reply}, 13, "скан", true},
+		{Message{ReplyToMessage: &Message{Text: "Напоминание: #4 позвонить #дом"}}, 4, "", true},
+		{Message{ReplyToMessage: &Message{Text: "Теги: #дом"}}, 0, "", false},
+		{Message{Caption: "/attach x"}, 0, "", false},
+		{Message{Caption: "/show 7", ReplyToMessage: reply}, 0, "", false},
+		{Message{Caption: "просто фото"}, 0, "", false},
+	}
+	for _, tt := range tests {
+		id, caption, err := attachmentTarget(&tt.msg)
+		if (err == nil) != tt.ok || id != tt.id || caption != tt.caption {
+			t.Errorf("%+v: got %d %q %v", tt.msg, id, caption, err)
+		}
+	}
+}
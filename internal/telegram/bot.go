@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"example.com/yourapp/internal/domain"
 	"example.com/yourapp/internal/repository"
@@ -18,20 +21,31 @@ import (
 type Bot struct {
 	client      *Client
 	taskService *usecase.TaskService
-	users       repository.UserRepository
+	users       *usecase.UserService
+	projects    repository.ProjectRepository
+	attachments repository.AttachmentRepository
+	auth        *usecase.AuthService
 	pollTimeout time.Duration
 }
 
-func NewBot(token string, taskService *usecase.TaskService, users repository.UserRepository, pollTimeout time.Duration) *Bot {
+func NewBot(token string, taskService *usecase.TaskService, users *usecase.UserService, projects repository.ProjectRepository, attachments repository.AttachmentRepository, auth *usecase.AuthService, pollTimeout time.Duration) *Bot {
 	return &Bot{
 		client:      NewClient(token),
 		taskService: taskService,
 		users:       users,
+		projects:    projects,
+		attachments: attachments,
+		auth:        auth,
 		pollTimeout: pollTimeout,
 	}
 }
 
+// Run receives updates via long polling. Use WebhookHandler instead when running
+// several replicas.
 func (b *Bot) Run(ctx context.Context) error {
+	if err := b.client.DeleteWebhook(ctx); err != nil {
+		log.Printf("telegram deleteWebhook error: %v", err)
+	}
 	offset := 0
 	for {
 		if ctx.Err() != nil {
@@ -48,16 +62,38 @@ func (b *Bot) Run(ctx context.Context) error {
 		}
 		for _, upd := range updates {
 			offset = upd.UpdateID + 1
-			if upd.Message == nil || upd.Message.Text == "" {
-				continue
-			}
-			if err := b.handleMessage(ctx, upd.Message); err != nil {
-				log.Printf("telegram handle message error: %v", err)
-			}
+			b.handleUpdate(ctx, upd)
 		}
 	}
 }
 
+func (b *Bot) handleUpdate(ctx context.Context, upd Update) {
+	if upd.CallbackQuery != nil {
+		if err := b.handleCallback(ctx, upd.CallbackQuery); err != nil {
+			log.Printf("telegram handle callback error: %v", err)
+		}
+		return
+	}
+	if upd.Message != nil && upd.Message.Location != nil {
+		if err := b.handleLocation(ctx, upd.Message); err != nil {
+			log.Printf("telegram handle location error: %v", err)
+		}
+		return
+	}
+	if upd.Message != nil && upd.Message.Text == "" {
+		if err := b.handleAttachment(ctx, upd.Message); err != nil {
+			log.Printf("telegram handle attachment error: %v", err)
+		}
+		return
+	}
+	if upd.Message == nil {
+		return
+	}
+	if err := b.handleMessage(ctx, upd.Message); err != nil {
+		log.Printf("telegram handle message error: %v", err)
+	}
+}
+
 func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
 	if msg.From == nil {
 		return nil
@@ -67,7 +103,7 @@ func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
 		return nil
 	}
 
-	user, err := b.ensureUser(msg)
+	user, err := b.ensureUser(msg.From, msg.Chat.ID)
 	if err != nil {
 		_ = b.client.SendMessage(ctx, msg.Chat.ID, "Что-то пошло не так, попробуй ещё раз.")
 		return err
@@ -81,41 +117,77 @@ func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
 	case "start":
 		return b.client.SendMessage(ctx, msg.Chat.ID, helpText())
 	case "add":
-		text, dueAt, err := parseAddArgs(args, tz)
+		in := usecase.NewTask{UserID: user.ID, ProjectID: user.CurrentProjectID}
+		return b.addTask(ctx, msg, in, args, tz,
+			"Формат: /add [!1-4] <текст> [#тег|@контекст] [когда], например /add !1 позвонить маме #дом завтра в 9")
+	case "sub":
+		parentID, rest, err := splitTaskID(args)
 		if err != nil {
-			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /add <текст> [YYYY-MM-DD HH:MM]")
-		}
-		var remindAt *time.Time
-		if dueAt != nil {
-			remindAt = dueAt
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /sub <id> <текст> [когда], например /sub 5 собрать changelog")
 		}
-		task, err := b.taskService.Create(user.ID, text, dueAt, remindAt, tz)
-		if err != nil {
-			if errors.Is(err, usecase.ErrInvalidText) {
-				return b.client.SendMessage(ctx, msg.Chat.ID, "Текст пустой, давай по‑нормальному :)")
-			}
-			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог добавить задачу.")
+		if err := b.ensureTaskOwner(parentID, user.ID, tz); err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
 		}
-		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, добавил задачу #%d.", task.ID))
+		in := usecase.NewTask{UserID: user.ID, ParentID: &parentID}
+		return b.addTask(ctx, msg, in, rest, tz, "Формат: /sub <id> [!1-4] <текст> [#тег|@контекст] [когда]")
 	case "list":
+		rest, priority := splitPriority(args)
+		rest, tags := splitTags(rest)
+		if rest != "" {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /list [!1-4] [#тег ...]")
+		}
 		items, err := b.taskService.ListActive(user.ID, tz)
 		if err != nil {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог получить список задач.")
 		}
-		return b.client.SendMessage(ctx, msg.Chat.ID, formatTaskList(items))
+		var projectID int64
+		if user.CurrentProjectID != nil {
+			projectID = *user.CurrentProjectID
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, formatTaskList(filterTasks(items, projectID, priority, tags)))
+	case "tags":
+		tags, err := b.taskService.ListTags(user.ID)
+		if err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог получить теги.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, formatTags(tags))
+	case "find":
+		return b.handleFindCommand(ctx, msg, user, args, tz)
+	case "show":
+		return b.handleShowCommand(ctx, msg, user, args, tz)
+	case "block":
+		return b.handleBlockCommand(ctx, msg, user, args, tz, false)
+	case "unblock":
+		return b.handleBlockCommand(ctx, msg, user, args, tz, true)
+	case "projects":
+		return b.handleProjectsCommand(ctx, msg, user)
+	case "project":
+		return b.handleProjectCommand(ctx, msg, user, args)
 	case "done":
-		id, err := parseIDArg(args)
+		id, policy, err := parseDoneArgs(args)
 		if err != nil {
-			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /done <id>")
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /done <id> [all]")
 		}
 		if err := b.ensureTaskOwner(id, user.ID, tz); err != nil {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
 		}
-		task, err := b.taskService.MarkDone(id, tz)
+		task, next, err := b.taskService.Complete(id, policy, tz)
 		if err != nil {
+			if errors.Is(err, usecase.ErrOpenSubtasks) {
+				return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf(
+					"У #%d есть незакрытые подзадачи. Закрыть всё вместе: /done %d all", id, id))
+			}
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог завершить задачу.")
 		}
-		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Готово, задача #%d закрыта.", task.ID))
+		reply := fmt.Sprintf("Готово, задача #%d закрыта.", task.ID)
+		if next != nil {
+			reply += fmt.Sprintf("\nСледующий повтор — #%d", next.ID)
+			if next.DueAt != nil {
+				reply += ", " + formatTime(next.DueAt)
+			}
+			reply += "."
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, reply)
 	case "del":
 		id, err := parseIDArg(args)
 		if err != nil {
@@ -124,14 +196,14 @@ func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
 		if err := b.ensureTaskOwner(id, user.ID, tz); err != nil {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
 		}
-		if err := b.taskService.Delete(id); err != nil {
+		if err := b.taskService.Delete(id, 0); err != nil {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог удалить задачу.")
 		}
 		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Удалил задачу #%d.", id))
 	case "due":
-		id, dueAt, err := parseDueArgs(args, tz)
+		id, dueAt, err := parseDueArgs(args, time.Now(), tz)
 		if err != nil {
-			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /due <id> <YYYY-MM-DD HH:MM>")
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /due <id> <когда>, например /due 3 через 2 часа или /due 3 в пятницу 18:00")
 		}
 		if err := b.ensureTaskOwner(id, user.ID, tz); err != nil {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
@@ -144,24 +216,77 @@ func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
 			return b.client.SendMessage(ctx, msg.Chat.ID, "Срок поставил, а напоминание — нет :(")
 		}
 		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Срок для #%d: %s.", task.ID, formatTime(dueAt)))
+	case "token":
+		if msg.Chat.Type != "private" {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Токен выдаю только в личке.")
+		}
+		plain, _, err := b.auth.IssueToken(user.ID, args, 0)
+		if err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог выпустить токен.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf(
+			"Твой API-токен (второй раз не покажу):\n%s\n\nЗаголовок: Authorization: Bearer <токен>", plain))
+	case "tz":
+		return b.handleTimezoneCommand(ctx, msg, user, args)
+	case "repeat":
+		id, rule, err := parseRepeatArgs(args)
+		if err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /repeat <id> <правило|off>, например /repeat 3 FREQ=WEEKLY;BYDAY=MO,FR")
+		}
+		if err := b.ensureTaskOwner(id, user.ID, tz); err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
+		}
+		task, err := b.taskService.SetRecurrence(id, rule, tz)
+		if err != nil {
+			if errors.Is(err, usecase.ErrInvalidRecurrence) {
+				return b.client.SendMessage(ctx, msg.Chat.ID, "Не понял правило повтора.")
+			}
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог настроить повтор.")
+		}
+		if task.Recurrence == "" {
+			return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Повтор для #%d выключен.", task.ID))
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Повтор для #%d: %s.", task.ID, task.Recurrence))
 	default:
 		return b.client.SendMessage(ctx, msg.Chat.ID, "Не понял команду. /start покажет хелп.")
 	}
 }
 
-func (b *Bot) ensureUser(msg *Message) (domain.User, error) {
-	user, err := b.users.GetByTelegramID(msg.From.ID)
-	if err == nil {
-		return user, nil
-	}
-	if !errors.Is(err, storage.ErrNotFound) {
-		return domain.User{}, err
-	}
-	return b.users.CreateUser(domain.User{
-		TelegramUserID: msg.From.ID,
-		ChatID:         msg.Chat.ID,
-		Timezone:       "UTC",
-	})
+// addTask parses "[!1-4] <text> [#tag] [when]" into in and creates the task; usage is
+// sent back when args don't parse.
+func (b *Bot) addTask(ctx context.Context, msg *Message, in usecase.NewTask, args, tz, usage string) error {
+	rest, priority := splitPriority(args)
+	rest, tags := splitTags(rest)
+	text, dueAt, err := parseAddArgs(rest, time.Now(), tz)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, usage)
+	}
+	in.Text = text
+	in.Priority = priority
+	in.Tags = tags
+	in.DueAt = dueAt
+	in.RemindAt = dueAt
+	task, err := b.taskService.Create(in, tz)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidText) {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Текст пустой, давай по‑нормальному :)")
+		}
+		if errors.Is(err, usecase.ErrInvalidParent) {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Это уже подзадача, вложенные подзадачи не поддерживаются.")
+		}
+		if errors.Is(err, usecase.ErrProjectArchived) || errors.Is(err, usecase.ErrProjectNotFound) {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Проект в архиве или удалён. Переключись: /project <название> или /project off.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог добавить задачу.")
+	}
+	if task.ParentID != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, добавил подзадачу #%d к #%d.", task.ID, *task.ParentID))
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, добавил задачу #%d.", task.ID))
+}
+
+func (b *Bot) ensureUser(from *User, chatID int64) (domain.User, error) {
+	return b.users.EnsureTelegramUser(from.ID, chatID)
 }
 
 func (b *Bot) ensureTaskOwner(taskID, userID int64, tz string) error {
@@ -192,46 +317,119 @@ func parseCommand(text string) (string, string) {
 	return cmd, strings.TrimSpace(parts[1])
 }
 
-func parseAddArgs(args, tz string) (string, *time.Time, error) {
+func parseAddArgs(args string, now time.Time, tz string) (string, *time.Time, error) {
 	args = strings.TrimSpace(args)
 	if args == "" {
 		return "", nil, errors.New("empty")
 	}
+	loc, err := usecase.LocationFromTZ(tz)
+	if err != nil {
+		return "", nil, err
+	}
+	text, dueAt := splitTrailingDate(args, now, loc)
+	if text == "" {
+		return "", nil, errors.New("empty text")
+	}
+	return text, dueAt, nil
+}
+
+// splitPriority pulls a standalone "!1".."!4" token out of args; 0 means none was given.
+func splitPriority(args string) (string, int) {
 	fields := strings.Fields(args)
-	if len(fields) >= 3 {
-		datePart := fields[len(fields)-2]
-		timePart := fields[len(fields)-1]
-		if looksLikeDate(datePart) && looksLikeTime(timePart) {
-			dt, err := parseDateTime(datePart, timePart, tz)
-			if err != nil {
-				return "", nil, err
-			}
-			text := strings.TrimSpace(strings.Join(fields[:len(fields)-2], " "))
-			if text == "" {
-				return "", nil, errors.New("empty text")
-			}
-			return text, &dt, nil
+	for i, f := range fields {
+		if len(f) == 2 && f[0] == '!' && f[1] >= '1' && f[1] <= '4' {
+			rest := append(fields[:i:i], fields[i+1:]...)
+			return strings.Join(rest, " "), int(f[1] - '0')
 		}
 	}
-	return args, nil, nil
+	return strings.TrimSpace(args), 0
 }
 
-func parseDueArgs(args, tz string) (int64, *time.Time, error) {
-	fields := strings.Fields(args)
-	if len(fields) < 3 {
+// splitTags pulls "#tag" and "@context" tokens out of args. Both are tags: "@home" and
+// "#home" are the same one. Tokens like "#5" stay in the text, since that is how task
+// ids are written.
+func splitTags(args string) (string, []string) {
+	var rest, tags []string
+	for _, f := range strings.Fields(args) {
+		body, tagged := strings.CutPrefix(f, "#")
+		if !tagged {
+			body, tagged = strings.CutPrefix(f, "@")
+		}
+		name, err := domain.NormalizeTag(body)
+		first, _ := utf8.DecodeRuneInString(body)
+		if tagged && err == nil && unicode.IsLetter(first) {
+			tags = append(tags, name)
+			continue
+		}
+		rest = append(rest, f)
+	}
+	return strings.Join(rest, " "), tags
+}
+
+// filterTasks keeps tasks from the given project and with the given priority (0 means
+// any for both) that carry all tags.
+func filterTasks(items []domain.Task, projectID int64, priority int, tags []string) []domain.Task {
+	out := items[:0]
+	for _, t := range items {
+		if projectID != 0 && (t.ProjectID == nil || *t.ProjectID != projectID) {
+			continue
+		}
+		if priority != 0 && t.Priority != priority {
+			continue
+		}
+		if !hasAllTags(t, tags) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func hasAllTags(t domain.Task, tags []string) bool {
+	for _, want := range tags {
+		if !slices.Contains(t.Tags, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDueArgs(args string, now time.Time, tz string) (int64, *time.Time, error) {
+	idPart, rest, ok := strings.Cut(strings.TrimSpace(args), " ")
+	if !ok {
 		return 0, nil, errors.New("invalid")
 	}
-	id, err := strconv.ParseInt(fields[0], 10, 64)
+	id, err := strconv.ParseInt(idPart, 10, 64)
 	if err != nil || id <= 0 {
 		return 0, nil, errors.New("id")
 	}
-	dt, err := parseDateTime(fields[1], fields[2], tz)
+	loc, err := usecase.LocationFromTZ(tz)
+	if err != nil {
+		return 0, nil, err
+	}
+	dt, err := parseWhen(rest, now, loc)
 	if err != nil {
 		return 0, nil, err
 	}
 	return id, &dt, nil
 }
 
+func parseRepeatArgs(args string) (int64, string, error) {
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		return 0, "", errors.New("invalid")
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", errors.New("id")
+	}
+	rule := fields[1]
+	if strings.EqualFold(rule, "off") {
+		rule = ""
+	}
+	return id, rule, nil
+}
+
 func parseIDArg(args string) (int64, error) {
 	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
 	if err != nil || id <= 0 {
@@ -240,14 +438,6 @@ func parseIDArg(args string) (int64, error) {
 	return id, nil
 }
 
-func parseDateTime(datePart, timePart, tz string) (time.Time, error) {
-	loc, err := usecase.LocationFromTZ(tz)
-	if err != nil {
-		return time.Time{}, err
-	}
-	return time.ParseInLocation("2006-01-02 15:04", datePart+" "+timePart, loc)
-}
-
 func looksLikeDate(s string) bool {
 	if len(s) != 10 || s[4] != '-' || s[7] != '-' {
 		return false
@@ -263,37 +453,53 @@ func looksLikeDate(s string) bool {
 	return true
 }
 
-func looksLikeTime(s string) bool {
-	if len(s) != 5 || s[2] != ':' {
-		return false
-	}
-	for i := 0; i < len(s); i++ {
-		if i == 2 {
-			continue
-		}
-		if s[i] < '0' || s[i] > '9' {
-			return false
-		}
-	}
-	return true
-}
-
 func formatTaskList(items []domain.Task) string {
 	if len(items) == 0 {
 		return "Пока пусто. Добавь задачу через /add."
 	}
 	lines := make([]string, 0, len(items)+1)
 	lines = append(lines, "Активные задачи:")
-	for _, t := range items {
-		line := fmt.Sprintf("%d) %s", t.ID, t.Text)
+	for _, e := range nestSubtasks(items) {
+		t := e.task
+		line := fmt.Sprintf("%d) ", t.ID)
+		if e.nested {
+			line = "    └ " + line
+		}
+		if domain.ValidPriority(t.Priority) && t.Priority != domain.PriorityDefault {
+			line += fmt.Sprintf("[P%d] ", t.Priority)
+		}
+		line += t.Text
+		if t.Progress != nil {
+			line += fmt.Sprintf(" (%d/%d)", t.Progress.Done, t.Progress.Total)
+		}
 		if t.DueAt != nil {
 			line += " — до " + formatTime(t.DueAt)
 		}
+		if t.Recurrence != "" {
+			line += " ↻"
+		}
+		if t.Blocked {
+			line += " 🔒"
+		}
+		for _, tag := range t.Tags {
+			line += " #" + tag
+		}
 		lines = append(lines, line)
 	}
 	return strings.Join(lines, "\n")
 }
 
+func formatTags(tags []domain.Tag) string {
+	if len(tags) == 0 {
+		return "Тегов пока нет. Добавь их прямо в задаче: /add купить молоко #магазин."
+	}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, "#"+t.Name)
+	}
+	return "Теги: " + strings.Join(names, " ") + "\nФильтр: /list #тег"
+}
+
 func formatTime(t *time.Time) string {
 	if t == nil {
 		return ""
@@ -305,10 +511,23 @@ func helpText() string {
 	return strings.Join([]string{
 		"Команды:",
 		"/start — этот хелп",
-		"/add <текст> [YYYY-MM-DD HH:MM] — добавить задачу",
-		"/list — активные задачи",
-		"/done <id> — завершить",
+		"/add [!1-4] <текст> [#тег|@контекст] [когда] — добавить задачу, !1 — самый высокий приоритет",
+		"/sub <id> <текст> [когда] — подзадача (пункт чек-листа) к задаче",
+		"/list [!1-4] [#тег|@контекст] — активные задачи, важные сверху",
+		"/find <слова> — поиск по всем задачам, включая закрытые",
+		"/tags — мои теги",
+		"/projects — мои проекты",
+		"/project [название | off] — текущий проект: в него попадают /add и /list",
+		"/block <id> <id> — первая задача ждёт вторую (🔒 в списке), /unblock <id> <id> — снять",
+		"/show <id> — задача и её вложения; чтобы прикрепить фото, файл или голосовое, ответь им на сообщение о задаче или подпиши /attach <id>",
+		"/done <id> [all] — завершить; all закроет и открытые подзадачи",
 		"/del <id> — удалить",
-		"/due <id> <YYYY-MM-DD HH:MM> — срок и напоминание",
+		"/due <id> <когда> — срок и напоминание",
+		"/repeat <id> <правило|off> — повтор, например FREQ=WEEKLY;BYDAY=MO,FR",
+		"/tz [Europe/Moscow | +03:00] — часовой пояс (или пришли геопозицию)",
+		"/token [название] — API-токен для HTTP API",
+		"",
+		"«Когда» можно писать по-человечески: завтра в 9, через 2 часа, в пятницу 18:00,",
+		"tomorrow 9am, in 30m, next monday, 2026-01-02 10:00.",
 	}, "\n")
 }
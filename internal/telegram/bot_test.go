@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitPriority(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantRest     string
+		wantPriority int
+	}{
+		{"!1 купить молоко", "купить молоко", 1},
+		{"купить молоко !3 завтра в 9", "купить молоко завтра в 9", 3},
+		{"купить молоко", "купить молоко", 0},
+		{"ура! !5 готово", "ура! !5 готово", 0},
+		{"!2", "", 2},
+	}
+	for _, tt := range tests {
+		rest, priority := splitPriority(tt.in)
+		if rest != tt.wantRest || priority != tt.wantPriority {
+			t.Errorf("%q: got %q %d", tt.in, rest, priority)
+		}
+	}
+}
+
+func TestSplitTags(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantRest string
+		wantTags []string
+	}{
+		{"купить молоко #Магазин завтра в 9", "купить молоко завтра в 9", []string{"магазин"}},
+		{"#work #home отчёт", "отчёт", []string{"work", "home"}},
+		{"см. задачу #5", "см. задачу #5", nil},
+		{"без тегов", "без тегов", nil},
+		{"# пусто", "# пусто", nil},
+		{"отчёт @work #срочно", "отчёт", []string{"work", "срочно"}},
+		{"@Home", "", []string{"home"}},
+		{"письмо на me@example.com", "письмо на me@example.com", nil},
+		{"@ 5 @5", "@ 5 @5", nil},
+	}
+	for _, tt := range tests {
+		rest, tags := splitTags(tt.in)
+		if rest != tt.wantRest || strings.Join(tags, ",") != strings.Join(tt.wantTags, ",") {
+			t.Errorf("%q: got %q %v", tt.in, rest, tags)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/usecase"
+)
+
+const (
+	callbackDone   = "done"
+	callbackSnooze = "snooze"
+
+	snooze10m      = "10m"
+	snooze1h       = "1h"
+	snoozeTomorrow = "tomorrow"
+)
+
+// reminderKeyboard is attached to reminder messages; callback data is "<action>:<task id>[:<option>]".
+func reminderKeyboard(taskID int64) *InlineKeyboardMarkup {
+	id := strconv.FormatInt(taskID, 10)
+	snooze := func(option string) string {
+		return callbackSnooze + ":" + id + ":" + option
+	}
+	return &InlineKeyboardMarkup{
+		InlineKeyboard: [][]InlineKeyboardButton{
+			{
+				{Text: "Готово", CallbackData: callbackDone + ":" + id},
+				{Text: "+10 мин", CallbackData: snooze(snooze10m)},
+			},
+			{
+				{Text: "+1 час", CallbackData: snooze(snooze1h)},
+				{Text: "Завтра 09:00", CallbackData: snooze(snoozeTomorrow)},
+			},
+		},
+	}
+}
+
+func (b *Bot) handleCallback(ctx context.Context, cq *CallbackQuery) error {
+	if cq.From == nil || cq.Message == nil {
+		return b.client.AnswerCallbackQuery(ctx, cq.ID, "")
+	}
+	if zone, ok := strings.CutPrefix(cq.Data, callbackTimezone+":"); ok {
+		return b.handleTimezoneCallback(ctx, cq, zone)
+	}
+	action, taskID, option, err := parseCallbackData(cq.Data)
+	if err != nil {
+		return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не понял кнопку.")
+	}
+	user, err := b.ensureUser(cq.From, cq.Message.Chat.ID)
+	if err != nil {
+		_ = b.client.AnswerCallbackQuery(ctx, cq.ID, "Что-то пошло не так, попробуй ещё раз.")
+		return err
+	}
+	tz := user.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	if err := b.ensureTaskOwner(taskID, user.ID, tz); err != nil {
+		return b.client.AnswerCallbackQuery(ctx, cq.ID, "Задача не найдена.")
+	}
+
+	var status string
+	switch action {
+	case callbackDone:
+		if _, _, err := b.taskService.Complete(taskID, usecase.RequireSubtasks, tz); err != nil {
+			if errors.Is(err, usecase.ErrOpenSubtasks) {
+				return b.client.AnswerCallbackQuery(ctx, cq.ID, "Сначала закрой подзадачи или отправь /done <id> all.")
+			}
+			return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не смог завершить задачу.")
+		}
+		status = "Готово, задача закрыта."
+	case callbackSnooze:
+		loc, err := usecase.LocationFromTZ(tz)
+		if err != nil {
+			return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не понял часовой пояс.")
+		}
+		remindAt, err := snoozeUntil(option, time.Now(), loc)
+		if err != nil {
+			return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не понял кнопку.")
+		}
+		task, err := b.taskService.SetRemind(taskID, &remindAt, tz)
+		if err != nil {
+			return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не смог отложить напоминание.")
+		}
+		status = "Напомню " + formatTime(task.RemindAt) + "."
+	}
+	if err := b.client.AnswerCallbackQuery(ctx, cq.ID, status); err != nil {
+		return err
+	}
+	text := cq.Message.Text
+	if text != "" {
+		text += "\n\n"
+	}
+	return b.client.EditMessageText(ctx, cq.Message.Chat.ID, cq.Message.MessageID, text+status, nil)
+}
+
+func parseCallbackData(data string) (string, int64, string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 2 {
+		return "", 0, "", errors.New("callback data")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, "", errors.New("id")
+	}
+	switch {
+	case parts[0] == callbackDone && len(parts) == 2:
+		return callbackDone, id, "", nil
+	case parts[0] == callbackSnooze && len(parts) == 3:
+		return callbackSnooze, id, parts[2], nil
+	}
+	return "", 0, "", fmt.Errorf("callback action %q", parts[0])
+}
+
+func snoozeUntil(option string, now time.Time, loc *time.Location) (time.Time, error) {
+	switch option {
+	case snooze10m:
+		return now.Add(10 * time.Minute), nil
+	case snooze1h:
+		return now.Add(time.Hour), nil
+	case snoozeTomorrow:
+		local := now.In(loc)
+		return time.Date(local.Year(), local.Month(), local.Day()+1, 9, 0, 0, 0, loc), nil
+	}
+	return time.Time{}, fmt.Errorf("snooze option %q", option)
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCallbackData(t *testing.T) {
+	tests := []struct {
+		data   string
+		action string
+		id     int64
+		option string
+		ok     bool
+	}{
+		{"done:42", callbackDone, 42, "", true},
+		{"snooze:42:1h", callbackSnooze, 42, "1h", true},
+		{"done", "", 0, "", false},
+		{"", "", 0, "", false},
+		{"done:abc", "", 0, "", false},
+		{"done:0", "", 0, "", false},
+		{"done:-3", "", 0, "", false},
+		{"done:42:1h", "", 0, "", false},
+		{"snooze:42", "", 0, "", false},
+		{"snooze:42:1h:x", "", 0, "", false},
+		{"delete:42", "", 0, "", false},
+	}
+	for _, tt := range tests {
+		action, id, option, err := parseCallbackData(tt.data)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: expected ok=%v, got err %v", tt.data, tt.ok, err)
+			continue
+		}
+		if action != tt.action || id != tt.id || option != tt.option {
+			t.Errorf("%q: expected %s %d %q, got %s %d %q", tt.data, tt.action, tt.id, tt.option, action, id, option)
+		}
+	}
+}
+
+func TestSnoozeUntil(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	tests := []struct {
+		name   string
+		option string
+		now    time.Time
+		want   time.Time
+	}{
+		{
+			name:   "ten minutes",
+			option: snooze10m,
+			now:    time.Date(2026, 5, 4, 12, 0, 0, 0, berlin),
+			want:   time.Date(2026, 5, 4, 12, 10, 0, 0, berlin),
+		},
+		{
+			name:   "an hour",
+			option: snooze1h,
+			now:    time.Date(2026, 5, 4, 23, 30, 0, 0, berlin),
+			want:   time.Date(2026, 5, 5, 0, 30, 0, 0, berlin),
+		},
+		{
+			name:   "tomorrow",
+			option: snoozeTomorrow,
+			now:    time.Date(2026, 5, 4, 23, 30, 0, 0, berlin),
+			want:   time.Date(2026, 5, 5, 9, 0, 0, 0, berlin),
+		},
+		{
+			// Only 22 hours later: the night loses an hour.
+			name:   "tomorrow across spring DST",
+			option: snoozeTomorrow,
+			now:    time.Date(2026, 3, 28, 10, 0, 0, 0, berlin),
+			want:   time.Date(2026, 3, 28, 10, 0, 0, 0, berlin).Add(22 * time.Hour),
+		},
+		{
+			name:   "tomorrow across fall DST",
+			option: snoozeTomorrow,
+			now:    time.Date(2026, 10, 24, 10, 0, 0, 0, berlin),
+			want:   time.Date(2026, 10, 24, 10, 0, 0, 0, berlin).Add(24 * time.Hour),
+		},
+		{
+			// Already the next day in Berlin while UTC is still on the 4th.
+			name:   "tomorrow uses the user's date",
+			option: snoozeTomorrow,
+			now:    time.Date(2026, 5, 4, 22, 30, 0, 0, time.UTC),
+			want:   time.Date(2026, 5, 6, 9, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snoozeUntil(tt.option, tt.now, berlin)
+			if err != nil {
+				t.Fatalf("snoozeUntil: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got.In(berlin))
+			}
+		})
+	}
+	if _, err := snoozeUntil("2d", time.Now(), berlin); err == nil {
+		t.Fatalf("expected an error for an unknown option")
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 )
 
+const allowedUpdates = `["message","callback_query"]`
+
 type Client struct {
 	token   string
 	baseURL string
@@ -41,7 +43,7 @@ func (c *Client) GetUpdates(ctx context.Context, offset int, timeout time.Durati
 	if offset > 0 {
 		q.Set("offset", strconv.Itoa(offset))
 	}
-	q.Set("allowed_updates", `["message"]`)
+	q.Set("allowed_updates", allowedUpdates)
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
@@ -56,10 +58,91 @@ func (c *Client) GetUpdates(ctx context.Context, offset int, timeout time.Durati
 }
 
 func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.SendMessageWithMarkup(ctx, chatID, text, nil)
+}
+
+func (c *Client) SendMessageWithMarkup(ctx context.Context, chatID int64, text string, markup *InlineKeyboardMarkup) error {
 	payload := map[string]any{
 		"chat_id": chatID,
 		"text":    text,
 	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	var res apiResponse[Message]
+	return c.post(ctx, "sendMessage", payload, &res)
+}
+
+// EditMessageText replaces the text of a sent message; a nil markup removes its inline keyboard.
+func (c *Client) EditMessageText(ctx context.Context, chatID int64, messageID int, text string, markup *InlineKeyboardMarkup) error {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	var res apiResponse[Message]
+	return c.post(ctx, "editMessageText", payload, &res)
+}
+
+// sendFile resends a file the bot has received before, by its Telegram file id; method
+// is sendPhoto, sendDocument or sendVoice and field the matching parameter name.
+func (c *Client) sendFile(ctx context.Context, method, field string, chatID int64, fileID, caption string) error {
+	payload := map[string]any{
+		"chat_id": chatID,
+		field:     fileID,
+	}
+	if caption != "" {
+		payload["caption"] = caption
+	}
+	var res apiResponse[Message]
+	return c.post(ctx, method, payload, &res)
+}
+
+func (c *Client) SendPhoto(ctx context.Context, chatID int64, fileID, caption string) error {
+	return c.sendFile(ctx, "sendPhoto", "photo", chatID, fileID, caption)
+}
+
+func (c *Client) SendDocument(ctx context.Context, chatID int64, fileID, caption string) error {
+	return c.sendFile(ctx, "sendDocument", "document", chatID, fileID, caption)
+}
+
+func (c *Client) SendVoice(ctx context.Context, chatID int64, fileID, caption string) error {
+	return c.sendFile(ctx, "sendVoice", "voice", chatID, fileID, caption)
+}
+
+func (c *Client) AnswerCallbackQuery(ctx context.Context, callbackQueryID, text string) error {
+	payload := map[string]any{
+		"callback_query_id": callbackQueryID,
+	}
+	if text != "" {
+		payload["text"] = text
+	}
+	var res apiResponse[bool]
+	return c.post(ctx, "answerCallbackQuery", payload, &res)
+}
+
+// SetWebhook switches the bot to webhook delivery; Telegram sends secret back in the
+// X-Telegram-Bot-Api-Secret-Token header of every update.
+func (c *Client) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	payload := map[string]any{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": json.RawMessage(allowedUpdates),
+	}
+	var res apiResponse[bool]
+	return c.post(ctx, "setWebhook", payload, &res)
+}
+
+// DeleteWebhook is required before getUpdates works again after webhook mode was used.
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	var res apiResponse[bool]
+	return c.post(ctx, "deleteWebhook", map[string]any{}, &res)
+}
+
+func (c *Client) post(ctx context.Context, method string, payload any, out any) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
@@ -67,18 +150,14 @@ func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) err
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		fmt.Sprintf("%s/bot%s/sendMessage", c.baseURL, c.token),
+		fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method),
 		bytes.NewReader(body),
 	)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	var res apiResponse[Message]
-	if err := c.do(req, &res); err != nil {
-		return err
-	}
-	return nil
+	return c.do(req, out)
 }
 
 type apiResponse[T any] struct {
@@ -108,20 +187,68 @@ func (c *Client) do(req *http.Request, out any) error {
 		if !v.Ok {
 			return errors.New(v.Description)
 		}
+	case *apiResponse[bool]:
+		if !v.Ok {
+			return errors.New(v.Description)
+		}
 	}
 	return nil
 }
 
 type Update struct {
-	UpdateID int      `json:"update_id"`
-	Message  *Message `json:"message"`
+	UpdateID      int            `json:"update_id"`
+	Message       *Message       `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+}
+
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    *User    `json:"from"`
+	Message *Message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
 }
 
 type Message struct {
-	MessageID int    `json:"message_id"`
-	From      *User  `json:"from"`
-	Chat      Chat   `json:"chat"`
-	Text      string `json:"text"`
+	MessageID      int         `json:"message_id"`
+	From           *User       `json:"from"`
+	Chat           Chat        `json:"chat"`
+	Text           string      `json:"text"`
+	Caption        string      `json:"caption"`
+	Location       *Location   `json:"location"`
+	Photo          []PhotoSize `json:"photo"`
+	Document       *File       `json:"document"`
+	Voice          *File       `json:"voice"`
+	ReplyToMessage *Message    `json:"reply_to_message"`
+}
+
+// PhotoSize is one resolution of a photo; Telegram lists them smallest first.
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// File holds the fields Document and Voice share.
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name"`
+	MimeType     string `json:"mime_type"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
 }
 
 type User struct {
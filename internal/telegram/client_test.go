@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI stands in for the Bot API: it records every call and answers ok, or with an
+// error for the methods listed in fail.
+type fakeAPI struct {
+	mu    sync.Mutex
+	calls []apiCall
+	fail  map[string]bool
+}
+
+type apiCall struct {
+	Method  string
+	Payload map[string]any
+}
+
+// newFakeClient returns a Client talking to a fresh fakeAPI.
+func newFakeClient(t *testing.T) (*Client, *fakeAPI) {
+	t.Helper()
+	api := &fakeAPI{fail: map[string]bool{}}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	client := NewClient("token")
+	client.baseURL = srv.URL
+	return client, api
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	var payload map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+	a.mu.Lock()
+	a.calls = append(a.calls, apiCall{Method: method, Payload: payload})
+	fail := a.fail[method]
+	a.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if fail {
+		_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+		return
+	}
+	_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+}
+
+func (a *fakeAPI) setFail(method string, fail bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fail[method] = fail
+}
+
+// sent returns the calls of one method.
+func (a *fakeAPI) sent(method string) []apiCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []apiCall
+	for _, c := range a.calls {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
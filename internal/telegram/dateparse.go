@@ -0,0 +1,360 @@
+package telegram
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNoDate = errors.New("no date expression")
+
+// defaultHour is used when only a day is given ("завтра", "next monday").
+const defaultHour = 9
+
+// maxDateTokens bounds how many trailing words of /add text are tried as a date.
+const maxDateTokens = 6
+
+// parseWhen resolves a Russian or English date expression relative to now in loc:
+//
+//	через 2 часа, in 30m, in an hour            — relative offset
+//	завтра в 9, tomorrow 9am, today at 18:30   — day word plus optional time
+//	в пятницу 18:00, next monday, on fri 5pm   — weekday plus optional time
+//	2026-01-02 10:00, 02.01 10:00, в 9 вечера  — absolute date and/or time
+//
+// Minutes and hours are absolute durations; days and weeks keep the wall-clock time, so
+// they stay correct across DST changes. A time without a day means the next such time
+// (today or tomorrow). A bare weekday means the nearest such day, today included when
+// the time is still ahead; "next"/"следующий" always skips today. A bare number is only
+// read as an hour when a day or a preposition ("в", "at") makes it unambiguous.
+func parseWhen(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	toks := tokenize(s)
+	if len(toks) == 0 {
+		return time.Time{}, errNoDate
+	}
+	now = now.In(loc)
+	if rel, n, ok := matchRelative(toks); ok && n == len(toks) {
+		return rel.apply(now), nil
+	}
+	var day *dayRef
+	var clk *clock
+	for i := 0; i < len(toks); {
+		if day == nil {
+			if d, n, ok := matchDay(toks[i:], now); ok {
+				day = &d
+				i += n
+				continue
+			}
+		}
+		if clk == nil {
+			if c, n, ok := matchClock(toks[i:]); ok {
+				clk = &c
+				i += n
+				continue
+			}
+		}
+		return time.Time{}, errNoDate
+	}
+	if clk != nil && !clk.explicit && day == nil {
+		return time.Time{}, errNoDate
+	}
+	hour, minute := defaultHour, 0
+	if clk != nil {
+		hour, minute = clk.hour, clk.minute
+	}
+	if day == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if !at.After(now) {
+			at = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, loc)
+		}
+		return at, nil
+	}
+	at := time.Date(day.date.Year(), day.date.Month(), day.date.Day(), hour, minute, 0, 0, loc)
+	if day.weekday && !at.After(now) {
+		at = time.Date(day.date.Year(), day.date.Month(), day.date.Day()+7, hour, minute, 0, 0, loc)
+	}
+	return at, nil
+}
+
+// splitTrailingDate looks for the longest date expression at the end of text and
+// returns the remaining text and the parsed time (nil if there is none).
+func splitTrailingDate(text string, now time.Time, loc *time.Location) (string, *time.Time) {
+	fields := strings.Fields(text)
+	for k := min(len(fields)-1, maxDateTokens); k >= 1; k-- {
+		at, err := parseWhen(strings.Join(fields[len(fields)-k:], " "), now, loc)
+		if err != nil {
+			continue
+		}
+		return strings.Join(fields[:len(fields)-k], " "), &at
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func tokenize(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	fields := strings.Fields(s)
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimRight(f, ",!?")
+		if f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+type relative struct {
+	n    int
+	unit string // "m", "h", "d", "w"
+}
+
+func (r relative) apply(now time.Time) time.Time {
+	switch r.unit {
+	case "m":
+		return now.Add(time.Duration(r.n) * time.Minute)
+	case "h":
+		return now.Add(time.Duration(r.n) * time.Hour)
+	case "d":
+		return now.AddDate(0, 0, r.n)
+	default:
+		return now.AddDate(0, 0, 7*r.n)
+	}
+}
+
+var relativeUnits = map[string]string{
+	"m": "m", "min": "m", "mins": "m", "minute": "m", "minutes": "m",
+	"м": "m", "мин": "m", "минуту": "m", "минуты": "m", "минут": "m",
+	"h": "h", "hr": "h", "hrs": "h", "hour": "h", "hours": "h",
+	"ч": "h", "час": "h", "часа": "h", "часов": "h",
+	"d": "d", "day": "d", "days": "d",
+	"д": "d", "день": "d", "дня": "d", "дней": "d",
+	"w": "w", "week": "w", "weeks": "w",
+	"нед": "w", "неделю": "w", "недели": "w", "недель": "w",
+}
+
+// matchRelative matches "через 2 часа", "через час", "in 30m", "in an hour", "через полчаса".
+func matchRelative(toks []string) (relative, int, bool) {
+	if len(toks) < 2 || (toks[0] != "через" && toks[0] != "in") {
+		return relative{}, 0, false
+	}
+	if toks[1] == "полчаса" {
+		return relative{n: 30, unit: "m"}, 2, true
+	}
+	if unit, ok := relativeUnits[toks[1]]; ok && len(toks[1]) > 2 {
+		return relative{n: 1, unit: unit}, 2, true
+	}
+	if n, unit, ok := splitNumberUnit(toks[1]); ok {
+		return relative{n: n, unit: unit}, 2, true
+	}
+	if len(toks) < 3 {
+		return relative{}, 0, false
+	}
+	n, err := strconv.Atoi(toks[1])
+	if toks[1] == "a" || toks[1] == "an" || toks[1] == "one" {
+		n, err = 1, nil
+	}
+	if err != nil || n <= 0 {
+		return relative{}, 0, false
+	}
+	unit, ok := relativeUnits[toks[2]]
+	if !ok {
+		return relative{}, 0, false
+	}
+	return relative{n: n, unit: unit}, 3, true
+}
+
+// splitNumberUnit splits compact forms like "30m", "2h", "15мин".
+func splitNumberUnit(tok string) (int, string, bool) {
+	i := 0
+	for i < len(tok) && tok[i] >= '0' && tok[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(tok) {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(tok[:i])
+	if err != nil || n <= 0 {
+		return 0, "", false
+	}
+	unit, ok := relativeUnits[tok[i:]]
+	return n, unit, ok
+}
+
+type dayRef struct {
+	date time.Time
+	// weekday marks a bare weekday that may roll over to next week if the time has passed.
+	weekday bool
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"понедельник": time.Monday, "пн": time.Monday,
+	"вторник": time.Tuesday, "вт": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday,
+	"четверг": time.Thursday, "чт": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday,
+	"воскресенье": time.Sunday, "вс": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующую": true, "следующее": true, "следующая": true,
+}
+
+func matchDay(toks []string, now time.Time) (dayRef, int, bool) {
+	i := 0
+	if toks[i] == "в" || toks[i] == "во" || toks[i] == "on" {
+		i++
+	}
+	next := false
+	if i < len(toks) && nextWords[toks[i]] {
+		next = true
+		i++
+	}
+	if i >= len(toks) {
+		return dayRef{}, 0, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tok := toks[i]
+	if wd, ok := weekdayNames[tok]; ok {
+		diff := (int(wd) - int(now.Weekday()) + 7) % 7
+		if next && diff == 0 {
+			diff = 7
+		}
+		return dayRef{date: today.AddDate(0, 0, diff), weekday: !next && diff == 0}, i + 1, true
+	}
+	if next || i > 0 {
+		return dayRef{}, 0, false
+	}
+	switch tok {
+	case "сегодня", "today":
+		return dayRef{date: today}, 1, true
+	case "завтра", "tomorrow":
+		return dayRef{date: today.AddDate(0, 0, 1)}, 1, true
+	case "послезавтра":
+		return dayRef{date: today.AddDate(0, 0, 2)}, 1, true
+	}
+	if d, ok := parseDate(tok, now); ok {
+		return dayRef{date: d}, 1, true
+	}
+	return dayRef{}, 0, false
+}
+
+// parseDate accepts YYYY-MM-DD, DD.MM.YYYY and DD.MM (the nearest such day not in the past).
+func parseDate(tok string, now time.Time) (time.Time, bool) {
+	loc := now.Location()
+	if looksLikeDate(tok) {
+		d, err := time.ParseInLocation("2006-01-02", tok, loc)
+		return d, err == nil
+	}
+	if d, err := time.ParseInLocation("02.01.2006", tok, loc); err == nil {
+		return d, true
+	}
+	for _, layout := range []string{"02.01", "2.1", "02.1", "2.01"} {
+		d, err := time.ParseInLocation(layout, tok, loc)
+		if err != nil {
+			continue
+		}
+		d = time.Date(now.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+		if d.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)) {
+			d = d.AddDate(1, 0, 0)
+		}
+		return d, true
+	}
+	return time.Time{}, false
+}
+
+type clock struct {
+	hour, minute int
+	// explicit is false for a bare number like "9" that needs a day to be read as a time.
+	explicit bool
+}
+
+func matchClock(toks []string) (clock, int, bool) {
+	i := 0
+	prep := false
+	if toks[i] == "в" || toks[i] == "at" || toks[i] == "к" {
+		prep = true
+		i++
+	}
+	if i >= len(toks) {
+		return clock{}, 0, false
+	}
+	switch toks[i] {
+	case "noon", "полдень":
+		return clock{hour: 12, explicit: true}, i + 1, true
+	case "midnight", "полночь":
+		return clock{hour: 0, explicit: true}, i + 1, true
+	}
+	tok := toks[i]
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(tok, s) {
+			tok, suffix = strings.TrimSuffix(tok, s), s
+		}
+	}
+	hour, minute, colon, ok := parseHourMinute(tok)
+	if !ok {
+		return clock{}, 0, false
+	}
+	i++
+	if suffix == "" && i < len(toks) {
+		switch toks[i] {
+		case "am", "pm", "утра", "дня", "вечера", "ночи":
+			suffix = toks[i]
+			i++
+		}
+	}
+	switch suffix {
+	case "am", "ночи":
+		if hour > 12 {
+			return clock{}, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "утра":
+		if hour > 12 {
+			return clock{}, 0, false
+		}
+	case "pm", "дня", "вечера":
+		if hour > 12 {
+			return clock{}, 0, false
+		}
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	return clock{hour: hour, minute: minute, explicit: prep || colon || suffix != ""}, i, true
+}
+
+func parseHourMinute(tok string) (int, int, bool, bool) {
+	hs, ms, colon := strings.Cut(tok, ":")
+	if !colon {
+		hs, ms, colon = strings.Cut(tok, ".")
+	}
+	if hs == "" || len(hs) > 2 {
+		return 0, 0, false, false
+	}
+	hour, err := strconv.Atoi(hs)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, false, false
+	}
+	minute := 0
+	if colon {
+		if len(ms) != 2 {
+			return 0, 0, false, false
+		}
+		minute, err = strconv.Atoi(ms)
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, 0, false, false
+		}
+	}
+	return hour, minute, colon, true
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseWhen(t *testing.T) {
+	moscow := mustLoad(t, "Europe/Moscow")
+	// Wednesday, 2026-01-07 14:20 in Moscow.
+	now := time.Date(2026, 1, 7, 14, 20, 0, 0, moscow)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"завтра в 9", time.Date(2026, 1, 8, 9, 0, 0, 0, moscow)},
+		{"завтра 9:30", time.Date(2026, 1, 8, 9, 30, 0, 0, moscow)},
+		{"завтра", time.Date(2026, 1, 8, 9, 0, 0, 0, moscow)},
+		{"послезавтра в 9 вечера", time.Date(2026, 1, 9, 21, 0, 0, 0, moscow)},
+		{"через 2 часа", now.Add(2 * time.Hour)},
+		{"через час", now.Add(time.Hour)},
+		{"через полчаса", now.Add(30 * time.Minute)},
+		{"через 3 дня", time.Date(2026, 1, 10, 14, 20, 0, 0, moscow)},
+		{"в пятницу 18:00", time.Date(2026, 1, 9, 18, 0, 0, 0, moscow)},
+		{"в среду в 18", time.Date(2026, 1, 7, 18, 0, 0, 0, moscow)},
+		{"в среду в 10", time.Date(2026, 1, 14, 10, 0, 0, 0, moscow)},
+		{"в 9", time.Date(2026, 1, 8, 9, 0, 0, 0, moscow)},
+		{"в 18:00", time.Date(2026, 1, 7, 18, 0, 0, 0, moscow)},
+		{"tomorrow 9am", time.Date(2026, 1, 8, 9, 0, 0, 0, moscow)},
+		{"today at 6pm", time.Date(2026, 1, 7, 18, 0, 0, 0, moscow)},
+		{"in 30m", now.Add(30 * time.Minute)},
+		{"in an hour", now.Add(time.Hour)},
+		{"in 2 weeks", time.Date(2026, 1, 21, 14, 20, 0, 0, moscow)},
+		{"next monday", time.Date(2026, 1, 12, 9, 0, 0, 0, moscow)},
+		{"next wednesday", time.Date(2026, 1, 14, 9, 0, 0, 0, moscow)},
+		{"on fri 12am", time.Date(2026, 1, 9, 0, 0, 0, 0, moscow)},
+		{"2026-01-02 10:00", time.Date(2026, 1, 2, 10, 0, 0, 0, moscow)},
+		{"10.01 8:15", time.Date(2026, 1, 10, 8, 15, 0, 0, moscow)},
+		{"05.01", time.Date(2027, 1, 5, 9, 0, 0, 0, moscow)},
+	}
+	for _, tt := range tests {
+		got, err := parseWhen(tt.in, now, moscow)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestParseWhen_RejectsAmbiguous(t *testing.T) {
+	now := time.Date(2026, 1, 7, 14, 20, 0, 0, time.UTC)
+	for _, in := range []string{"", "9", "в магазин", "купить 2 молока", "через", "in 30 parrots", "в 25:00", "13pm", "next"} {
+		if got, err := parseWhen(in, now, time.UTC); err == nil {
+			t.Errorf("%q: expected error, got %v", in, got)
+		}
+	}
+}
+
+func TestParseWhen_DST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	// Clocks go forward on 2026-03-29 at 02:00 and back on 2026-10-25 at 03:00.
+	beforeSpring := time.Date(2026, 3, 28, 20, 0, 0, 0, berlin)
+
+	got, err := parseWhen("завтра в 9", beforeSpring, berlin)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got.Format("15:04 -07:00") != "09:00 +02:00" {
+		t.Fatalf("expected 09:00 CEST, got %v", got)
+	}
+
+	got, err = parseWhen("через 1 день", beforeSpring, berlin)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got.Format("2006-01-02 15:04") != "2026-03-29 20:00" || got.Sub(beforeSpring) != 23*time.Hour {
+		t.Fatalf("expected same wall clock next day (23h later), got %v", got)
+	}
+
+	got, err = parseWhen("через 12 часов", beforeSpring, berlin)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got.Format("2006-01-02 15:04") != "2026-03-29 09:00" {
+		t.Fatalf("expected absolute 12h to land at 09:00 local, got %v", got)
+	}
+
+	beforeFall := time.Date(2026, 10, 24, 22, 0, 0, 0, berlin)
+	got, err = parseWhen("tomorrow 9am", beforeFall, berlin)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got.Format("2006-01-02 15:04 -07:00") != "2026-10-25 09:00 +01:00" {
+		t.Fatalf("expected 09:00 CET after fall back, got %v", got)
+	}
+}
+
+func TestParseAddArgs_SplitsTrailingDate(t *testing.T) {
+	now := time.Date(2026, 1, 7, 14, 20, 0, 0, time.UTC)
+	tests := []struct {
+		in       string
+		wantText string
+		wantDue  bool
+	}{
+		{"позвонить маме завтра в 9", "позвонить маме", true},
+		{"call mom in 30m", "call mom", true},
+		{"сходить в магазин", "сходить в магазин", false},
+		{"купить 2 молока", "купить 2 молока", false},
+		{"встреча в пятницу 18:00", "встреча", true},
+		{"report 2026-01-09 10:00", "report", true},
+	}
+	for _, tt := range tests {
+		text, due, err := parseAddArgs(tt.in, now, "UTC")
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.in, err)
+			continue
+		}
+		if text != tt.wantText || (due != nil) != tt.wantDue {
+			t.Errorf("%q: got text %q due %v", tt.in, text, due)
+		}
+	}
+	if _, _, err := parseAddArgs("   ", now, "UTC"); err == nil {
+		t.Errorf("expected error for empty args")
+	}
+}
@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+	"example.com/yourapp/internal/usecase"
+)
+
+// handleBlockCommand handles "/block <id> <blocker id>" and, with unblock set, its
+// reverse.
+func (b *Bot) handleBlockCommand(ctx context.Context, msg *Message, user domain.User, args, tz string, unblock bool) error {
+	taskID, blockedByID, err := parseTwoIDs(args)
+	if err != nil {
+		if unblock {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /unblock <id> <id>, например /unblock 5 3")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /block <id> <от чего зависит>, например /block 5 3 — #5 ждёт #3")
+	}
+	if err := b.ensureTaskOwner(taskID, user.ID, tz); err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
+	}
+	if unblock {
+		if err := b.taskService.Unblock(taskID, blockedByID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("#%d и так не ждёт #%d.", taskID, blockedByID))
+			}
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог убрать зависимость.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, #%d больше не ждёт #%d.", taskID, blockedByID))
+	}
+	if err := b.taskService.Block(taskID, blockedByID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Задача не найдена.")
+		case errors.Is(err, usecase.ErrDependencyCycle):
+			return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Так получится цикл: #%d уже (через другие задачи) ждёт #%d.", blockedByID, taskID))
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог добавить зависимость.")
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, #%d ждёт #%d: пока #%d не готова, напоминаний по #%d не будет.", taskID, blockedByID, blockedByID, taskID))
+}
+
+// parseTwoIDs reads "<id> <id>".
+func parseTwoIDs(args string) (int64, int64, error) {
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		return 0, 0, errors.New("invalid")
+	}
+	var ids [2]int64
+	for i, f := range fields {
+		id, err := strconv.ParseInt(strings.TrimPrefix(f, "#"), 10, 64)
+		if err != nil || id <= 0 {
+			return 0, 0, errors.New("id")
+		}
+		ids[i] = id
+	}
+	return ids[0], ids[1], nil
+}
@@ -0,0 +1,14 @@
+package telegram
+
+import "testing"
+
+func TestParseTwoIDs(t *testing.T) {
+	if a, b, err := parseTwoIDs(" 5  #3 "); err != nil || a != 5 || b != 3 {
+		t.Fatalf("got %d %d %v", a, b, err)
+	}
+	for _, in := range []string{"5", "5 3 1", "5 x", "0 3"} {
+		if _, _, err := parseTwoIDs(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/repository"
+	"example.com/yourapp/internal/usecase"
+)
+
+// Notifier polls for due reminders and delivers them to the task owner's chat.
+type Notifier struct {
+	client      *Client
+	taskService *usecase.TaskService
+	users       repository.UserRepository
+	interval    time.Duration
+	now         func() time.Time
+}
+
+func NewNotifier(token string, taskService *usecase.TaskService, users repository.UserRepository, interval time.Duration) *Notifier {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Notifier{
+		client:      NewClient(token),
+		taskService: taskService,
+		users:       users,
+		interval:    interval,
+		now:         time.Now,
+	}
+}
+
+func (n *Notifier) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		n.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Notifier) dispatch(ctx context.Context) {
+	items, err := n.taskService.ListDueForNotify(n.now())
+	if err != nil {
+		log.Printf("reminder list error: %v", err)
+		return
+	}
+	for _, task := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := n.notify(ctx, task); err != nil {
+			log.Printf("reminder for task #%d error (attempt %d): %v", task.ID, task.ReminderAttempts, err)
+			if err := n.taskService.MarkNotifyFailed(task, err); err != nil {
+				log.Printf("reminder for task #%d mark failed error: %v", task.ID, err)
+			}
+			continue
+		}
+		if err := n.taskService.MarkNotified(task.ID); err != nil {
+			log.Printf("reminder for task #%d mark notified error: %v", task.ID, err)
+		}
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, task domain.Task) error {
+	user, err := n.users.GetUser(task.UserID)
+	if err != nil {
+		return err
+	}
+	loc, err := usecase.LocationFromTZ(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return n.client.SendMessageWithMarkup(ctx, user.ChatID, formatReminder(task, loc), reminderKeyboard(task.ID))
+}
+
+func formatReminder(task domain.Task, loc *time.Location) string {
+	text := fmt.Sprintf("Напоминание: #%d %s", task.ID, task.Text)
+	if task.DueAt != nil {
+		due := task.DueAt.In(loc)
+		text += "\nСрок: " + formatTime(&due)
+	}
+	return text
+}
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage/memory"
+	"example.com/yourapp/internal/usecase"
+)
+
+func newTestNotifier(t *testing.T) (*Notifier, *fakeAPI, *memory.Store, domain.User) {
+	t.Helper()
+	store := memory.New()
+	user, err := store.CreateUser(domain.User{TelegramUserID: 1, ChatID: 100, Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	client, api := newFakeClient(t)
+	n := NewNotifier("token", usecase.NewTaskService(store), store, time.Hour)
+	n.client = client
+	return n, api, store, user
+}
+
+func dueTask(t *testing.T, store *memory.Store, userID int64, text string) domain.Task {
+	t.Helper()
+	at := time.Now().Add(-time.Minute).UTC()
+	task, err := store.CreateTask(domain.Task{UserID: userID, Text: text, Status: domain.TaskStatusActive, RemindAt: &at})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+func TestNotifierDelivers(t *testing.T) {
+	n, api, store, user := newTestNotifier(t)
+	task := dueTask(t, store, user.ID, "позвонить")
+
+	n.dispatch(context.Background())
+
+	sent := api.sent("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(sent))
+	}
+	if chat, _ := sent[0].Payload["chat_id"].(float64); int64(chat) != user.ChatID {
+		t.Fatalf("expected chat %d, got %v", user.ChatID, sent[0].Payload["chat_id"])
+	}
+	if text, _ := sent[0].Payload["text"].(string); !strings.Contains(text, "позвонить") {
+		t.Fatalf("expected the task text in the reminder, got %q", text)
+	}
+	got, _ := store.GetTask(task.ID)
+	if got.ReminderStatus != domain.ReminderStatusDelivered || got.NotifiedAt == nil {
+		t.Fatalf("expected delivered, got %q notified_at=%v", got.ReminderStatus, got.NotifiedAt)
+	}
+
+	n.dispatch(context.Background())
+	if len(api.sent("sendMessage")) != 1 {
+		t.Fatal("expected a delivered reminder not to be sent again")
+	}
+}
+
+func TestNotifierSchedulesRetryOnFailure(t *testing.T) {
+	n, api, store, user := newTestNotifier(t)
+	task := dueTask(t, store, user.ID, "task")
+	api.setFail("sendMessage", true)
+
+	n.dispatch(context.Background())
+
+	got, _ := store.GetTask(task.ID)
+	if got.ReminderStatus != domain.ReminderStatusPending || got.ReminderAttempts != 1 {
+		t.Fatalf("expected pending after 1 attempt, got %q attempts=%d", got.ReminderStatus, got.ReminderAttempts)
+	}
+	if got.ReminderNextAt == nil || !got.ReminderNextAt.After(time.Now()) {
+		t.Fatalf("expected a retry in the future, got %v", got.ReminderNextAt)
+	}
+	if !strings.Contains(got.ReminderError, "chat not found") {
+		t.Fatalf("expected the API error recorded, got %q", got.ReminderError)
+	}
+
+	// The retry waits for its backoff.
+	n.dispatch(context.Background())
+	if len(api.sent("sendMessage")) != 1 {
+		t.Fatal("expected no resend before the backoff")
+	}
+}
+
+func TestNotifierDeadLettersAfterMaxAttempts(t *testing.T) {
+	n, api, store, user := newTestNotifier(t)
+	task := dueTask(t, store, user.ID, "task")
+	api.setFail("sendMessage", true)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		// Each round is an hour later, past any backoff.
+		at := start.Add(time.Duration(i) * time.Hour)
+		n.now = func() time.Time { return at }
+		n.dispatch(context.Background())
+	}
+	got, _ := store.GetTask(task.ID)
+	if got.ReminderStatus != domain.ReminderStatusFailed || got.ReminderAttempts != 5 {
+		t.Fatalf("expected failed after 5 attempts, got %q attempts=%d", got.ReminderStatus, got.ReminderAttempts)
+	}
+	if len(api.sent("sendMessage")) != 5 {
+		t.Fatalf("expected 5 sends, got %d", len(api.sent("sendMessage")))
+	}
+	failed, err := n.taskService.ListReminders(domain.ReminderStatusFailed, "UTC")
+	if err != nil || len(failed) != 1 {
+		t.Fatalf("expected the reminder in the dead-letter list, got %v %v", failed, err)
+	}
+}
+
+func TestNotifierRunStopsOnCancel(t *testing.T) {
+	n, _, _, _ := newTestNotifier(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- n.Run(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
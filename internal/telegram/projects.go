@@ -0,0 +1,110 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+func (b *Bot) handleProjectsCommand(ctx context.Context, msg *Message, user domain.User) error {
+	projects, err := b.projects.ListProjects(user.ID, false)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог получить проекты.")
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, formatProjects(projects, user.CurrentProjectID))
+}
+
+// handleProjectCommand shows or switches the current project. An unknown name creates
+// the project; archived projects can't be switched to from the bot.
+func (b *Bot) handleProjectCommand(ctx context.Context, msg *Message, user domain.User, args string) error {
+	args = strings.TrimSpace(args)
+	switch {
+	case args == "":
+		if user.CurrentProjectID == nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Сейчас задачи попадают во входящие. Сменить: /project <название>")
+		}
+		project, err := b.projects.GetProject(*user.CurrentProjectID)
+		if err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог получить текущий проект.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Текущий проект: %s. Во входящие: /project off", project.Name))
+	case strings.EqualFold(args, "off"):
+		if _, err := b.users.SetCurrentProject(user.ID, nil); err != nil {
+			return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог переключить проект.")
+		}
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Ок, новые задачи снова попадают во входящие.")
+	}
+
+	name, err := domain.NormalizeProjectName(args)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Название проекта — одна строка до %d символов.", domain.MaxProjectNameLength))
+	}
+	project, created, err := b.findOrCreateProject(user.ID, name)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог найти или создать проект.")
+	}
+	if project.Archived {
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Проект %s в архиве.", project.Name))
+	}
+	if _, err := b.users.SetCurrentProject(user.ID, &project.ID); err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог переключить проект.")
+	}
+	if created {
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Создал проект %s и переключился на него.", project.Name))
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Текущий проект: %s.", project.Name))
+}
+
+func (b *Bot) findOrCreateProject(userID int64, name string) (domain.Project, bool, error) {
+	projects, err := b.projects.ListProjects(userID, true)
+	if err != nil {
+		return domain.Project{}, false, err
+	}
+	if p, ok := findProject(projects, name); ok {
+		return p, false, nil
+	}
+	project, err := b.projects.CreateProject(domain.Project{UserID: userID, Name: name})
+	if errors.Is(err, storage.ErrConflict) {
+		// Created concurrently, or the name differs from an existing one only in case.
+		projects, err = b.projects.ListProjects(userID, true)
+		if err != nil {
+			return domain.Project{}, false, err
+		}
+		if p, ok := findProject(projects, name); ok {
+			return p, false, nil
+		}
+		return domain.Project{}, false, storage.ErrConflict
+	}
+	return project, err == nil, err
+}
+
+// findProject looks a project up by name, ignoring case.
+func findProject(projects []domain.Project, name string) (domain.Project, bool) {
+	for _, p := range projects {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return domain.Project{}, false
+}
+
+func formatProjects(projects []domain.Project, current *int64) string {
+	if len(projects) == 0 {
+		return "Проектов пока нет. Создать и переключиться: /project <название>"
+	}
+	var b strings.Builder
+	b.WriteString("Проекты:")
+	for _, p := range projects {
+		marker := "  "
+		if current != nil && *current == p.ID {
+			marker = "→ "
+		}
+		b.WriteString("\n" + marker + p.Name)
+	}
+	b.WriteString("\nПереключиться: /project <название>, во входящие: /project off")
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
+)
+
+// maxFindResults keeps a /find reply readable; the rest is one refinement away.
+const maxFindResults = 10
+
+func (b *Bot) handleFindCommand(ctx context.Context, msg *Message, user domain.User, args, tz string) error {
+	if len(domain.SearchTerms(args)) == 0 {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Формат: /find <слова>, например /find молоко")
+	}
+	results, err := b.taskService.Search(user.ID, args, storage.TaskFilter{}, tz)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не смог поискать задачи.")
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, formatSearchResults(results))
+}
+
+// formatSearchResults lists the best matches with the matched words in «»; done tasks
+// are marked ✓.
+func formatSearchResults(results []domain.SearchResult) string {
+	if len(results) == 0 {
+		return "Ничего не нашёл."
+	}
+	marks := strings.NewReplacer(domain.HighlightStart, "«", domain.HighlightEnd, "»")
+	lines := []string{"Нашёл:"}
+	for i, r := range results {
+		if i == maxFindResults {
+			lines = append(lines, fmt.Sprintf("…и ещё %d. Уточни запрос.", len(results)-maxFindResults))
+			break
+		}
+		line := fmt.Sprintf("%d) %s", r.ID, marks.Replace(r.Highlight))
+		if r.Status == domain.TaskStatusDone {
+			line += " ✓"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
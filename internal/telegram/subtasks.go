@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/usecase"
+)
+
+type listEntry struct {
+	task domain.Task
+	// nested is set for a subtask listed right under its parent.
+	nested bool
+}
+
+// nestSubtasks moves subtasks under their parent, keeping the order otherwise. A subtask
+// whose parent is not in items stays where it is.
+func nestSubtasks(items []domain.Task) []listEntry {
+	listed := make(map[int64]bool, len(items))
+	for _, t := range items {
+		listed[t.ID] = true
+	}
+	children := make(map[int64][]domain.Task)
+	for _, t := range items {
+		if t.ParentID != nil && listed[*t.ParentID] {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+		}
+	}
+	out := make([]listEntry, 0, len(items))
+	for _, t := range items {
+		if t.ParentID != nil && listed[*t.ParentID] {
+			continue
+		}
+		out = append(out, listEntry{task: t})
+		for _, c := range children[t.ID] {
+			out = append(out, listEntry{task: c, nested: true})
+		}
+	}
+	return out
+}
+
+// splitTaskID takes the leading task id off args: "5 buy a cake" is 5 and "buy a cake".
+func splitTaskID(args string) (int64, string, error) {
+	idPart, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", errors.New("id")
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return 0, "", errors.New("text")
+	}
+	return id, rest, nil
+}
+
+// parseDoneArgs reads "<id> [all]"; "all" (or "все") closes open subtasks too.
+func parseDoneArgs(args string) (int64, usecase.SubtaskPolicy, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, 0, errors.New("invalid")
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, 0, errors.New("id")
+	}
+	if len(fields) == 1 {
+		return id, usecase.RequireSubtasks, nil
+	}
+	switch strings.ToLower(fields[1]) {
+	case "all", "все":
+		return id, usecase.CascadeSubtasks, nil
+	}
+	return 0, 0, errors.New("invalid")
+}
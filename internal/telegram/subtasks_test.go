@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/usecase"
+)
+
+func TestParseDoneArgs(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantID     int64
+		wantPolicy usecase.SubtaskPolicy
+		wantErr    bool
+	}{
+		{"5", 5, usecase.RequireSubtasks, false},
+		{"5 all", 5, usecase.CascadeSubtasks, false},
+		{" 7  Все ", 7, usecase.CascadeSubtasks, false},
+		{"5 now", 0, 0, true},
+		{"", 0, 0, true},
+		{"-1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		id, policy, err := parseDoneArgs(tt.in)
+		if (err != nil) != tt.wantErr || id != tt.wantID || policy != tt.wantPolicy {
+			t.Errorf("%q: got %d %v %v", tt.in, id, policy, err)
+		}
+	}
+}
+
+func TestNestSubtasks(t *testing.T) {
+	parent := int64(1)
+	missing := int64(9)
+	items := []domain.Task{
+		{ID: 2, ParentID: &parent},
+		{ID: 3},
+		{ID: 1},
+		{ID: 4, ParentID: &missing},
+	}
+	var got []string
+	for _, e := range nestSubtasks(items) {
+		got = append(got, fmt.Sprintf("%d:%v", e.task.ID, e.nested))
+	}
+	if want := "3:false,1:false,2:true,4:false"; strings.Join(got, ",") != want {
+		t.Fatalf("got %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"example.com/yourapp/internal/domain"
+)
+
+const callbackTimezone = "tz"
+
+// maxCityDistanceKm limits how far a shared location may be from a known city for its
+// zone to be suggested; beyond that the suggestion falls back to a longitude offset.
+const maxCityDistanceKm = 800
+
+type zoneCity struct {
+	lat, lon float64
+	zone     string
+}
+
+var zoneCities = []zoneCity{
+	{55.76, 37.62, "Europe/Moscow"},
+	{59.94, 30.31, "Europe/Moscow"},
+	{54.71, 20.51, "Europe/Kaliningrad"},
+	{53.20, 50.15, "Europe/Samara"},
+	{56.84, 60.61, "Asia/Yekaterinburg"},
+	{54.99, 73.37, "Asia/Omsk"},
+	{55.03, 82.92, "Asia/Novosibirsk"},
+	{56.01, 92.87, "Asia/Krasnoyarsk"},
+	{52.29, 104.28, "Asia/Irkutsk"},
+	{62.03, 129.73, "Asia/Yakutsk"},
+	{43.12, 131.89, "Asia/Vladivostok"},
+	{59.56, 150.80, "Asia/Magadan"},
+	{53.02, 158.65, "Asia/Kamchatka"},
+	{50.45, 30.52, "Europe/Kyiv"},
+	{53.90, 27.56, "Europe/Minsk"},
+	{43.24, 76.89, "Asia/Almaty"},
+	{41.30, 69.24, "Asia/Tashkent"},
+	{41.72, 44.79, "Asia/Tbilisi"},
+	{40.18, 44.51, "Asia/Yerevan"},
+	{40.41, 49.87, "Asia/Baku"},
+	{41.01, 28.98, "Europe/Istanbul"},
+	{51.51, -0.13, "Europe/London"},
+	{48.86, 2.35, "Europe/Paris"},
+	{52.37, 4.90, "Europe/Amsterdam"},
+	{52.52, 13.40, "Europe/Berlin"},
+	{52.23, 21.01, "Europe/Warsaw"},
+	{41.90, 12.50, "Europe/Rome"},
+	{40.42, -3.70, "Europe/Madrid"},
+	{38.72, -9.14, "Europe/Lisbon"},
+	{60.17, 24.94, "Europe/Helsinki"},
+	{37.98, 23.73, "Europe/Athens"},
+	{32.09, 34.78, "Asia/Jerusalem"},
+	{25.20, 55.27, "Asia/Dubai"},
+	{28.61, 77.21, "Asia/Kolkata"},
+	{13.76, 100.50, "Asia/Bangkok"},
+	{1.35, 103.82, "Asia/Singapore"},
+	{39.90, 116.41, "Asia/Shanghai"},
+	{35.68, 139.69, "Asia/Tokyo"},
+	{-33.87, 151.21, "Australia/Sydney"},
+	{40.71, -74.01, "America/New_York"},
+	{41.88, -87.63, "America/Chicago"},
+	{39.74, -104.99, "America/Denver"},
+	{34.05, -118.24, "America/Los_Angeles"},
+	{-23.55, -46.63, "America/Sao_Paulo"},
+}
+
+// suggestTimezone guesses a zone for a shared location: the zone of the nearest known
+// city, or a fixed offset derived from the longitude when no city is close enough.
+func suggestTimezone(lat, lon float64) string {
+	best, bestDist := "", math.MaxFloat64
+	for _, c := range zoneCities {
+		if d := distanceKm(lat, lon, c.lat, c.lon); d < bestDist {
+			best, bestDist = c.zone, d
+		}
+	}
+	if bestDist <= maxCityDistanceKm {
+		return best
+	}
+	hours := int(math.Round(lon / 15))
+	sign := '+'
+	if hours < 0 {
+		sign, hours = '-', -hours
+	}
+	return fmt.Sprintf("%c%02d:00", sign, hours)
+}
+
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func (b *Bot) handleTimezoneCommand(ctx context.Context, msg *Message, user domain.User, args string) error {
+	if args == "" {
+		return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf(
+			"Твой часовой пояс: %s.\nПоменять: /tz <Europe/Moscow | +03:00> или пришли геопозицию.", user.Timezone))
+	}
+	updated, err := b.users.SetTimezone(user.ID, args)
+	if err != nil {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Не знаю такой пояс. Пример: /tz Europe/Moscow или /tz +03:00")
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("Ок, часовой пояс: %s.", updated.Timezone))
+}
+
+func (b *Bot) handleLocation(ctx context.Context, msg *Message) error {
+	if msg.From == nil || msg.Location == nil {
+		return nil
+	}
+	if _, err := b.ensureUser(msg.From, msg.Chat.ID); err != nil {
+		_ = b.client.SendMessage(ctx, msg.Chat.ID, "Что-то пошло не так, попробуй ещё раз.")
+		return err
+	}
+	zone := suggestTimezone(msg.Location.Latitude, msg.Location.Longitude)
+	markup := &InlineKeyboardMarkup{
+		InlineKeyboard: [][]InlineKeyboardButton{
+			{{Text: "Поставить " + zone, CallbackData: callbackTimezone + ":" + zone}},
+		},
+	}
+	return b.client.SendMessageWithMarkup(ctx, msg.Chat.ID,
+		fmt.Sprintf("Похоже, твой часовой пояс — %s.", zone), markup)
+}
+
+func (b *Bot) handleTimezoneCallback(ctx context.Context, cq *CallbackQuery, zone string) error {
+	user, err := b.ensureUser(cq.From, cq.Message.Chat.ID)
+	if err != nil {
+		_ = b.client.AnswerCallbackQuery(ctx, cq.ID, "Что-то пошло не так, попробуй ещё раз.")
+		return err
+	}
+	updated, err := b.users.SetTimezone(user.ID, zone)
+	if err != nil {
+		return b.client.AnswerCallbackQuery(ctx, cq.ID, "Не знаю такой пояс.")
+	}
+	status := fmt.Sprintf("Ок, часовой пояс: %s.", updated.Timezone)
+	if err := b.client.AnswerCallbackQuery(ctx, cq.ID, status); err != nil {
+		return err
+	}
+	return b.client.EditMessageText(ctx, cq.Message.Chat.ID, cq.Message.MessageID, status, nil)
+}
@@ -0,0 +1,21 @@
+package telegram
+
+import "testing"
+
+func TestSuggestTimezone(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		{55.75, 37.61, "Europe/Moscow"},
+		{56.33, 44.00, "Europe/Moscow"},
+		{51.92, 4.48, "Europe/Amsterdam"},
+		{-20.0, -140.0, "-09:00"},
+		{0.0, 7.4, "+00:00"},
+	}
+	for _, tt := range tests {
+		if got := suggestTimezone(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("(%v, %v): expected %s, got %s", tt.lat, tt.lon, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"example.com/yourapp/internal/repository"
+)
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// SetWebhook registers webhookURL with Telegram so updates are pushed instead of polled.
+func (b *Bot) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	return b.client.SetWebhook(ctx, webhookURL, secret)
+}
+
+// WebhookHandler serves updates pushed by Telegram. Requests without the matching secret
+// token are refused. Telegram redelivers updates that were not acknowledged in time, so
+// update ids are recorded in updates, which replicas share, and an update seen before is
+// acknowledged without being handled again.
+func (b *Bot) WebhookHandler(secret string, updates repository.UpdateRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(secretTokenHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var upd Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&upd); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fresh, err := updates.MarkUpdateSeen(int64(upd.UpdateID), time.Now())
+		if err != nil {
+			// Telegram retries the update later.
+			log.Printf("telegram webhook dedup error: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if fresh {
+			b.handleUpdate(r.Context(), upd)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/storage/memory"
+	"example.com/yourapp/internal/usecase"
+)
+
+func newTestWebhook(t *testing.T) (http.Handler, *fakeAPI) {
+	t.Helper()
+	store := memory.New()
+	client, api := newFakeClient(t)
+	auth := usecase.NewAuthService(store, store, "", "token", time.Hour)
+	bot := NewBot("token", usecase.NewTaskService(store), usecase.NewUserService(store), store, store, auth, time.Second)
+	bot.client = client
+	return bot.WebhookHandler("s3cret", store), api
+}
+
+func postUpdate(h http.Handler, secret, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(secretTokenHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+const startUpdate = `{"update_id": 7, "message": {"message_id": 1, "from": {"id": 42}, "chat": {"id": 42}, "text": "/start"}}`
+
+func TestWebhookDispatchesUpdate(t *testing.T) {
+	h, api := newTestWebhook(t)
+	if code := postUpdate(h, "s3cret", startUpdate); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	sent := api.sent("sendMessage")
+	if len(sent) != 1 || sent[0].Payload["chat_id"] != float64(42) {
+		t.Fatalf("expected a reply to chat 42, got %+v", sent)
+	}
+}
+
+func TestWebhookRejectsWrongSecret(t *testing.T) {
+	h, api := newTestWebhook(t)
+	for _, secret := range []string{"", "wrong"} {
+		if code := postUpdate(h, secret, startUpdate); code != http.StatusForbidden {
+			t.Fatalf("secret %q: expected 403, got %d", secret, code)
+		}
+	}
+	if sent := api.sent("sendMessage"); len(sent) != 0 {
+		t.Fatalf("expected the update to be dropped, got %+v", sent)
+	}
+}
+
+func TestWebhookSkipsDuplicateUpdate(t *testing.T) {
+	h, api := newTestWebhook(t)
+	for i := 0; i < 2; i++ {
+		if code := postUpdate(h, "s3cret", startUpdate); code != http.StatusOK {
+			t.Fatalf("delivery %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if sent := api.sent("sendMessage"); len(sent) != 1 {
+		t.Fatalf("expected the redelivered update to be handled once, got %d replies", len(sent))
+	}
+}
+
+func TestWebhookRejectsMalformedBody(t *testing.T) {
+	h, _ := newTestWebhook(t)
+	if code := postUpdate(h, "s3cret", "{"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", code)
+	}
+}
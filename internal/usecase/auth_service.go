@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/repository"
+	"example.com/yourapp/internal/storage"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+const tokenPrefix = "tm_"
+
+// Principal is who an authenticated request acts as: a user, or the static admin token,
+// which has no user behind it.
+type Principal struct {
+	domain.User
+	// StaticAdmin is set for the static admin token; User then only carries the role.
+	StaticAdmin bool
+}
+
+type AuthService struct {
+	tokens     repository.TokenRepository
+	users      repository.UserRepository
+	adminToken string
+	botToken   string
+	sessionTTL time.Duration
+	now        func() time.Time
+}
+
+// NewAuthService creates the service; a non-empty adminToken is accepted as a static
+// bearer token with the admin role, which is how the first admin bootstraps access.
+// botToken verifies Telegram login payloads, which are exchanged for session tokens
+// living sessionTTL.
+func NewAuthService(tokens repository.TokenRepository, users repository.UserRepository, adminToken, botToken string, sessionTTL time.Duration) *AuthService {
+	return &AuthService{
+		tokens:     tokens,
+		users:      users,
+		adminToken: adminToken,
+		botToken:   botToken,
+		sessionTTL: sessionTTL,
+		now:        time.Now,
+	}
+}
+
+// IssueToken creates a token for the user. The plaintext token is returned only here;
+// a zero ttl means the token does not expire. Expired tokens of all users are swept
+// along the way, so stale sessions don't pile up; a failed sweep is only logged, since
+// login must not depend on housekeeping.
+func (s *AuthService) IssueToken(userID int64, name string, ttl time.Duration) (string, domain.APIToken, error) {
+	if err := s.tokens.DeleteExpiredTokens(s.now().UTC()); err != nil {
+		log.Printf("expired token sweep error: %v", err)
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", domain.APIToken{}, err
+	}
+	plain := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	token := domain.APIToken{
+		UserID: userID,
+		Name:   strings.TrimSpace(name),
+		Hash:   HashToken(plain),
+	}
+	if ttl > 0 {
+		expiresAt := s.now().UTC().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	created, err := s.tokens.CreateToken(token)
+	if err != nil {
+		return "", domain.APIToken{}, err
+	}
+	return plain, created, nil
+}
+
+func (s *AuthService) Authenticate(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrUnauthorized
+	}
+	if s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+		return Principal{User: domain.User{Role: domain.RoleAdmin}, StaticAdmin: true}, nil
+	}
+	user, err := s.tokens.GetUserByTokenHash(HashToken(token), s.now().UTC())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return Principal{}, ErrUnauthorized
+		}
+		return Principal{}, err
+	}
+	return Principal{User: user}, nil
+}
+
+// LoginWidget exchanges a verified Telegram Login Widget payload for a session token.
+func (s *AuthService) LoginWidget(fields map[string]string) (string, domain.APIToken, domain.User, error) {
+	if s.botToken == "" {
+		return "", domain.APIToken{}, domain.User{}, ErrInvalidTelegramAuth
+	}
+	telegramUserID, err := VerifyLoginWidget(fields, s.botToken, s.now())
+	if err != nil {
+		return "", domain.APIToken{}, domain.User{}, err
+	}
+	return s.telegramSession(telegramUserID, "telegram-login")
+}
+
+// LoginInitData exchanges verified Mini App initData for a session token.
+func (s *AuthService) LoginInitData(initData string) (string, domain.APIToken, domain.User, error) {
+	if s.botToken == "" {
+		return "", domain.APIToken{}, domain.User{}, ErrInvalidTelegramAuth
+	}
+	telegramUserID, err := VerifyInitData(initData, s.botToken, s.now())
+	if err != nil {
+		return "", domain.APIToken{}, domain.User{}, err
+	}
+	return s.telegramSession(telegramUserID, "telegram-webapp")
+}
+
+// telegramSession maps a Telegram user to domain.User, creating it like the bot does on
+// first contact (the private chat id equals the user id), and issues a session token.
+func (s *AuthService) telegramSession(telegramUserID int64, name string) (string, domain.APIToken, domain.User, error) {
+	user, err := s.users.GetByTelegramID(telegramUserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		user, err = s.users.CreateUser(domain.User{
+			TelegramUserID: telegramUserID,
+			ChatID:         telegramUserID,
+			Timezone:       "UTC",
+		})
+		if errors.Is(err, storage.ErrConflict) {
+			user, err = s.users.GetByTelegramID(telegramUserID)
+		}
+	}
+	if err != nil {
+		return "", domain.APIToken{}, domain.User{}, err
+	}
+	plain, token, err := s.IssueToken(user.ID, name, s.sessionTTL)
+	if err != nil {
+		return "", domain.APIToken{}, domain.User{}, err
+	}
+	return plain, token, user, nil
+}
+
+func (s *AuthService) ListTokens(userID int64) ([]domain.APIToken, error) {
+	return s.tokens.ListTokens(userID)
+}
+
+func (s *AuthService) RevokeToken(userID, id int64) error {
+	return s.tokens.DeleteToken(userID, id)
+}
+
+// HashToken returns the hex SHA-256 of a token. Tokens are 256-bit random values, so a
+// fast hash is enough to make a leaked table useless.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
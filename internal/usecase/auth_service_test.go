@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage/memory"
+)
+
+func TestAuthService_IssueAndAuthenticate(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 10, ChatID: 10})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	svc := NewAuthService(repo, repo, "static-admin", "", 0)
+	svc.now = func() time.Time { return now }
+
+	plain, token, err := svc.IssueToken(user.ID, "cli", time.Hour)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if token.Hash == plain || token.Hash != HashToken(plain) {
+		t.Fatalf("expected only the hash to be stored, got %q", token.Hash)
+	}
+
+	got, err := svc.Authenticate(plain)
+	if err != nil || got.ID != user.ID || got.IsAdmin() || got.StaticAdmin {
+		t.Fatalf("expected regular user %d, got %+v %v", user.ID, got, err)
+	}
+	if _, err := svc.Authenticate(plain + "x"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized for wrong token, got %v", err)
+	}
+	if admin, err := svc.Authenticate("static-admin"); err != nil || !admin.IsAdmin() || !admin.StaticAdmin || admin.ID != 0 {
+		t.Fatalf("expected static admin token to authenticate, got %+v %v", admin, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := svc.Authenticate(plain); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthService_IssueTokenSweepsExpired(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 10, ChatID: 10})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	svc := NewAuthService(repo, repo, "", "", 0)
+	svc.now = func() time.Time { return now }
+	if _, _, err := svc.IssueToken(user.ID, "session", time.Hour); err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if _, _, err := svc.IssueToken(user.ID, "cli", 0); err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, _, err := svc.IssueToken(user.ID, "session", time.Hour); err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	tokens, err := svc.ListTokens(user.ID)
+	if err != nil || len(tokens) != 2 || tokens[0].Name != "cli" {
+		t.Fatalf("expected the expired session to be swept, got %+v %v", tokens, err)
+	}
+}
+
+// failingSweep is a token repository whose expired-token sweep always fails.
+type failingSweep struct {
+	*memory.Store
+}
+
+func (failingSweep) DeleteExpiredTokens(time.Time) error {
+	return errors.New("sweep failed")
+}
+
+func TestAuthService_IssueTokenIgnoresSweepError(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 10, ChatID: 10})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	svc := NewAuthService(failingSweep{repo}, repo, "", "", 0)
+	plain, _, err := svc.IssueToken(user.ID, "session", time.Hour)
+	if err != nil {
+		t.Fatalf("expected the token despite the failed sweep, got %v", err)
+	}
+	if got, err := svc.Authenticate(plain); err != nil || got.ID != user.ID {
+		t.Fatalf("expected the token to authenticate, got %+v %v", got, err)
+	}
+}
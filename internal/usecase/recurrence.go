@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqHourly  = "HOURLY"
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// maxRecurrenceSteps bounds catching up on missed occurrences when a recurring task
+// is completed late.
+const maxRecurrenceSteps = 10000
+
+// Recurrence is a subset of RFC 5545 RRULE: FREQ, INTERVAL, BYDAY (weekly),
+// BYMONTHDAY (monthly), UNTIL and COUNT. COUNT is the number of occurrences left,
+// including the current task. A date-only UNTIL (UntilDate) includes that whole day in
+// the user's timezone.
+type Recurrence struct {
+	Freq      string
+	Interval  int
+	Weekdays  []time.Weekday
+	MonthDay  int
+	Until     *time.Time
+	UntilDate bool
+	Count     int
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var rruleWeekdayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRecurrence parses a rule like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func ParseRecurrence(s string) (Recurrence, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	var r Recurrence
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqHourly, FreqDaily, FreqWeekly, FreqMonthly:
+				r.Freq = value
+			default:
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[code]
+				if !ok {
+					return Recurrence{}, ErrInvalidRecurrence
+				}
+				r.Weekdays = append(r.Weekdays, wd)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 31 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.MonthDay = n
+		case "UNTIL":
+			until, dateOnly, err := parseRRuleTime(value)
+			if err != nil {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Until = &until
+			r.UntilDate = dateOnly
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Count = n
+		default:
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+	}
+	if r.Freq == "" {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	if len(r.Weekdays) > 0 && r.Freq != FreqWeekly {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	if r.MonthDay > 0 && r.Freq != FreqMonthly {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	sort.Slice(r.Weekdays, func(i, j int) bool { return mondayIndex(r.Weekdays[i]) < mondayIndex(r.Weekdays[j]) })
+	return r, nil
+}
+
+// NormalizeRecurrence validates a rule and returns its canonical form; an empty rule
+// stays empty.
+func NormalizeRecurrence(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	r, err := ParseRecurrence(s)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		codes := make([]string, 0, len(r.Weekdays))
+		for _, wd := range r.Weekdays {
+			codes = append(codes, rruleWeekdayCodes[wd])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	if r.Until != nil && r.UntilDate {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	} else if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after from. Calendar frequencies keep the
+// wall-clock time of from in loc, so a daily 09:00 task stays at 09:00 across DST
+// changes; HOURLY steps are absolute durations.
+func (r Recurrence) Next(from time.Time, loc *time.Location) time.Time {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	local := from.In(loc)
+	switch r.Freq {
+	case FreqHourly:
+		return from.Add(time.Duration(interval) * time.Hour)
+	case FreqWeekly:
+		if len(r.Weekdays) == 0 {
+			return addDays(local, 7*interval)
+		}
+		cur := mondayIndex(local.Weekday())
+		for _, wd := range r.Weekdays {
+			if idx := mondayIndex(wd); idx > cur {
+				return addDays(local, idx-cur)
+			}
+		}
+		return addDays(local, 7*interval-cur+mondayIndex(r.Weekdays[0]))
+	case FreqMonthly:
+		day := r.MonthDay
+		if day == 0 {
+			day = local.Day()
+		}
+		if r.MonthDay > 0 {
+			candidate := monthDay(local, local.Year(), local.Month(), day)
+			if candidate.After(local) {
+				return candidate
+			}
+		}
+		return monthDay(local, local.Year(), local.Month()+time.Month(interval), day)
+	default:
+		return addDays(local, interval)
+	}
+}
+
+func (r Recurrence) step(from time.Time, loc *time.Location) (time.Time, bool) {
+	if r.Count == 1 {
+		return time.Time{}, false
+	}
+	next := r.Next(from, loc)
+	if r.pastUntil(next, loc) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// pastUntil reports whether t is after the end of the series.
+func (r Recurrence) pastUntil(t time.Time, loc *time.Location) bool {
+	if r.Until == nil {
+		return false
+	}
+	if !r.UntilDate {
+		return t.After(*r.Until)
+	}
+	u := *r.Until
+	end := time.Date(u.Year(), u.Month(), u.Day()+1, 0, 0, 0, 0, loc)
+	return !t.Before(end)
+}
+
+func (r Recurrence) consume() Recurrence {
+	if r.Count > 0 {
+		r.Count--
+	}
+	return r
+}
+
+// parseRRuleTime parses a UTC date-time or a date; the date comes back as midnight UTC
+// and dateOnly is set.
+func parseRRuleTime(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("rrule time %q", s)
+}
+
+func mondayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+func addDays(local time.Time, days int) time.Time {
+	return time.Date(local.Year(), local.Month(), local.Day()+days,
+		local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), local.Location())
+}
+
+// monthDay clamps day to the length of the month, so BYMONTHDAY=31 falls on the last
+// day of shorter months.
+func monthDay(local time.Time, year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, local.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day,
+		local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), local.Location())
+}
+
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	tests := []struct {
+		name string
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "daily keeps wall clock across spring DST",
+			rule: "FREQ=DAILY",
+			from: time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			want: time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+		},
+		{
+			name: "hourly is absolute across fall DST",
+			rule: "FREQ=HOURLY;INTERVAL=2",
+			from: time.Date(2026, 10, 25, 1, 30, 0, 0, berlin),
+			want: time.Date(2026, 10, 25, 1, 30, 0, 0, berlin).Add(2 * time.Hour),
+		},
+		{
+			name: "weekly picks next weekday in the same week",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TH",
+			from: time.Date(2026, 1, 5, 10, 0, 0, 0, berlin), // Monday
+			want: time.Date(2026, 1, 8, 10, 0, 0, 0, berlin),
+		},
+		{
+			name: "weekly with interval wraps to a later week",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			from: time.Date(2026, 1, 8, 10, 0, 0, 0, berlin), // Thursday
+			want: time.Date(2026, 1, 19, 10, 0, 0, 0, berlin),
+		},
+		{
+			name: "monthly clamps to the last day",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			from: time.Date(2026, 1, 31, 8, 0, 0, 0, berlin),
+			want: time.Date(2026, 2, 28, 8, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.rule, err)
+			}
+			if got := rule.Next(tt.from, berlin); !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got.In(berlin))
+			}
+		})
+	}
+}
+
+func TestParseRecurrence_Rejects(t *testing.T) {
+	for _, rule := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX", "INTERVAL=2", "FREQ=DAILY;COUNT=0"} {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Fatalf("expected error for %q", rule)
+		}
+	}
+}
+
+func TestRecurrenceUntilDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	rule, err := ParseRecurrence("FREQ=DAILY;UNTIL=20260110")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := rule.String(); got != "FREQ=DAILY;UNTIL=20260110" {
+		t.Fatalf("expected the date-only form to survive, got %q", got)
+	}
+	// 20:00 in New York on the UNTIL date is already the next day in UTC.
+	if next, ok := rule.step(time.Date(2026, 1, 9, 20, 0, 0, 0, newYork), newYork); !ok || next.In(newYork).Day() != 10 {
+		t.Fatalf("expected an occurrence on the UNTIL date, got %v %v", next, ok)
+	}
+	if next, ok := rule.step(time.Date(2026, 1, 10, 20, 0, 0, 0, newYork), newYork); ok {
+		t.Fatalf("expected the series to end after the UNTIL date, got %v", next)
+	}
+}
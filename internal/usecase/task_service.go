@@ -8,11 +8,36 @@ import (
 
 	"example.com/yourapp/internal/domain"
 	"example.com/yourapp/internal/repository"
+	"example.com/yourapp/internal/storage"
 )
 
 var (
 	ErrInvalidText     = errors.New("task text is empty")
+	ErrInvalidStatus   = errors.New("invalid task status")
 	ErrInvalidTimezone = errors.New("invalid timezone")
+	ErrInvalidPriority = errors.New("invalid priority")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectArchived = errors.New("project is archived")
+	ErrInvalidParent   = errors.New("invalid parent task")
+	ErrOpenSubtasks    = errors.New("task has open subtasks")
+	ErrDependencyCycle = errors.New("dependency cycle")
+)
+
+// SubtaskPolicy decides what completing a task with open subtasks does.
+type SubtaskPolicy int
+
+const (
+	// RequireSubtasks refuses with ErrOpenSubtasks.
+	RequireSubtasks SubtaskPolicy = iota
+	// CascadeSubtasks completes the subtasks along with the task.
+	CascadeSubtasks
+)
+
+const (
+	reminderLease       = 2 * time.Minute
+	reminderMaxAttempts = 5
+	reminderBaseBackoff = 30 * time.Second
+	reminderMaxBackoff  = 30 * time.Minute
 )
 
 type TaskService struct {
@@ -27,21 +52,87 @@ func NewTaskService(repo repository.TaskRepository) *TaskService {
 	}
 }
 
-func (s *TaskService) Create(userID int64, text string, dueAt, remindAt *time.Time, tz string) (domain.Task, error) {
-	trimmed := strings.TrimSpace(text)
+// NewTask is what Create needs to add a task.
+type NewTask struct {
+	UserID int64
+	// ProjectID is nil for the inbox; a subtask defaults to its parent's project.
+	ProjectID *int64
+	// ParentID makes the task a subtask. Subtasks are one level deep.
+	ParentID *int64
+	Text     string
+	// Priority 0 means domain.PriorityDefault.
+	Priority int
+	Tags     []string
+	DueAt    *time.Time
+	RemindAt *time.Time
+	// Status "" means active.
+	Status     string
+	Recurrence string
+	NotifiedAt *time.Time
+}
+
+// Create adds a task. The project, if any, has to be the user's and not archived; the
+// parent, if any, has to be one of the user's top-level tasks.
+func (s *TaskService) Create(in NewTask, tz string) (domain.Task, error) {
+	trimmed := strings.TrimSpace(in.Text)
 	if trimmed == "" {
 		return domain.Task{}, ErrInvalidText
 	}
+	status := in.Status
+	if status == "" {
+		status = domain.TaskStatusActive
+	}
+	if !validStatus(status) {
+		return domain.Task{}, ErrInvalidStatus
+	}
+	priority := in.Priority
+	if priority == 0 {
+		priority = domain.PriorityDefault
+	}
+	if !domain.ValidPriority(priority) {
+		return domain.Task{}, ErrInvalidPriority
+	}
+	tags, err := domain.NormalizeTags(in.Tags)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	recurrence, err := NormalizeRecurrence(in.Recurrence)
+	if err != nil {
+		return domain.Task{}, err
+	}
 	loc, err := locationFromTZ(tz)
 	if err != nil {
 		return domain.Task{}, err
 	}
+	if in.ParentID != nil {
+		parent, err := s.repo.GetByID(*in.ParentID)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && (parent.UserID != in.UserID || parent.ParentID != nil)) {
+			return domain.Task{}, ErrInvalidParent
+		}
+		if err != nil {
+			return domain.Task{}, err
+		}
+		if in.ProjectID == nil {
+			in.ProjectID = parent.ProjectID
+		}
+	}
+	if in.ProjectID != nil {
+		if err := s.checkProject(in.UserID, *in.ProjectID); err != nil {
+			return domain.Task{}, err
+		}
+	}
 	task := domain.Task{
-		UserID:   userID,
-		Text:     trimmed,
-		Status:   domain.TaskStatusActive,
-		DueAt:    toUTC(dueAt),
-		RemindAt: toUTC(remindAt),
+		UserID:     in.UserID,
+		ProjectID:  in.ProjectID,
+		ParentID:   in.ParentID,
+		Text:       trimmed,
+		Status:     status,
+		Priority:   priority,
+		Tags:       tags,
+		DueAt:      toUTC(in.DueAt),
+		RemindAt:   toUTC(in.RemindAt),
+		NotifiedAt: toUTC(in.NotifiedAt),
+		Recurrence: recurrence,
 	}
 	created, err := s.repo.Create(task)
 	if err != nil {
@@ -65,6 +156,39 @@ func (s *TaskService) ListActive(userID int64, tz string) ([]domain.Task, error)
 	return items, nil
 }
 
+// List returns one page of the user's tasks; see storage.Page.
+func (s *TaskService) List(userID int64, f storage.TaskFilter, p storage.Page, tz string) ([]domain.Task, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListTasks(userID, f, p)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i] = toLocation(items[i], loc)
+	}
+	return items, nil
+}
+
+// Search finds the user's tasks matching f whose text matches every word of query; the
+// best match comes first.
+func (s *TaskService) Search(userID int64, query string, f storage.TaskFilter, tz string) ([]domain.SearchResult, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.SearchTasks(userID, query, f)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Task = toLocation(items[i].Task, loc)
+	}
+	return items, nil
+}
+
 func (s *TaskService) GetByID(id int64, tz string) (domain.Task, error) {
 	loc, err := locationFromTZ(tz)
 	if err != nil {
@@ -77,20 +201,248 @@ func (s *TaskService) GetByID(id int64, tz string) (domain.Task, error) {
 	return toLocation(item, loc), nil
 }
 
+// GetWithSubtasks returns the task with its subtasks and progress filled in.
+func (s *TaskService) GetWithSubtasks(id int64, tz string) (domain.Task, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	item, err := s.repo.GetByID(id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	subtasks, err := s.repo.ListSubtasks(id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	for i := range subtasks {
+		subtasks[i] = toLocation(subtasks[i], loc)
+	}
+	item = toLocation(item, loc)
+	item.Subtasks = subtasks
+	item.Progress = domain.SubtaskProgress(subtasks)
+	return item, nil
+}
+
+// Update writes t's editable fields: text, status, priority, due and reminder times,
+// recurrence, project and tags. t.Version is checked as in the repository (0 skips the
+// check), so a task read before someone else's write comes back as storage.ErrStale.
+// Marking the task done follows Complete: open subtasks are handled according to policy
+// and a recurring task gets its next occurrence. A new remind_at starts a fresh reminder,
+// as with SetRemind.
+func (s *TaskService) Update(t domain.Task, policy SubtaskPolicy, tz string) (domain.Task, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	t.Text = strings.TrimSpace(t.Text)
+	if t.Text == "" {
+		return domain.Task{}, ErrInvalidText
+	}
+	if !validStatus(t.Status) {
+		return domain.Task{}, ErrInvalidStatus
+	}
+	if !domain.ValidPriority(t.Priority) {
+		return domain.Task{}, ErrInvalidPriority
+	}
+	if t.Tags, err = domain.NormalizeTags(t.Tags); err != nil {
+		return domain.Task{}, err
+	}
+	if t.Recurrence, err = NormalizeRecurrence(t.Recurrence); err != nil {
+		return domain.Task{}, err
+	}
+	current, err := s.repo.GetByID(t.ID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if t.ProjectID != nil && !sameID(t.ProjectID, current.ProjectID) {
+		if err := s.checkProject(current.UserID, *t.ProjectID); err != nil {
+			return domain.Task{}, err
+		}
+	}
+	t.DueAt = toUTC(t.DueAt)
+	t.RemindAt = toUTC(t.RemindAt)
+	t.NotifiedAt = toUTC(t.NotifiedAt)
+	if current.Status == domain.TaskStatusDone || t.Status != domain.TaskStatusDone {
+		updated, err := s.repo.UpdateTask(t)
+		if err != nil {
+			return domain.Task{}, err
+		}
+		return toLocation(updated, loc), nil
+	}
+	subtasks, err := s.repo.ListSubtasks(t.ID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	c, err := s.completion(t, subtasks, loc)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if p := domain.SubtaskProgress(subtasks); p != nil && p.Done < p.Total {
+		if policy != CascadeSubtasks {
+			return domain.Task{}, ErrOpenSubtasks
+		}
+		c.Subtasks = true
+	}
+	// As in Complete, the edit, the subtasks and the next occurrence are written together.
+	updated, _, err := s.repo.UpdateAndComplete(t, c)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return toLocation(updated, loc), nil
+}
+
 func (s *TaskService) MarkDone(id int64, tz string) (domain.Task, error) {
+	done, _, err := s.Complete(id, RequireSubtasks, tz)
+	return done, err
+}
+
+// Complete marks the task done and, for a recurring task, creates its next occurrence
+// with a fresh copy of the subtasks. Open subtasks are handled according to policy.
+// Completing an already done task is a no-op and never spawns a second occurrence.
+func (s *TaskService) Complete(id int64, policy SubtaskPolicy, tz string) (domain.Task, *domain.Task, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	current, err := s.repo.GetByID(id)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	if current.Status == domain.TaskStatusDone {
+		return toLocation(current, loc), nil, nil
+	}
+	subtasks, err := s.repo.ListSubtasks(id)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	c, err := s.completion(current, subtasks, loc)
+	if err != nil {
+		return domain.Task{}, nil, err
+	}
+	if p := domain.SubtaskProgress(subtasks); p != nil && p.Done < p.Total {
+		if policy != CascadeSubtasks {
+			return domain.Task{}, nil, ErrOpenSubtasks
+		}
+		c.Subtasks = true
+	}
+	// The store closes the subtasks and creates the next occurrence in the same
+	// transaction, and only if this call is the one that completes the task, so
+	// concurrent completions spawn it once.
+	item, next, err := s.repo.CompleteTask(id, c)
+	if err != nil || next == nil {
+		return toLocation(item, loc), nil, err
+	}
+	created := toLocation(*next, loc)
+	return toLocation(item, loc), &created, nil
+}
+
+// completion builds what completing t creates along with it: the next occurrence of a
+// recurring task with a fresh copy of its subtasks.
+func (s *TaskService) completion(t domain.Task, subtasks []domain.Task, loc *time.Location) (storage.Completion, error) {
+	if t.Recurrence == "" {
+		return storage.Completion{}, nil
+	}
+	next, ok, err := s.nextOccurrence(t, loc)
+	if err != nil || !ok {
+		return storage.Completion{}, err
+	}
+	c := storage.Completion{Next: &next}
+	for _, sub := range subtasks {
+		c.NextSubtasks = append(c.NextSubtasks, domain.Task{
+			UserID:   sub.UserID,
+			Text:     sub.Text,
+			Status:   domain.TaskStatusActive,
+			Priority: sub.Priority,
+			Tags:     sub.Tags,
+		})
+	}
+	return c, nil
+}
+
+func (s *TaskService) SetRecurrence(id int64, rule string, tz string) (domain.Task, error) {
 	loc, err := locationFromTZ(tz)
 	if err != nil {
 		return domain.Task{}, err
 	}
-	item, err := s.repo.MarkDone(id)
+	normalized, err := NormalizeRecurrence(rule)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	item, err := s.repo.SetRecurrence(id, normalized)
 	if err != nil {
 		return domain.Task{}, err
 	}
 	return toLocation(item, loc), nil
 }
 
-func (s *TaskService) Delete(id int64) error {
-	return s.repo.Delete(id)
+// nextOccurrence builds the task following a completed recurring task. Occurrences that
+// are already in the past are skipped (and count against COUNT); false means the series
+// is over.
+func (s *TaskService) nextOccurrence(t domain.Task, loc *time.Location) (domain.Task, bool, error) {
+	rule, err := ParseRecurrence(t.Recurrence)
+	if err != nil {
+		return domain.Task{}, false, err
+	}
+	now := s.now()
+	next := domain.Task{
+		UserID:    t.UserID,
+		Text:      t.Text,
+		Status:    domain.TaskStatusActive,
+		ProjectID: t.ProjectID,
+		ParentID:  t.ParentID,
+		Priority:  t.Priority,
+		Tags:      t.Tags,
+	}
+	anchor := t.DueAt
+	if anchor == nil {
+		anchor = t.RemindAt
+	}
+	if anchor == nil {
+		if rule.Count == 1 || rule.pastUntil(now, loc) {
+			return domain.Task{}, false, nil
+		}
+		next.Recurrence = rule.consume().String()
+		return next, true, nil
+	}
+	if rule.Freq == FreqMonthly && rule.MonthDay == 0 {
+		// Pin the day of the month, so a task on the 31st comes back on the 31st after
+		// a shorter month instead of drifting to the 28th.
+		rule.MonthDay = anchor.In(loc).Day()
+	}
+	at := *anchor
+	for i := 0; ; i++ {
+		step, ok := rule.step(at, loc)
+		if !ok || i >= maxRecurrenceSteps {
+			return domain.Task{}, false, nil
+		}
+		rule = rule.consume()
+		at = step
+		if at.After(now) {
+			break
+		}
+	}
+	next.Recurrence = rule.String()
+	shift := func(v *time.Time) *time.Time {
+		if v == nil {
+			return nil
+		}
+		var moved time.Time
+		if rule.Freq == FreqHourly {
+			moved = v.Add(at.Sub(*anchor))
+		} else {
+			moved = addDays(v.In(loc), calendarDays(anchor.In(loc), at.In(loc)))
+		}
+		return toUTC(&moved)
+	}
+	next.DueAt = shift(t.DueAt)
+	next.RemindAt = shift(t.RemindAt)
+	return next, true, nil
+}
+
+// Delete deletes the task and its subtasks. version is checked as in Update.
+func (s *TaskService) Delete(id, version int64) error {
+	return s.repo.DeleteTask(id, version)
 }
 
 func (s *TaskService) SetDue(id int64, dueAt *time.Time, tz string) (domain.Task, error) {
@@ -117,8 +469,129 @@ func (s *TaskService) SetRemind(id int64, remindAt *time.Time, tz string) (domai
 	return toLocation(item, loc), nil
 }
 
+// ListDueForNotify claims reminders that are due at now. Every returned task must be
+// reported back via MarkNotified or MarkNotifyFailed; otherwise it is handed out again
+// once the claim lease expires.
 func (s *TaskService) ListDueForNotify(now time.Time) ([]domain.Task, error) {
-	return s.repo.ListDueForNotify(now.UTC())
+	return s.repo.ListDueForNotify(now.UTC(), reminderLease)
+}
+
+func (s *TaskService) MarkNotified(id int64) error {
+	return s.repo.MarkNotified(id, s.now().UTC())
+}
+
+// MarkNotifyFailed schedules a retry with exponential backoff, or moves the reminder
+// to the failed state once the attempts are exhausted.
+func (s *TaskService) MarkNotifyFailed(task domain.Task, cause error) error {
+	var retryAt *time.Time
+	if task.ReminderAttempts < reminderMaxAttempts {
+		next := s.now().UTC().Add(reminderBackoff(task.ReminderAttempts))
+		retryAt = &next
+	}
+	return s.repo.MarkNotifyFailed(task.ID, cause.Error(), retryAt)
+}
+
+func (s *TaskService) ListReminders(status, tz string) ([]domain.Task, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListReminders(status)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i] = toLocation(items[i], loc)
+	}
+	return items, nil
+}
+
+// Block makes taskID wait for blockedByID; both have to be the same user's tasks
+// (storage.ErrNotFound otherwise).
+func (s *TaskService) Block(taskID, blockedByID int64) error {
+	err := s.repo.AddDependency(taskID, blockedByID)
+	if errors.Is(err, storage.ErrCycle) {
+		return ErrDependencyCycle
+	}
+	return err
+}
+
+func (s *TaskService) Unblock(taskID, blockedByID int64) error {
+	return s.repo.RemoveDependency(taskID, blockedByID)
+}
+
+// Graph returns the tasks the task waits for and the tasks waiting for it, both
+// transitively, with the edges between them.
+func (s *TaskService) Graph(id int64, tz string) (domain.Graph, error) {
+	loc, err := locationFromTZ(tz)
+	if err != nil {
+		return domain.Graph{}, err
+	}
+	item, err := s.repo.GetByID(id)
+	if err != nil {
+		return domain.Graph{}, err
+	}
+	edges, err := s.repo.ListDependencies(item.UserID)
+	if err != nil {
+		return domain.Graph{}, err
+	}
+	ids, sub := domain.DependencyGraph(edges, id)
+	graph := domain.Graph{Nodes: make([]domain.Task, 0, len(ids)), Edges: sub}
+	if graph.Edges == nil {
+		graph.Edges = []domain.Dependency{}
+	}
+	for _, nodeID := range ids {
+		node, err := s.repo.GetByID(nodeID)
+		if errors.Is(err, storage.ErrNotFound) {
+			// Deleted since the edges were read.
+			continue
+		}
+		if err != nil {
+			return domain.Graph{}, err
+		}
+		graph.Nodes = append(graph.Nodes, toLocation(node, loc))
+	}
+	return graph, nil
+}
+
+func (s *TaskService) ListTags(userID int64) ([]domain.Tag, error) {
+	return s.repo.ListTags(userID)
+}
+
+func (s *TaskService) checkProject(userID, projectID int64) error {
+	project, err := s.repo.GetProject(projectID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && project.UserID != userID) {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if project.Archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+func validStatus(status string) bool {
+	return status == domain.TaskStatusActive || status == domain.TaskStatusDone
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func reminderBackoff(attempt int) time.Duration {
+	d := reminderBaseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= reminderMaxBackoff {
+			return reminderMaxBackoff
+		}
+	}
+	return d
 }
 
 func toUTC(t *time.Time) *time.Time {
@@ -150,6 +623,9 @@ func locationFromTZ(tz string) (*time.Location, error) {
 	if tz == "" || tz == "UTC" {
 		return time.UTC, nil
 	}
+	if tz == "Local" {
+		return nil, ErrInvalidTimezone
+	}
 	loc, err := time.LoadLocation(tz)
 	if err == nil {
 		return loc, nil
@@ -2,10 +2,12 @@ package usecase
 
 import (
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage"
 	"example.com/yourapp/internal/storage/memory"
 )
 
@@ -17,7 +19,7 @@ func TestTaskServiceCreate_ValidatesAndNormalizesTimes(t *testing.T) {
 	}
 	svc := NewTaskService(repo)
 
-	if _, err := svc.Create(user.ID, "   ", nil, nil, "+03:00"); !errors.Is(err, ErrInvalidText) {
+	if _, err := svc.Create(NewTask{UserID: user.ID, Text: "   "}, "+03:00"); !errors.Is(err, ErrInvalidText) {
 		t.Fatalf("expected ErrInvalidText, got %v", err)
 	}
 
@@ -25,7 +27,7 @@ func TestTaskServiceCreate_ValidatesAndNormalizesTimes(t *testing.T) {
 	dueAt := time.Date(2026, 1, 2, 10, 0, 0, 0, loc)
 	remindAt := time.Date(2026, 1, 2, 9, 30, 0, 0, loc)
 
-	created, err := svc.Create(user.ID, "  test  ", &dueAt, &remindAt, "+03:00")
+	created, err := svc.Create(NewTask{UserID: user.ID, Text: "  test  ", DueAt: &dueAt, RemindAt: &remindAt}, "+03:00")
 	if err != nil {
 		t.Fatalf("create task: %v", err)
 	}
@@ -85,7 +87,7 @@ func TestTaskServiceListDueForNotify_Idempotent(t *testing.T) {
 
 	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
 	remindAt := now.Add(-time.Minute)
-	_, err = svc.Create(user.ID, "notify", nil, &remindAt, "UTC")
+	_, err = svc.Create(NewTask{UserID: user.ID, Text: "notify", RemindAt: &remindAt}, "UTC")
 	if err != nil {
 		t.Fatalf("create task: %v", err)
 	}
@@ -106,3 +108,492 @@ func TestTaskServiceListDueForNotify_Idempotent(t *testing.T) {
 		t.Fatalf("expected 0 tasks on second run, got %d", len(items))
 	}
 }
+
+func TestTaskServiceMarkNotifyFailed_RetriesThenDeadLetters(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 4, ChatID: 4, Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	svc := NewTaskService(repo)
+	svc.now = func() time.Time { return now }
+
+	remindAt := now.Add(-time.Minute)
+	created, err := svc.Create(NewTask{UserID: user.ID, Text: "flaky", RemindAt: &remindAt}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	for attempt := 1; attempt <= reminderMaxAttempts; attempt++ {
+		items, err := svc.ListDueForNotify(now)
+		if err != nil {
+			t.Fatalf("list due: %v", err)
+		}
+		if len(items) != 1 || items[0].ReminderAttempts != attempt {
+			t.Fatalf("attempt %d: expected 1 claimed task, got %v", attempt, items)
+		}
+		if err := svc.MarkNotifyFailed(items[0], errors.New("telegram down")); err != nil {
+			t.Fatalf("mark failed: %v", err)
+		}
+		if items, _ := svc.ListDueForNotify(now); len(items) != 0 {
+			t.Fatalf("attempt %d: expected backoff before retry, got %v", attempt, items)
+		}
+		now = now.Add(reminderMaxBackoff)
+	}
+
+	failed, err := svc.ListReminders(domain.ReminderStatusFailed, "UTC")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != created.ID || failed[0].ReminderError != "telegram down" {
+		t.Fatalf("expected task in dead-letter list, got %v", failed)
+	}
+	if items, _ := svc.ListDueForNotify(now); len(items) != 0 {
+		t.Fatalf("expected no claims for failed reminder, got %v", items)
+	}
+	if _, err := svc.SetRemind(created.ID, &remindAt, "UTC"); err != nil {
+		t.Fatalf("set remind: %v", err)
+	}
+	items, err := svc.ListDueForNotify(now)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected reset reminder to be claimable, got %v %v", items, err)
+	}
+	if err := svc.MarkNotified(items[0].ID); err != nil {
+		t.Fatalf("mark notified: %v", err)
+	}
+	stored, _ := repo.GetByID(created.ID)
+	if stored.ReminderStatus != domain.ReminderStatusDelivered || stored.NotifiedAt == nil {
+		t.Fatalf("expected delivered reminder, got %+v", stored)
+	}
+}
+
+func TestTaskServiceComplete_SpawnsNextOccurrence(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 5, ChatID: 5, Timezone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	berlin, err := LocationFromTZ("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	svc := NewTaskService(repo)
+	svc.now = func() time.Time { return time.Date(2026, 3, 28, 8, 0, 0, 0, berlin) }
+
+	dueAt := time.Date(2026, 3, 28, 9, 0, 0, 0, berlin)
+	remindAt := dueAt.Add(-15 * time.Minute)
+	created, err := svc.Create(NewTask{
+		UserID:   user.ID,
+		Text:     "stand-up",
+		Priority: domain.PriorityP2,
+		Tags:     []string{"work"},
+		DueAt:    &dueAt,
+		RemindAt: &remindAt,
+	}, user.Timezone)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if _, err := svc.SetRecurrence(created.ID, "FREQ=DAILY;COUNT=2", user.Timezone); err != nil {
+		t.Fatalf("set recurrence: %v", err)
+	}
+	if _, err := svc.Create(NewTask{UserID: user.ID, ParentID: &created.ID, Text: "notes"}, user.Timezone); err != nil {
+		t.Fatalf("create subtask: %v", err)
+	}
+
+	done, next, err := svc.Complete(created.ID, CascadeSubtasks, user.Timezone)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if done.Status != domain.TaskStatusDone || next == nil {
+		t.Fatalf("expected done task and next occurrence, got %v %v", done, next)
+	}
+	if got := next.DueAt.Format("2006-01-02 15:04 -07:00"); got != "2026-03-29 09:00 +02:00" {
+		t.Fatalf("expected next due at 09:00 after DST switch, got %s", got)
+	}
+	if got := next.RemindAt.Format("15:04"); got != "08:45" {
+		t.Fatalf("expected next remind at 08:45, got %s", got)
+	}
+	if next.Recurrence != "FREQ=DAILY;COUNT=1" {
+		t.Fatalf("expected count to decrease, got %q", next.Recurrence)
+	}
+	if next.Priority != domain.PriorityP2 || len(next.Tags) != 1 || next.Tags[0] != "work" {
+		t.Fatalf("expected priority and tags to carry over, got %d %v", next.Priority, next.Tags)
+	}
+	if subtasks, _ := repo.ListSubtasks(next.ID); len(subtasks) != 1 || subtasks[0].Status != domain.TaskStatusActive {
+		t.Fatalf("expected a fresh copy of the subtasks, got %+v", subtasks)
+	}
+
+	if _, again, err := svc.Complete(created.ID, RequireSubtasks, user.Timezone); err != nil || again != nil {
+		t.Fatalf("expected completing twice to be a no-op, got %v %v", again, err)
+	}
+	if _, last, err := svc.Complete(next.ID, CascadeSubtasks, user.Timezone); err != nil || last != nil {
+		t.Fatalf("expected series to end after COUNT, got %v %v", last, err)
+	}
+}
+
+func TestTaskServiceComplete_MonthlyKeepsDayOfMonth(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 5, ChatID: 5})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	svc := NewTaskService(repo)
+	svc.now = func() time.Time { return time.Date(2026, 1, 30, 8, 0, 0, 0, time.UTC) }
+	dueAt := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	task, err := svc.Create(NewTask{UserID: user.ID, Text: "rent", DueAt: &dueAt, Recurrence: "FREQ=MONTHLY"}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	for _, want := range []string{"2026-02-28", "2026-03-31", "2026-04-30"} {
+		_, next, err := svc.Complete(task.ID, RequireSubtasks, "UTC")
+		if err != nil || next == nil {
+			t.Fatalf("complete: got %v %v", next, err)
+		}
+		if got := next.DueAt.Format("2006-01-02"); got != want {
+			t.Fatalf("expected next due on %s, got %s", want, got)
+		}
+		task = *next
+	}
+}
+
+func TestTaskServiceComplete_ConcurrentSpawnsOnce(t *testing.T) {
+	repo := memory.New()
+	user, err := repo.CreateUser(domain.User{TelegramUserID: 5, ChatID: 5})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	svc := NewTaskService(repo)
+	dueAt := time.Now().Add(time.Hour)
+	task, err := svc.Create(NewTask{UserID: user.ID, Text: "stand-up", DueAt: &dueAt, Recurrence: "FREQ=DAILY"}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	const workers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	spawned := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, next, err := svc.Complete(task.ID, RequireSubtasks, "UTC")
+			if err != nil {
+				t.Errorf("complete: %v", err)
+				return
+			}
+			if next != nil {
+				mu.Lock()
+				spawned++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if spawned != 1 {
+		t.Fatalf("expected one next occurrence, got %d", spawned)
+	}
+	if tasks, _ := repo.ListTasks(user.ID, storage.TaskFilter{}, storage.Page{}); len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+}
+
+func TestTaskServiceCreate_ChecksProject(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 6, ChatID: 6, Timezone: "UTC"})
+	other, _ := repo.CreateUser(domain.User{TelegramUserID: 7, ChatID: 7, Timezone: "UTC"})
+	work, _ := repo.CreateProject(domain.Project{UserID: user.ID, Name: "work"})
+	foreign, _ := repo.CreateProject(domain.Project{UserID: other.ID, Name: "work"})
+	old, _ := repo.CreateProject(domain.Project{UserID: user.ID, Name: "old", Archived: true})
+	svc := NewTaskService(repo)
+
+	if _, err := svc.Create(NewTask{UserID: user.ID, ProjectID: &foreign.ID, Text: "x"}, "UTC"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+	if _, err := svc.Create(NewTask{UserID: user.ID, ProjectID: &old.ID, Text: "x"}, "UTC"); !errors.Is(err, ErrProjectArchived) {
+		t.Fatalf("expected ErrProjectArchived, got %v", err)
+	}
+	created, err := svc.Create(NewTask{UserID: user.ID, ProjectID: &work.ID, Text: "report"}, "UTC")
+	if err != nil || created.ProjectID == nil || *created.ProjectID != work.ID {
+		t.Fatalf("expected task in project, got %+v %v", created, err)
+	}
+}
+
+func TestTaskServiceComplete_SubtaskPolicy(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 8, ChatID: 8, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	parent, err := svc.Create(NewTask{UserID: user.ID, Text: "release"}, "UTC")
+	if err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	sub, err := svc.Create(NewTask{UserID: user.ID, ParentID: &parent.ID, Text: "changelog"}, "UTC")
+	if err != nil {
+		t.Fatalf("create subtask: %v", err)
+	}
+	if _, err := svc.Create(NewTask{UserID: user.ID, ParentID: &sub.ID, Text: "nested"}, "UTC"); !errors.Is(err, ErrInvalidParent) {
+		t.Fatalf("expected ErrInvalidParent for a nested subtask, got %v", err)
+	}
+
+	if _, _, err := svc.Complete(parent.ID, RequireSubtasks, "UTC"); !errors.Is(err, ErrOpenSubtasks) {
+		t.Fatalf("expected ErrOpenSubtasks, got %v", err)
+	}
+	done, _, err := svc.Complete(parent.ID, CascadeSubtasks, "UTC")
+	if err != nil || done.Status != domain.TaskStatusDone {
+		t.Fatalf("expected cascade to complete the parent, got %+v %v", done, err)
+	}
+	tree, err := svc.GetWithSubtasks(parent.ID, "UTC")
+	if err != nil || len(tree.Subtasks) != 1 || tree.Progress == nil || tree.Progress.Done != 1 {
+		t.Fatalf("expected the subtask done, got %+v %v", tree, err)
+	}
+}
+
+func TestTaskServiceUpdate_ValidatesAndResetsReminder(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 9, ChatID: 9, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	remindAt := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	created, err := svc.Create(NewTask{UserID: user.ID, Text: "call", RemindAt: &remindAt}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := repo.MarkNotified(created.ID, remindAt); err != nil {
+		t.Fatalf("mark notified: %v", err)
+	}
+	notified, _ := svc.GetByID(created.ID, "UTC")
+
+	bad := notified
+	bad.Text = "  "
+	if _, err := svc.Update(bad, RequireSubtasks, "UTC"); !errors.Is(err, ErrInvalidText) {
+		t.Fatalf("expected ErrInvalidText, got %v", err)
+	}
+	bad = notified
+	bad.Status = "archived"
+	if _, err := svc.Update(bad, RequireSubtasks, "UTC"); !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("expected ErrInvalidStatus, got %v", err)
+	}
+
+	loc := time.FixedZone("+03:00", 3*3600)
+	later := time.Date(2026, 1, 3, 12, 0, 0, 0, loc)
+	edit := notified
+	edit.Text = "  call back  "
+	edit.RemindAt = &later
+	updated, err := svc.Update(edit, RequireSubtasks, "+03:00")
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Text != "call back" {
+		t.Fatalf("expected trimmed text, got %q", updated.Text)
+	}
+	if updated.NotifiedAt != nil || updated.ReminderStatus != domain.ReminderStatusPending {
+		t.Fatalf("expected a fresh reminder, got notified_at=%v status=%q", updated.NotifiedAt, updated.ReminderStatus)
+	}
+	if got := updated.RemindAt.Format("-07:00"); got != "+03:00" {
+		t.Fatalf("expected remind_at in +03:00, got %s", got)
+	}
+
+	if _, err := svc.Update(edit, RequireSubtasks, "UTC"); !errors.Is(err, storage.ErrStale) {
+		t.Fatalf("expected ErrStale for an outdated version, got %v", err)
+	}
+}
+
+func TestTaskServiceUpdate_CompletesLikeComplete(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 10, ChatID: 10, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	due := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	parent, err := svc.Create(NewTask{UserID: user.ID, Text: "standup", DueAt: &due, Recurrence: "FREQ=DAILY"}, "UTC")
+	if err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	if _, err := svc.Create(NewTask{UserID: user.ID, ParentID: &parent.ID, Text: "notes"}, "UTC"); err != nil {
+		t.Fatalf("create subtask: %v", err)
+	}
+
+	done := parent
+	done.Status = domain.TaskStatusDone
+	if _, err := svc.Update(done, RequireSubtasks, "UTC"); !errors.Is(err, ErrOpenSubtasks) {
+		t.Fatalf("expected ErrOpenSubtasks, got %v", err)
+	}
+	updated, err := svc.Update(done, CascadeSubtasks, "UTC")
+	if err != nil || updated.Status != domain.TaskStatusDone {
+		t.Fatalf("expected the task done, got %+v %v", updated, err)
+	}
+	active, err := svc.ListActive(user.ID, "UTC")
+	if err != nil {
+		t.Fatalf("list active: %v", err)
+	}
+	var next *domain.Task
+	for i := range active {
+		if active[i].ParentID == nil {
+			next = &active[i]
+		}
+	}
+	if len(active) != 2 || next == nil || next.DueAt == nil || !next.DueAt.After(due) {
+		t.Fatalf("expected the next occurrence with its subtask, got %+v", active)
+	}
+}
+
+func TestTaskServiceUpdate_StaleCompletionWritesNothing(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 11, ChatID: 11, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	due := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	parent, err := svc.Create(NewTask{UserID: user.ID, Text: "standup", DueAt: &due, Recurrence: "FREQ=DAILY"}, "UTC")
+	if err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	sub, err := svc.Create(NewTask{UserID: user.ID, ParentID: &parent.ID, Text: "notes"}, "UTC")
+	if err != nil {
+		t.Fatalf("create subtask: %v", err)
+	}
+	if _, err := svc.SetDue(parent.ID, &due, "UTC"); err != nil {
+		t.Fatalf("set due: %v", err)
+	}
+
+	// parent was read before SetDue, so the edit loses and must not close or spawn anything.
+	done := parent
+	done.Status = domain.TaskStatusDone
+	if _, err := svc.Update(done, CascadeSubtasks, "UTC"); !errors.Is(err, storage.ErrStale) {
+		t.Fatalf("expected ErrStale, got %v", err)
+	}
+	if got, _ := svc.GetByID(sub.ID, "UTC"); got.Status != domain.TaskStatusActive {
+		t.Fatalf("expected the subtask still open, got %+v", got)
+	}
+	if active, _ := svc.ListActive(user.ID, "UTC"); len(active) != 2 {
+		t.Fatalf("expected no next occurrence, got %+v", active)
+	}
+}
+
+func TestTaskServiceUpdate_ChecksProject(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 12, ChatID: 12, Timezone: "UTC"})
+	other, _ := repo.CreateUser(domain.User{TelegramUserID: 13, ChatID: 13, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	foreign, _ := repo.CreateProject(domain.Project{UserID: other.ID, Name: "чужой"})
+	archived, _ := repo.CreateProject(domain.Project{UserID: user.ID, Name: "старый"})
+	archived.Archived = true
+	if _, err := repo.UpdateProject(archived); err != nil {
+		t.Fatalf("archive project: %v", err)
+	}
+	task, err := svc.Create(NewTask{UserID: user.ID, Text: "plan"}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	edit := task
+	edit.ProjectID = &foreign.ID
+	if _, err := svc.Update(edit, RequireSubtasks, "UTC"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound for another user's project, got %v", err)
+	}
+	edit.ProjectID = &archived.ID
+	if _, err := svc.Update(edit, RequireSubtasks, "UTC"); !errors.Is(err, ErrProjectArchived) {
+		t.Fatalf("expected ErrProjectArchived, got %v", err)
+	}
+	edit.ProjectID = nil
+	edit.Priority = 7
+	if _, err := svc.Update(edit, RequireSubtasks, "UTC"); !errors.Is(err, ErrInvalidPriority) {
+		t.Fatalf("expected ErrInvalidPriority, got %v", err)
+	}
+	edit.Priority = domain.PriorityDefault
+	edit.Recurrence = "FREQ=SOMETIMES"
+	if _, err := svc.Update(edit, RequireSubtasks, "UTC"); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Fatalf("expected ErrInvalidRecurrence, got %v", err)
+	}
+	if got, _ := svc.GetByID(task.ID, "UTC"); got.Version != task.Version {
+		t.Fatalf("expected rejected edits to leave the task alone, got %+v", got)
+	}
+}
+
+func TestTaskServiceListAndSearch_ConvertTimezone(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 14, ChatID: 14, Timezone: "Europe/Moscow"})
+	svc := NewTaskService(repo)
+
+	due := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	if _, err := svc.Create(NewTask{UserID: user.ID, Text: "Купить молоко", DueAt: &due}, "UTC"); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	listed, err := svc.List(user.ID, storage.TaskFilter{}, storage.Page{}, "Europe/Moscow")
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("list: got %+v %v", listed, err)
+	}
+	if got := listed[0].DueAt.Format("15:04 -07:00"); got != "12:00 +03:00" {
+		t.Fatalf("expected due_at in Moscow time, got %s", got)
+	}
+	found, err := svc.Search(user.ID, "молок", storage.TaskFilter{}, "Europe/Moscow")
+	if err != nil || len(found) != 1 {
+		t.Fatalf("search: got %+v %v", found, err)
+	}
+	if got := found[0].DueAt.Format("15:04 -07:00"); got != "12:00 +03:00" {
+		t.Fatalf("expected due_at in Moscow time, got %s", got)
+	}
+	if _, err := svc.List(user.ID, storage.TaskFilter{}, storage.Page{}, "Mars/Olympus"); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone, got %v", err)
+	}
+}
+
+func TestTaskServiceDelete_ChecksVersion(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 15, ChatID: 15, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	task, err := svc.Create(NewTask{UserID: user.ID, Text: "draft"}, "UTC")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := svc.Delete(task.ID, task.Version+1); !errors.Is(err, storage.ErrStale) {
+		t.Fatalf("expected ErrStale, got %v", err)
+	}
+	if err := svc.Delete(task.ID, task.Version); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := svc.Delete(task.ID, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestTaskServiceGraph(t *testing.T) {
+	repo := memory.New()
+	user, _ := repo.CreateUser(domain.User{TelegramUserID: 16, ChatID: 16, Timezone: "UTC"})
+	svc := NewTaskService(repo)
+
+	var ids []int64
+	for _, text := range []string{"build", "migrate", "deploy", "unrelated"} {
+		task, err := svc.Create(NewTask{UserID: user.ID, Text: text}, "UTC")
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+	build, migrate, deploy, unrelated := ids[0], ids[1], ids[2], ids[3]
+	if err := svc.Block(migrate, build); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if err := svc.Block(deploy, migrate); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if err := svc.Block(build, deploy); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle, got %v", err)
+	}
+
+	graph, err := svc.Graph(migrate, "UTC")
+	if err != nil || len(graph.Nodes) != 3 || len(graph.Edges) != 2 {
+		t.Fatalf("expected the whole chain, got %+v %v", graph, err)
+	}
+	for _, node := range graph.Nodes {
+		if node.ID == unrelated {
+			t.Fatalf("expected only connected tasks, got %+v", graph.Nodes)
+		}
+	}
+	alone, err := svc.Graph(unrelated, "UTC")
+	if err != nil || len(alone.Nodes) != 1 || alone.Edges == nil || len(alone.Edges) != 0 {
+		t.Fatalf("expected a lone node with no edges, got %+v %v", alone, err)
+	}
+}
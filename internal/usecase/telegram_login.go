@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTelegramAuth = errors.New("invalid telegram auth data")
+
+// telegramAuthMaxAge is how old auth_date may be before a login payload is rejected.
+const telegramAuthMaxAge = 24 * time.Hour
+
+// VerifyLoginWidget checks a Telegram Login Widget payload: the hash must be the
+// HMAC-SHA256 of the sorted "key=value" lines, keyed with SHA256(bot token).
+// It returns the Telegram user id.
+func VerifyLoginWidget(fields map[string]string, botToken string, now time.Time) (int64, error) {
+	secret := sha256.Sum256([]byte(botToken))
+	if err := verifyTelegramHash(fields, secret[:], now); err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidTelegramAuth
+	}
+	return id, nil
+}
+
+// VerifyInitData checks Mini App initData (a URL-encoded query string); the HMAC key is
+// HMAC-SHA256("WebAppData", bot token). It returns the id from the embedded user JSON.
+func VerifyInitData(initData string, botToken string, now time.Time) (int64, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return 0, ErrInvalidTelegramAuth
+	}
+	fields := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) != 1 {
+			return 0, ErrInvalidTelegramAuth
+		}
+		fields[k] = v[0]
+	}
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(botToken))
+	if err := verifyTelegramHash(fields, mac.Sum(nil), now); err != nil {
+		return 0, err
+	}
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(fields["user"]), &user); err != nil || user.ID <= 0 {
+		return 0, ErrInvalidTelegramAuth
+	}
+	return user.ID, nil
+}
+
+func verifyTelegramHash(fields map[string]string, secret []byte, now time.Time) error {
+	got, err := hex.DecodeString(fields["hash"])
+	if err != nil || len(got) == 0 {
+		return ErrInvalidTelegramAuth
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, k+"="+fields[k])
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal(mac.Sum(nil), got) {
+		return ErrInvalidTelegramAuth
+	}
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return ErrInvalidTelegramAuth
+	}
+	if now.Sub(time.Unix(authDate, 0)) > telegramAuthMaxAge {
+		return ErrInvalidTelegramAuth
+	}
+	return nil
+}
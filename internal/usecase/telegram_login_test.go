@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/yourapp/internal/storage/memory"
+)
+
+func signTelegram(fields map[string]string, secret []byte) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, k+"="+fields[k])
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyLoginWidget(t *testing.T) {
+	const botToken = "123:abc"
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	fields := map[string]string{
+		"id":         "42",
+		"first_name": "Ann",
+		"auth_date":  strconv.FormatInt(now.Add(-time.Minute).Unix(), 10),
+	}
+	secret := sha256.Sum256([]byte(botToken))
+	fields["hash"] = signTelegram(fields, secret[:])
+
+	id, err := VerifyLoginWidget(fields, botToken, now)
+	if err != nil || id != 42 {
+		t.Fatalf("expected id 42, got %d %v", id, err)
+	}
+	if _, err := VerifyLoginWidget(fields, "other", now); !errors.Is(err, ErrInvalidTelegramAuth) {
+		t.Fatalf("expected wrong bot token to fail, got %v", err)
+	}
+	if _, err := VerifyLoginWidget(fields, botToken, now.Add(48*time.Hour)); !errors.Is(err, ErrInvalidTelegramAuth) {
+		t.Fatalf("expected stale auth_date to fail, got %v", err)
+	}
+	fields["first_name"] = "Bob"
+	if _, err := VerifyLoginWidget(fields, botToken, now); !errors.Is(err, ErrInvalidTelegramAuth) {
+		t.Fatalf("expected tampered payload to fail, got %v", err)
+	}
+}
+
+func TestAuthService_LoginInitDataCreatesUser(t *testing.T) {
+	const botToken = "123:abc"
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	fields := map[string]string{
+		"query_id":  "AAE",
+		"user":      `{"id":77,"first_name":"Ann"}`,
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+	}
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(botToken))
+	fields["hash"] = signTelegram(fields, mac.Sum(nil))
+	values := url.Values{}
+	for k, v := range fields {
+		values.Set(k, v)
+	}
+
+	repo := memory.New()
+	svc := NewAuthService(repo, repo, "", botToken, time.Hour)
+	svc.now = func() time.Time { return now }
+
+	plain, token, user, err := svc.LoginInitData(values.Encode())
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if user.TelegramUserID != 77 || token.UserID != user.ID || token.ExpiresAt == nil {
+		t.Fatalf("unexpected session %+v for %+v", token, user)
+	}
+	if got, err := svc.Authenticate(plain); err != nil || got.ID != user.ID {
+		t.Fatalf("expected session token to authenticate, got %+v %v", got, err)
+	}
+	_, _, again, err := svc.LoginInitData(values.Encode())
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected second login to reuse user %d, got %+v %v", user.ID, again, err)
+	}
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/repository"
+	"example.com/yourapp/internal/storage"
+)
+
+var (
+	ErrInvalidTelegramID = errors.New("telegram user id and chat id are required")
+	ErrInvalidRole       = errors.New("invalid role")
+)
+
+type UserService struct {
+	repo repository.UserRepository
+}
+
+func NewUserService(repo repository.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// NewUser is what Create needs to add a user. An empty Timezone means UTC and an empty
+// Role means domain.RoleUser.
+type NewUser struct {
+	TelegramUserID int64
+	ChatID         int64
+	Timezone       string
+	Role           string
+}
+
+// Create adds a user; it returns storage.ErrConflict when the Telegram id is taken.
+func (s *UserService) Create(in NewUser) (domain.User, error) {
+	if in.TelegramUserID == 0 || in.ChatID == 0 {
+		return domain.User{}, ErrInvalidTelegramID
+	}
+	tz := strings.TrimSpace(in.Timezone)
+	if tz == "" {
+		tz = "UTC"
+	}
+	if _, err := locationFromTZ(tz); err != nil {
+		return domain.User{}, err
+	}
+	role := in.Role
+	if role == "" {
+		role = domain.RoleUser
+	}
+	if role != domain.RoleUser && role != domain.RoleAdmin {
+		return domain.User{}, ErrInvalidRole
+	}
+	return s.repo.CreateUser(domain.User{
+		TelegramUserID: in.TelegramUserID,
+		ChatID:         in.ChatID,
+		Timezone:       tz,
+		Role:           role,
+	})
+}
+
+// EnsureTelegramUser returns the user behind a Telegram account, creating it in UTC on
+// first contact.
+func (s *UserService) EnsureTelegramUser(telegramUserID, chatID int64) (domain.User, error) {
+	user, err := s.repo.GetByTelegramID(telegramUserID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return domain.User{}, err
+	}
+	user, err = s.Create(NewUser{TelegramUserID: telegramUserID, ChatID: chatID})
+	if errors.Is(err, storage.ErrConflict) {
+		// Another update from the same user created it first.
+		return s.repo.GetByTelegramID(telegramUserID)
+	}
+	return user, err
+}
+
+func (s *UserService) Get(id int64) (domain.User, error) {
+	return s.repo.GetUser(id)
+}
+
+// List returns one page of users; see storage.Page.
+func (s *UserService) List(p storage.Page) ([]domain.User, error) {
+	return s.repo.ListUsers(p)
+}
+
+// SetTimezone accepts IANA names ("Europe/Moscow") and fixed offsets ("+03:00").
+func (s *UserService) SetTimezone(id int64, tz string) (domain.User, error) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" {
+		return domain.User{}, ErrInvalidTimezone
+	}
+	if _, err := locationFromTZ(tz); err != nil {
+		return domain.User{}, err
+	}
+	return s.repo.UpdateTimezone(id, tz)
+}
+
+// SetCurrentProject picks the project new tasks from the bot go to; nil is the inbox.
+func (s *UserService) SetCurrentProject(id int64, projectID *int64) (domain.User, error) {
+	return s.repo.SetCurrentProject(id, projectID)
+}
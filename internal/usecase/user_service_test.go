@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/yourapp/internal/domain"
+	"example.com/yourapp/internal/storage/memory"
+)
+
+func TestUserServiceCreate_Validates(t *testing.T) {
+	svc := NewUserService(memory.New())
+
+	if _, err := svc.Create(NewUser{ChatID: 1}); !errors.Is(err, ErrInvalidTelegramID) {
+		t.Fatalf("expected ErrInvalidTelegramID, got %v", err)
+	}
+	if _, err := svc.Create(NewUser{TelegramUserID: 1, ChatID: 1, Timezone: "Mars/Olympus"}); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone, got %v", err)
+	}
+	if _, err := svc.Create(NewUser{TelegramUserID: 1, ChatID: 1, Role: "root"}); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+	user, err := svc.Create(NewUser{TelegramUserID: 1, ChatID: 1})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if user.Timezone != "UTC" || user.Role != domain.RoleUser {
+		t.Fatalf("expected UTC and the user role by default, got %+v", user)
+	}
+}
+
+func TestUserServiceEnsureTelegramUser_ReusesUser(t *testing.T) {
+	svc := NewUserService(memory.New())
+
+	first, err := svc.EnsureTelegramUser(7, 70)
+	if err != nil {
+		t.Fatalf("first contact: %v", err)
+	}
+	again, err := svc.EnsureTelegramUser(7, 70)
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("expected the same user, got %+v %v", again, err)
+	}
+
+	if _, err := svc.SetTimezone(first.ID, "  "); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone for a blank zone, got %v", err)
+	}
+	updated, err := svc.SetTimezone(first.ID, " Europe/Moscow ")
+	if err != nil || updated.Timezone != "Europe/Moscow" {
+		t.Fatalf("expected Europe/Moscow, got %+v %v", updated, err)
+	}
+}
+
+func TestUserServiceSetCurrentProject(t *testing.T) {
+	repo := memory.New()
+	svc := NewUserService(repo)
+
+	user, err := svc.EnsureTelegramUser(8, 80)
+	if err != nil {
+		t.Fatalf("first contact: %v", err)
+	}
+	project, err := repo.CreateProject(domain.Project{UserID: user.ID, Name: "Дом"})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	updated, err := svc.SetCurrentProject(user.ID, &project.ID)
+	if err != nil || updated.CurrentProjectID == nil || *updated.CurrentProjectID != project.ID {
+		t.Fatalf("expected the project picked, got %+v %v", updated, err)
+	}
+	if got, err := svc.Get(user.ID); err != nil || got.CurrentProjectID == nil {
+		t.Fatalf("expected the pick stored, got %+v %v", got, err)
+	}
+	inbox, err := svc.SetCurrentProject(user.ID, nil)
+	if err != nil || inbox.CurrentProjectID != nil {
+		t.Fatalf("expected the inbox, got %+v %v", inbox, err)
+	}
+}
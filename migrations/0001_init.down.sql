@@ -0,0 +1,3 @@
+drop table if exists attachments;
+drop table if exists tasks;
+drop table if exists users;
@@ -0,0 +1,5 @@
+drop index if exists tasks_reminder_status_idx;
+alter table tasks drop column if exists reminder_next_at;
+alter table tasks drop column if exists reminder_error;
+alter table tasks drop column if exists reminder_attempts;
+alter table tasks drop column if exists reminder_status;
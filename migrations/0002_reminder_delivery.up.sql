@@ -0,0 +1,9 @@
+alter table tasks add column if not exists reminder_status text not null default 'pending'
+  check (reminder_status in ('pending', 'sending', 'delivered', 'failed'));
+alter table tasks add column if not exists reminder_attempts int not null default 0;
+alter table tasks add column if not exists reminder_error text;
+alter table tasks add column if not exists reminder_next_at timestamptz;
+
+update tasks set reminder_status = 'delivered' where notified_at is not null;
+
+create index if not exists tasks_reminder_status_idx on tasks(reminder_status, remind_at) where notified_at is null;
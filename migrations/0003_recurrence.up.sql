@@ -0,0 +1 @@
+alter table tasks add column if not exists recurrence text not null default '';
@@ -0,0 +1,2 @@
+drop table if exists api_tokens;
+alter table users drop column if exists role;
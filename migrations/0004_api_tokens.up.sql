@@ -0,0 +1,13 @@
+alter table users add column if not exists role text not null default 'user' check (role in ('user', 'admin'));
+
+create table if not exists api_tokens(
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  name text not null default '',
+  token_hash text not null,
+  created_at timestamptz not null default now(),
+  expires_at timestamptz
+);
+
+create unique index if not exists api_tokens_token_hash_idx on api_tokens(token_hash);
+create index if not exists api_tokens_user_id_idx on api_tokens(user_id);
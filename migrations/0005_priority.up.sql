@@ -0,0 +1,3 @@
+alter table tasks add column if not exists priority smallint not null default 4 check (priority between 1 and 4);
+
+create index if not exists tasks_user_id_status_priority_idx on tasks(user_id, status, priority, id);
@@ -0,0 +1,4 @@
+alter table users drop column if exists current_project_id;
+drop index if exists tasks_project_id_idx;
+alter table tasks drop column if exists project_id;
+drop table if exists projects;
@@ -0,0 +1,16 @@
+create table if not exists projects(
+  id bigserial primary key,
+  user_id bigint not null references users(id) on delete cascade,
+  name text not null,
+  color text not null default '',
+  archived boolean not null default false,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+create unique index if not exists projects_user_id_name_idx on projects(user_id, name);
+
+alter table tasks add column if not exists project_id bigint references projects(id) on delete set null;
+create index if not exists tasks_project_id_idx on tasks(project_id);
+
+alter table users add column if not exists current_project_id bigint references projects(id) on delete set null;
@@ -0,0 +1,4 @@
+-- Russian and English stems side by side, so a query finds the task in either language.
+alter table tasks add column if not exists search tsvector
+  generated always as (to_tsvector('russian'::regconfig, text) || to_tsvector('english'::regconfig, text)) stored;
+create index if not exists tasks_search_idx on tasks using gin(search);
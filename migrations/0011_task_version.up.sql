@@ -0,0 +1 @@
+alter table tasks add column if not exists version bigint not null default 1;
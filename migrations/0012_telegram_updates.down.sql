@@ -0,0 +1 @@
+drop table if exists telegram_updates;
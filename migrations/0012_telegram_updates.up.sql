@@ -0,0 +1,6 @@
+create table if not exists telegram_updates(
+  update_id bigint primary key,
+  received_at timestamptz not null
+);
+
+create index if not exists telegram_updates_received_at_idx on telegram_updates(received_at);
@@ -0,0 +1 @@
+create index if not exists api_tokens_expires_at_idx on api_tokens(expires_at) where expires_at is not null;
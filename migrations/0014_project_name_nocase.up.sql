@@ -0,0 +1,3 @@
+-- Project names are unique per user regardless of case, matching how the bot looks them up.
+drop index if exists projects_user_id_name_idx;
+create unique index if not exists projects_user_id_lower_name_idx on projects(user_id, lower(name));
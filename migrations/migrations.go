@@ -0,0 +1,25 @@
+// Package migrations embeds the schema migrations so the binary can apply them itself.
+// Files are named NNNN_name.up.sql and NNNN_name.down.sql; the Postgres schema lives in
+// this directory and the SQLite one in sqlite/, since the dialects differ.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+var SQLiteFS = mustSub(sqliteFiles, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
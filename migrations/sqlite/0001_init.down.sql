@@ -0,0 +1,4 @@
+drop table if exists api_tokens;
+drop table if exists attachments;
+drop table if exists tasks;
+drop table if exists users;
@@ -0,0 +1,54 @@
+create table if not exists users(
+  id integer primary key autoincrement,
+  telegram_user_id integer not null,
+  chat_id integer not null,
+  timezone text not null default 'UTC',
+  role text not null default 'user' check (role in ('user', 'admin')),
+  created_at timestamp not null
+);
+
+create unique index if not exists users_telegram_user_id_idx on users(telegram_user_id);
+
+create table if not exists tasks(
+  id integer primary key autoincrement,
+  user_id integer not null references users(id) on delete cascade,
+  text text not null,
+  status text not null default 'active' check (status in ('active', 'done')),
+  due_at timestamp,
+  remind_at timestamp,
+  notified_at timestamp,
+  created_at timestamp not null,
+  updated_at timestamp not null,
+  reminder_status text not null default 'pending'
+    check (reminder_status in ('pending', 'sending', 'delivered', 'failed')),
+  reminder_attempts integer not null default 0,
+  reminder_error text,
+  reminder_next_at timestamp,
+  recurrence text not null default ''
+);
+
+create index if not exists tasks_user_id_status_idx on tasks(user_id, status);
+create index if not exists tasks_reminder_status_idx on tasks(reminder_status, remind_at) where notified_at is null;
+
+create table if not exists attachments(
+  id integer primary key autoincrement,
+  task_id integer not null references tasks(id) on delete cascade,
+  type text not null,
+  telegram_file_id text not null,
+  file_unique_id text not null,
+  caption text
+);
+
+create index if not exists attachments_task_id_idx on attachments(task_id);
+
+create table if not exists api_tokens(
+  id integer primary key autoincrement,
+  user_id integer not null references users(id) on delete cascade,
+  name text not null default '',
+  token_hash text not null,
+  created_at timestamp not null,
+  expires_at timestamp
+);
+
+create unique index if not exists api_tokens_token_hash_idx on api_tokens(token_hash);
+create index if not exists api_tokens_user_id_idx on api_tokens(user_id);
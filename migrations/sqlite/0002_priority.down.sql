@@ -0,0 +1,2 @@
+drop index if exists tasks_user_id_status_priority_idx;
+alter table tasks drop column priority;
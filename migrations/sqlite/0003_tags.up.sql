@@ -0,0 +1,16 @@
+create table if not exists tags(
+  id integer primary key autoincrement,
+  user_id integer not null references users(id) on delete cascade,
+  name text not null,
+  created_at timestamp not null
+);
+
+create unique index if not exists tags_user_id_name_idx on tags(user_id, name);
+
+create table if not exists task_tags(
+  task_id integer not null references tasks(id) on delete cascade,
+  tag_id integer not null references tags(id) on delete cascade,
+  primary key (task_id, tag_id)
+);
+
+create index if not exists task_tags_tag_id_idx on task_tags(tag_id);
@@ -0,0 +1,18 @@
+create table if not exists projects(
+  id integer primary key autoincrement,
+  user_id integer not null references users(id) on delete cascade,
+  name text not null,
+  color text not null default '',
+  archived integer not null default 0,
+  created_at timestamp not null,
+  updated_at timestamp not null
+);
+
+create unique index if not exists projects_user_id_name_idx on projects(user_id, name);
+
+-- No foreign keys on the new columns: SQLite cannot drop a column that is part of one,
+-- which would make the down migration a table rebuild. The store checks and clears them.
+alter table tasks add column project_id integer;
+create index if not exists tasks_project_id_idx on tasks(project_id);
+
+alter table users add column current_project_id integer;
@@ -0,0 +1,2 @@
+drop index if exists tasks_parent_id_idx;
+alter table tasks drop column parent_id;
@@ -0,0 +1,3 @@
+-- No foreign key for the same reason as project_id: the store deletes subtasks itself.
+alter table tasks add column parent_id integer;
+create index if not exists tasks_parent_id_idx on tasks(parent_id);
@@ -0,0 +1,8 @@
+create table if not exists task_dependencies(
+  task_id integer not null references tasks(id) on delete cascade,
+  blocked_by_id integer not null references tasks(id) on delete cascade,
+  primary key (task_id, blocked_by_id),
+  check (task_id <> blocked_by_id)
+);
+
+create index if not exists task_dependencies_blocked_by_id_idx on task_dependencies(blocked_by_id);
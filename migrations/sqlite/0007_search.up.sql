@@ -0,0 +1,22 @@
+-- External-content index over tasks.text, kept in sync by the triggers below.
+create virtual table if not exists tasks_fts using fts5(
+  text,
+  content = 'tasks',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+create trigger if not exists tasks_fts_insert after insert on tasks begin
+  insert into tasks_fts(rowid, text) values (new.id, new.text);
+end;
+
+create trigger if not exists tasks_fts_delete after delete on tasks begin
+  insert into tasks_fts(tasks_fts, rowid, text) values ('delete', old.id, old.text);
+end;
+
+create trigger if not exists tasks_fts_update after update of text on tasks begin
+  insert into tasks_fts(tasks_fts, rowid, text) values ('delete', old.id, old.text);
+  insert into tasks_fts(rowid, text) values (new.id, new.text);
+end;
+
+insert into tasks_fts(tasks_fts) values ('rebuild');
@@ -0,0 +1 @@
+drop index if exists api_tokens_expires_at_idx;
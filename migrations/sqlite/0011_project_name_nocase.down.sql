@@ -0,0 +1,2 @@
+drop index if exists projects_user_id_fold_name_idx;
+create unique index if not exists projects_user_id_name_idx on projects(user_id, name);
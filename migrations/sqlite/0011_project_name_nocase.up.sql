@@ -0,0 +1,4 @@
+-- Project names are unique per user regardless of case, matching how the bot looks them up.
+-- fold is registered by the store; SQLite's lower() only knows ASCII.
+drop index if exists projects_user_id_name_idx;
+create unique index if not exists projects_user_id_fold_name_idx on projects(user_id, fold(name));